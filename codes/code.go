@@ -19,6 +19,7 @@ var (
 	IllegalInvoke    = NewCode(8, "illegal invoke")
 	IllegalRequest   = NewCode(9, "illegal request")
 	TooManyRequests  = NewCode(10, "too many requests")
+	AlreadyLoggedIn  = NewCode(11, "already logged in")
 )
 
 type Code struct {
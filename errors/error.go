@@ -55,6 +55,14 @@ var (
 	ErrWriterClosing           = New("writer is closing")
 	ErrDeadlineExceeded        = New("deadline exceeded")
 	ErrMissingResolver         = New("missing resolver")
+	ErrMissingTLSConfig        = New("missing tls config")
+	ErrOutboundQueueFull       = New("outbound queue full")
+	ErrSlowConsumer            = New("slow consumer")
+	ErrInvalidProxyHeader      = New("invalid proxy protocol header")
+	ErrAlreadyLoggedIn         = New("user already logged in")
+	ErrIncompatibleProtocol    = New("incompatible protocol version")
+	ErrInvalidTraceparent      = New("invalid traceparent")
+	ErrTraceQueueFull          = New("trace export queue full")
 )
 
 // NewError 新建一个错误
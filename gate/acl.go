@@ -0,0 +1,166 @@
+package gate
+
+import (
+	"gatesvr/codes"
+	"gatesvr/config"
+	"gatesvr/etc"
+	"gatesvr/log"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	ACLAuth  ACLPolicy = "auth"  // 需要绑定用户后才能访问
+	ACLAllow ACLPolicy = "allow" // 允许所有连接访问
+	ACLDeny  ACLPolicy = "deny"  // 拒绝所有连接访问
+)
+
+const etcConfigName = "etc" // etc配置文件名称
+
+type ACLPolicy string
+
+type ACLRules struct {
+	Enable     bool       `json:"enable"`     // 是否启用访问控制
+	Default    ACLPolicy  `json:"default"`    // 未声明路由的访问策略，默认auth
+	Anonymous  []int32    `json:"anonymous"`  // 允许匿名连接访问的路由，如登录、握手等
+	Authorized []int32    `json:"authorized"` // 需要绑定用户后才能访问的路由
+	Deny       []DenyRule `json:"deny"`       // 路由拒绝列表
+}
+
+type DenyRule struct {
+	Route int32   `json:"route"` // 路由ID
+	UIDs  []int64 `json:"uids"`  // 禁止访问该路由的用户，为空时禁止所有连接访问该路由
+}
+
+type acl struct {
+	gate  *Gate
+	table atomic.Pointer[aclTable]
+}
+
+type aclTable struct {
+	policy     ACLPolicy
+	anonymous  map[int32]struct{}
+	authorized map[int32]struct{}
+	deny       map[int32]map[int64]struct{}
+}
+
+func newACL(gate *Gate) *acl {
+	a := &acl{gate: gate}
+	a.load()
+
+	return a
+}
+
+// 监听访问控制规则变更
+func (a *acl) watch() {
+	if pattern := a.gate.opts.acl.config; pattern != "" {
+		name, _, _ := strings.Cut(pattern, ".")
+		config.Watch(func(...string) { a.load() }, name)
+	}
+
+	etc.GetConfigurator().Watch(func(...string) { a.load() }, etcConfigName)
+}
+
+// 加载访问控制规则
+// 优先级：配置中心 > 启动选项 > etc配置
+func (a *acl) load() {
+	var (
+		opts  = a.gate.opts.acl
+		rules *ACLRules
+	)
+
+	if opts.config != "" && config.Has(opts.config) {
+		rules = &ACLRules{}
+		if err := config.Get(opts.config).Scan(rules); err != nil {
+			log.Errorf("load acl rules from config failed: %v", err)
+			return
+		}
+	} else if opts.rules != nil {
+		rules = opts.rules
+	} else {
+		rules = &ACLRules{}
+		if err := etc.Get(defaultACLKey).Scan(rules); err != nil {
+			log.Errorf("load acl rules from etc failed: %v", err)
+			return
+		}
+	}
+
+	if !rules.Enable {
+		a.table.Store(nil)
+		return
+	}
+
+	table := &aclTable{
+		policy:     rules.Default,
+		anonymous:  make(map[int32]struct{}, len(rules.Anonymous)),
+		authorized: make(map[int32]struct{}, len(rules.Authorized)),
+		deny:       make(map[int32]map[int64]struct{}, len(rules.Deny)),
+	}
+
+	if table.policy == "" {
+		table.policy = ACLAuth
+	}
+
+	for _, route := range rules.Anonymous {
+		table.anonymous[route] = struct{}{}
+	}
+
+	for _, route := range rules.Authorized {
+		table.authorized[route] = struct{}{}
+	}
+
+	for _, rule := range rules.Deny {
+		uids, ok := table.deny[rule.Route]
+		if !ok {
+			uids = make(map[int64]struct{}, len(rule.UIDs))
+			table.deny[rule.Route] = uids
+		}
+
+		for _, uid := range rule.UIDs {
+			uids[uid] = struct{}{}
+		}
+	}
+
+	a.table.Store(table)
+}
+
+// 校验访问权限，允许访问时返回nil
+func (a *acl) check(uid int64, route int32) *codes.Code {
+	table := a.table.Load()
+	if table == nil {
+		return nil
+	}
+
+	if uids, ok := table.deny[route]; ok {
+		if len(uids) == 0 {
+			return codes.IllegalRequest
+		}
+
+		if _, ok = uids[uid]; ok && uid != 0 {
+			return codes.IllegalRequest
+		}
+	}
+
+	if _, ok := table.anonymous[route]; ok {
+		return nil
+	}
+
+	if _, ok := table.authorized[route]; ok {
+		if uid == 0 {
+			return codes.Unauthorized
+		}
+		return nil
+	}
+
+	switch table.policy {
+	case ACLAllow:
+		return nil
+	case ACLDeny:
+		return codes.IllegalRequest
+	default:
+		if uid == 0 {
+			return codes.Unauthorized
+		}
+		return nil
+	}
+}
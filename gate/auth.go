@@ -0,0 +1,234 @@
+package gate
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"gatesvr/codes"
+	"gatesvr/encoding/json"
+	"gatesvr/errors"
+	"gatesvr/log"
+	"gatesvr/packet"
+	"gatesvr/session"
+	"gatesvr/utils/xtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator 客户端认证器
+type Authenticator interface {
+	// Authenticate 认证客户端，认证成功时返回用户ID；返回的错误携带错误码时将错误码回复给客户端
+	Authenticate(ctx context.Context, args *AuthArgs) (int64, error)
+}
+
+// AuthenticatorFunc 认证函数
+type AuthenticatorFunc func(ctx context.Context, args *AuthArgs) (int64, error)
+
+// Authenticate 认证客户端
+func (fn AuthenticatorFunc) Authenticate(ctx context.Context, args *AuthArgs) (int64, error) {
+	return fn(ctx, args)
+}
+
+// AuthArgs 认证参数
+type AuthArgs struct {
+	CID  int64  // 连接ID
+	IP   string // 客户端IP地址
+	Data []byte // 认证消息数据
+}
+
+type authMessage struct {
+	Code    int    `json:"code"`          // 错误码
+	Message string `json:"message"`       // 错误消息
+	UID     int64  `json:"uid,omitempty"` // 用户ID
+}
+
+type guard struct {
+	gate    *Gate
+	pending sync.Map // 待认证连接的超时定时器（连接ID -> *time.Timer）
+}
+
+func newGuard(gate *Gate) *guard {
+	return &guard{gate: gate}
+}
+
+// 是否启用认证
+func (g *guard) enabled() bool {
+	return g.gate.opts.auth.authenticator != nil
+}
+
+// 连接打开后开始计时，超时未完成认证时关闭连接
+func (g *guard) watch(cid int64) {
+	if !g.enabled() {
+		return
+	}
+
+	timer := time.AfterFunc(g.gate.opts.auth.timeout, func() {
+		if _, ok := g.pending.LoadAndDelete(cid); !ok {
+			return
+		}
+
+		log.Warnf("authenticate timeout, cid: %d", cid)
+
+		_ = g.gate.session.Close(session.Conn, cid, true)
+	})
+
+	g.pending.Store(cid, timer)
+}
+
+// 移除连接的认证计时
+func (g *guard) remove(cid int64) {
+	if v, ok := g.pending.LoadAndDelete(cid); ok {
+		v.(*time.Timer).Stop()
+	}
+}
+
+// 处理认证消息，认证成功后自动绑定用户；认证失败时回复错误码并关闭连接
+func (g *guard) authenticate(ctx context.Context, cid int64, msg *packet.Message) {
+	code, uid := g.doAuthenticate(ctx, cid, msg.Buffer)
+
+	if err := g.reply(cid, msg.Seq, code, uid); err != nil {
+		log.Warnf("reply auth message failed, cid: %d uid: %d err: %v", cid, uid, err)
+	}
+
+	if code != codes.OK {
+		log.Warnf("authenticate failed, cid: %d code: %s", cid, code)
+
+		g.remove(cid)
+
+		_ = g.gate.session.Close(session.Conn, cid, false)
+	}
+}
+
+// 执行认证，返回结果码及认证的用户ID
+func (g *guard) doAuthenticate(ctx context.Context, cid int64, data []byte) (*codes.Code, int64) {
+	ip, err := g.gate.session.RemoteIP(session.Conn, cid)
+	if err != nil {
+		return codes.InternalError, 0
+	}
+
+	uid, err := g.gate.opts.auth.authenticator.Authenticate(ctx, &AuthArgs{CID: cid, IP: ip, Data: data})
+	if err != nil {
+		if code := errors.Code(err); code != nil && code != codes.OK {
+			return code, 0
+		}
+		return codes.Unauthorized, 0
+	}
+
+	if uid <= 0 {
+		return codes.Unauthorized, 0
+	}
+
+	// 先停止计时，避免绑定过程中因超时关闭连接
+	v, ok := g.pending.LoadAndDelete(cid)
+	if !ok {
+		return codes.DeadlineExceeded, 0
+	}
+	v.(*time.Timer).Stop()
+
+	if err = (&provider{gate: g.gate}).Bind(ctx, cid, uid); err != nil {
+		if errors.Is(err, errors.ErrAlreadyLoggedIn) {
+			return codes.AlreadyLoggedIn, 0
+		}
+
+		log.Errorf("bind authenticated user failed, cid: %d uid: %d err: %v", cid, uid, err)
+		return codes.InternalError, 0
+	}
+
+	return codes.OK, uid
+}
+
+// 回复认证消息
+func (g *guard) reply(cid int64, seq int32, code *codes.Code, uid int64) error {
+	buf, err := json.Marshal(&authMessage{Code: code.Code(), Message: code.Message(), UID: uid})
+	if err != nil {
+		return err
+	}
+
+	msg, err := packet.PackMessage(&packet.Message{Seq: seq, Route: g.gate.opts.auth.route, Buffer: buf})
+	if err != nil {
+		return err
+	}
+
+	return g.gate.session.Push(session.Conn, cid, msg)
+}
+
+type authRequest struct {
+	Token string `json:"token"` // 认证令牌
+}
+
+type authToken struct {
+	UID int64 `json:"uid"` // 用户ID
+	EXP int64 `json:"exp"` // 过期时间，为0时永不过期
+}
+
+type tokenAuthenticator struct {
+	secret []byte // 签名秘钥
+}
+
+// NewTokenAuthenticator 创建基于HMAC-SHA256签名令牌的认证器
+// 认证消息格式为：{"token":"..."}，令牌由SignAuthToken签发
+func NewTokenAuthenticator(secret string) Authenticator {
+	return &tokenAuthenticator{secret: []byte(secret)}
+}
+
+// SignAuthToken 签发认证令牌，格式为：base64(payload).base64(signature)；ttl为0时令牌永不过期
+func SignAuthToken(secret string, uid int64, ttl time.Duration) (string, error) {
+	token := &authToken{UID: uid}
+
+	if ttl > 0 {
+		token.EXP = xtime.Now().Add(ttl).Unix()
+	}
+
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(authMAC([]byte(secret), encoded)), nil
+}
+
+// Authenticate 校验认证令牌
+func (a *tokenAuthenticator) Authenticate(ctx context.Context, args *AuthArgs) (int64, error) {
+	req := &authRequest{}
+	if err := json.Unmarshal(args.Data, req); err != nil {
+		return 0, errors.NewError(err, codes.InvalidArgument)
+	}
+
+	encoded, signature, ok := strings.Cut(req.Token, ".")
+	if !ok {
+		return 0, errors.ErrInvalidArgument
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, authMAC(a.secret, encoded)) {
+		return 0, errors.ErrIllegalOperation
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, errors.ErrInvalidArgument
+	}
+
+	token := &authToken{}
+	if err = json.Unmarshal(payload, token); err != nil || token.UID <= 0 {
+		return 0, errors.ErrInvalidArgument
+	}
+
+	if token.EXP > 0 && xtime.Now().Unix() > token.EXP {
+		return 0, errors.ErrDeadlineExceeded
+	}
+
+	return token.UID, nil
+}
+
+// 计算签名
+func authMAC(secret []byte, encoded string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(encoded))
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,273 @@
+package gate
+
+import (
+	"context"
+	"gatesvr/cluster"
+	"gatesvr/encoding/json"
+	"gatesvr/log"
+	"gatesvr/network"
+	"gatesvr/packet"
+	"gatesvr/session"
+	"gatesvr/utils/xcall"
+	"gatesvr/utils/xtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const drainTickInterval = time.Second // 排空检测间隔时间
+
+type DrainProgress struct {
+	Draining  bool      // 是否正在排空
+	Total     int64     // 开始排空时的连接数
+	Closed    int64     // 排空过程中已关闭的连接数
+	Remaining int64     // 剩余连接数
+	StartTime time.Time // 开始排空时间
+}
+
+type reconnectMessage struct {
+	Endpoints []string `json:"endpoints"` // 可重连的网关地址，格式为：protocol://addr
+}
+
+type drainer struct {
+	gate      *Gate
+	total     atomic.Int64 // 开始排空时的连接数
+	closed    atomic.Int64 // 排空过程中已关闭的连接数
+	startTime atomic.Int64 // 开始排空时间
+	actives   sync.Map     // 连接最近活跃时间（连接ID -> *atomic.Int64）
+}
+
+func newDrainer(gate *Gate) *drainer {
+	return &drainer{gate: gate}
+}
+
+// 记录连接活跃
+func (d *drainer) active(cid int64) {
+	now := xtime.Now().UnixNano()
+
+	if v, ok := d.actives.Load(cid); ok {
+		v.(*atomic.Int64).Store(now)
+		return
+	}
+
+	v := &atomic.Int64{}
+	v.Store(now)
+	d.actives.Store(cid, v)
+}
+
+// 移除连接活跃记录
+func (d *drainer) remove(cid int64) {
+	d.actives.Delete(cid)
+}
+
+// 获取排空进度
+func (d *drainer) progress() DrainProgress {
+	remaining, _ := d.gate.session.Stat(session.Conn)
+
+	startTime := d.startTime.Load()
+	if startTime == 0 {
+		return DrainProgress{Remaining: remaining}
+	}
+
+	return DrainProgress{
+		Draining:  remaining > 0,
+		Total:     d.total.Load(),
+		Closed:    d.closed.Load(),
+		Remaining: remaining,
+		StartTime: time.Unix(0, startTime),
+	}
+}
+
+// 执行排空
+// 先推送重连通知，然后在排空窗口内按比例逐步关闭空闲连接，窗口结束后关闭剩余连接，超过最大等待时间后强制关闭
+func (d *drainer) drain() {
+	opts := d.gate.opts.drain
+	conns := d.gate.session.Conns()
+
+	d.total.Store(int64(len(conns)))
+	d.startTime.Store(xtime.Now().UnixNano())
+
+	if len(conns) == 0 {
+		return
+	}
+
+	log.Infof("gate start draining, connections: %d", len(conns))
+
+	d.notify()
+
+	// 未配置最大等待时间时，排空窗口结束后仍需强制关闭，避免慢消费连接的优雅关闭阻塞网关关闭
+	maxWaitTime := opts.maxWaitTime
+	if maxWaitTime <= 0 {
+		maxWaitTime = opts.window + defaultDrainGrace
+	}
+
+	deadline := time.NewTimer(maxWaitTime)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(drainTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if d.tick() {
+				return
+			}
+		case <-deadline.C:
+			conns = d.gate.session.Conns()
+			log.Warnf("gate drain timeout, force close remaining connections: %d", len(conns))
+			for _, conn := range conns {
+				if conn.Close(true) == nil {
+					d.closed.Add(1)
+				}
+			}
+			return
+		}
+	}
+}
+
+// 排空检测，返回是否已排空完毕
+func (d *drainer) tick() bool {
+	opts := d.gate.opts.drain
+	conns := d.gate.session.Conns()
+
+	if len(conns) == 0 {
+		log.Infof("gate drain completed, closed: %d", d.closed.Load())
+		return true
+	}
+
+	total := d.total.Load()
+	elapsed := xtime.Now().Sub(time.Unix(0, d.startTime.Load()))
+
+	// 排空窗口结束，关闭所有剩余连接
+	if elapsed >= opts.window {
+		for _, conn := range conns {
+			d.close(conn)
+		}
+		return false
+	}
+
+	// 按窗口进度计算本轮需要关闭的连接数，优先关闭空闲时间最长的连接
+	quota := int64(float64(total)*float64(elapsed)/float64(opts.window)) - (total - int64(len(conns)))
+	if quota > 0 {
+		idles := d.idles(conns, opts.idleTime)
+
+		for i := 0; i < len(idles) && int64(i) < quota; i++ {
+			d.close(idles[i])
+		}
+	}
+
+	log.Infof("gate draining, remaining: %d/%d", len(conns), total)
+
+	return false
+}
+
+// 获取空闲连接，按最近活跃时间升序排列
+func (d *drainer) idles(conns []network.Conn, idleTime time.Duration) []network.Conn {
+	type idle struct {
+		conn   network.Conn
+		active int64
+	}
+
+	line := xtime.Now().Add(-idleTime).UnixNano()
+	list := make([]idle, 0, len(conns))
+
+	for _, conn := range conns {
+		var active int64
+		if v, ok := d.actives.Load(conn.ID()); ok {
+			active = v.(*atomic.Int64).Load()
+		}
+
+		if active <= line {
+			list = append(list, idle{conn: conn, active: active})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].active < list[j].active
+	})
+
+	idles := make([]network.Conn, 0, len(list))
+	for _, item := range list {
+		idles = append(idles, item.conn)
+	}
+
+	return idles
+}
+
+// 优雅关闭连接
+// 优雅关闭需等待连接写出剩余消息，异步执行以免单个连接阻塞排空进度及强制关闭的超时检测
+func (d *drainer) close(conn network.Conn) {
+	if conn.State() != network.ConnOpened {
+		return
+	}
+
+	xcall.Go(func() {
+		if conn.Close() == nil {
+			d.closed.Add(1)
+		}
+	})
+}
+
+// 推送重连通知
+func (d *drainer) notify() {
+	route := d.gate.opts.drain.route
+	if route == 0 {
+		return
+	}
+
+	buf, err := json.Marshal(&reconnectMessage{Endpoints: d.alternates()})
+	if err != nil {
+		log.Errorf("marshal reconnect message failed: %v", err)
+		return
+	}
+
+	msg, err := packet.PackMessage(&packet.Message{Route: route, Buffer: buf})
+	if err != nil {
+		log.Errorf("pack reconnect message failed: %v", err)
+		return
+	}
+
+	n, err := d.gate.session.Broadcast(session.Conn, msg)
+	if err != nil {
+		log.Errorf("broadcast reconnect message failed: %v", err)
+		return
+	}
+
+	log.Infof("gate reconnect message pushed, connections: %d", n)
+}
+
+// 从注册中心获取可供重连的其他网关地址
+// 优先选择工作状态的网关，没有时退而选择繁忙状态的网关
+func (d *drainer) alternates() []string {
+	ctx, cancel := context.WithTimeout(d.gate.ctx, defaultTimeout)
+	defer cancel()
+
+	services, err := d.gate.opts.registry.Services(ctx, cluster.Gate.String())
+	if err != nil {
+		log.Errorf("fetch gate services failed: %v", err)
+		return nil
+	}
+
+	endpoints := make(map[string][]string, 2)
+	for _, service := range services {
+		if service.ID == d.gate.opts.id || service.Metadata == nil {
+			continue
+		}
+
+		listeners := service.Metadata[listenersMetadataKey]
+		if listeners == "" {
+			continue
+		}
+
+		endpoints[service.State] = append(endpoints[service.State], strings.Split(listeners, ",")...)
+	}
+
+	if list := endpoints[cluster.Work.String()]; len(list) > 0 {
+		return list
+	}
+
+	return endpoints[cluster.Busy.String()]
+}
@@ -0,0 +1,212 @@
+package gate
+
+import (
+	"gatesvr/config"
+	"gatesvr/etc"
+	"gatesvr/log"
+	"gatesvr/network/proxyproto"
+	"gatesvr/session"
+	"gatesvr/utils/xtime"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type IPRules struct {
+	Allow []string `json:"allow"` // 允许连接的地址段，为空时允许所有地址
+	Deny  []string `json:"deny"`  // 拒绝连接的地址段，优先于允许列表
+}
+
+type IPBan struct {
+	IP       string    // 被封禁的IP地址
+	Reason   string    // 封禁原因
+	ExpireAt time.Time // 解封时间
+}
+
+type filter struct {
+	gate     *Gate
+	table    atomic.Pointer[filterTable]
+	bans     sync.Map // 封禁记录（IP -> *ban）
+	offenses sync.Map // 连接的畸形消息计数（连接ID -> *atomic.Int64）
+}
+
+type filterTable struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+type ban struct {
+	IPBan
+	timer *time.Timer // 解封定时器
+}
+
+func newFilter(gate *Gate) *filter {
+	f := &filter{gate: gate}
+	f.load()
+
+	return f
+}
+
+// 监听地址过滤规则变更
+func (f *filter) watch() {
+	if pattern := f.gate.opts.ip.config; pattern != "" {
+		name, _, _ := strings.Cut(pattern, ".")
+		config.Watch(func(...string) { f.load() }, name)
+	}
+
+	etc.GetConfigurator().Watch(func(...string) { f.load() }, etcConfigName)
+}
+
+// 加载地址过滤规则
+// 优先级：配置中心 > 启动选项 > etc配置
+func (f *filter) load() {
+	var (
+		opts  = f.gate.opts.ip
+		rules *IPRules
+	)
+
+	if opts.config != "" && config.Has(opts.config) {
+		rules = &IPRules{}
+		if err := config.Get(opts.config).Scan(rules); err != nil {
+			log.Errorf("load ip rules from config failed: %v", err)
+			return
+		}
+	} else if opts.rules != nil {
+		rules = opts.rules
+	} else {
+		rules = &IPRules{}
+		if err := etc.Get(defaultIPKey).Scan(rules); err != nil {
+			log.Errorf("load ip rules from etc failed: %v", err)
+			return
+		}
+	}
+
+	allow, err := proxyproto.ParseCIDRs(rules.Allow)
+	if err != nil {
+		log.Errorf("parse ip allow list failed: %v", err)
+		return
+	}
+
+	deny, err := proxyproto.ParseCIDRs(rules.Deny)
+	if err != nil {
+		log.Errorf("parse ip deny list failed: %v", err)
+		return
+	}
+
+	if len(allow) == 0 && len(deny) == 0 {
+		f.table.Store(nil)
+		return
+	}
+
+	f.table.Store(&filterTable{allow: allow, deny: deny})
+}
+
+// 检测IP是否允许连接
+func (f *filter) allow(addr string) bool {
+	if _, ok := f.bans.Load(addr); ok {
+		return false
+	}
+
+	table := f.table.Load()
+	if table == nil {
+		return true
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	if contains(table.deny, ip) {
+		return false
+	}
+
+	return len(table.allow) == 0 || contains(table.allow, ip)
+}
+
+// 记录连接发送的畸形消息，达到阈值后封禁连接IP并关闭连接
+func (f *filter) malformed(cid int64) {
+	threshold := f.gate.opts.ip.banThreshold
+	if threshold <= 0 {
+		return
+	}
+
+	v, _ := f.offenses.LoadOrStore(cid, &atomic.Int64{})
+	if v.(*atomic.Int64).Add(1) != int64(threshold) {
+		return
+	}
+
+	ip, err := f.gate.session.RemoteIP(session.Conn, cid)
+	if err != nil {
+		return
+	}
+
+	f.ban(ip, f.gate.opts.ip.banDuration, "malformed packets")
+
+	_ = f.gate.session.Close(session.Conn, cid, true)
+}
+
+// 移除连接的畸形消息计数
+func (f *filter) remove(cid int64) {
+	f.offenses.Delete(cid)
+}
+
+// 封禁IP，duration为封禁时长
+func (f *filter) ban(ip string, duration time.Duration, reason string) {
+	b := &ban{IPBan: IPBan{IP: ip, Reason: reason, ExpireAt: xtime.Now().Add(duration)}}
+	b.timer = time.AfterFunc(duration, func() {
+		if f.bans.CompareAndDelete(ip, b) {
+			log.Infof("ip unbanned, ip: %s", ip)
+		}
+	})
+
+	if v, ok := f.bans.Swap(ip, b); ok {
+		v.(*ban).timer.Stop()
+	}
+
+	log.Warnf("ip banned, ip: %s duration: %v reason: %s", ip, duration, reason)
+}
+
+// 解封IP
+func (f *filter) unban(ip string) bool {
+	v, ok := f.bans.LoadAndDelete(ip)
+	if !ok {
+		return false
+	}
+
+	v.(*ban).timer.Stop()
+
+	log.Infof("ip unbanned, ip: %s", ip)
+
+	return true
+}
+
+// 获取所有封禁记录，按解封时间排序
+func (f *filter) list() []IPBan {
+	bans := make([]IPBan, 0)
+
+	f.bans.Range(func(_, value any) bool {
+		bans = append(bans, value.(*ban).IPBan)
+		return true
+	})
+
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].ExpireAt.Before(bans[j].ExpireAt)
+	})
+
+	return bans
+}
+
+// 检测地址段是否包含IP
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,18 +2,23 @@ package gate
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"gatesvr/cluster"
 	"gatesvr/component"
 	"gatesvr/core/info"
 	"gatesvr/core/net"
+	"gatesvr/errors"
 	"gatesvr/internal/transporter/gate"
 	"gatesvr/log"
 	"gatesvr/network"
 	"gatesvr/registry"
 	"gatesvr/session"
+	"gatesvr/utils/xtls"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Gate struct {
@@ -22,10 +27,22 @@ type Gate struct {
 	ctx      context.Context
 	cancel   context.CancelFunc
 	state    atomic.Int32
+	closing  atomic.Bool
 	proxy    *proxy
+	drainer  *drainer
+	limiter  *limiter
+	acl      *acl
+	resumer  *resumer
+	guard    *guard
+	sticky   *sticky
+	filter   *filter
+	reliable *reliable
+	tracer   *tracer
 	instance *registry.ServiceInstance
 	session  *session.Session
 	linker   *gate.Server
+	loader   *xtls.Loader
+	mu       sync.Mutex
 	wg       *sync.WaitGroup
 }
 
@@ -38,13 +55,39 @@ func NewGate(opts ...Option) *Gate {
 	g := &Gate{}
 	g.opts = o
 	g.ctx, g.cancel = context.WithCancel(o.ctx)
+	g.loader = newLoader(o)
 	g.proxy = newProxy(g)
+	g.drainer = newDrainer(g)
+	g.limiter = newLimiter(g)
+	g.acl = newACL(g)
+	g.resumer = newResumer(g)
+	g.guard = newGuard(g)
+	g.sticky = newSticky(g)
+	g.filter = newFilter(g)
+	g.reliable = newReliable(g)
+	g.tracer = newTracer(g)
 	g.session = session.NewSession()
+	g.session.SetLoginPolicy(o.login.policy, o.login.devices)
 	g.state.Store(int32(cluster.Shut))
 	g.wg = &sync.WaitGroup{}
 
 	return g
 }
+
+// 创建内部链接的证书加载器
+func newLoader(o *options) *xtls.Loader {
+	if o.tls.certFile == "" || o.tls.keyFile == "" {
+		return nil
+	}
+
+	loader, err := xtls.NewLoader(o.tls.certFile, o.tls.keyFile, o.tls.caFile)
+	if err != nil {
+		log.Fatalf("link tls certificate load failed: %v", err)
+	}
+
+	return loader
+}
+
 func (g *Gate) Name() string {
 	return g.opts.name
 }
@@ -55,10 +98,14 @@ func (g *Gate) Init() {
 		log.Fatal("instance id can not be empty")
 	}
 
-	if g.opts.server == nil {
+	if len(g.opts.servers) == 0 {
 		log.Fatal("server component is not injected")
 	}
 
+	if len(g.opts.servers) > session.MaxListeners {
+		log.Fatalf("the number of servers can not exceed %d", session.MaxListeners)
+	}
+
 	if g.opts.locator == nil {
 		log.Fatal("locator component is not injected")
 	}
@@ -66,6 +113,46 @@ func (g *Gate) Init() {
 	if g.opts.registry == nil {
 		log.Fatal("registry component is not injected")
 	}
+
+	if g.opts.resume.window > 0 && (g.opts.resume.secret == "" || g.opts.resume.route == 0) {
+		log.Fatal("resume secret and route can not be empty")
+	}
+
+	if g.guard.enabled() && g.opts.auth.route == 0 {
+		log.Fatal("auth route can not be empty")
+	}
+
+	if g.reliable.enabled() && (g.opts.reliable.window <= 0 || g.opts.reliable.timeout <= 0) {
+		log.Fatal("reliable push window and timeout must be greater than 0")
+	}
+
+	if g.reliable.enabled() && !g.reliable.probe() {
+		log.Fatal("reliable push requires the packet seq bytes to be greater than 0")
+	}
+
+	if g.opts.reply.route == 0 {
+		log.Fatal("error reply route can not be empty")
+	}
+
+	if g.opts.reply.codec == nil {
+		log.Fatal("error reply codec can not be empty")
+	}
+
+	if _, err := g.opts.reply.codec.Marshal(&errorMessage{}); err != nil {
+		log.Fatalf("error reply codec can not encode the error message: %v", err)
+	}
+
+	if g.opts.link.ordered < 0 || g.opts.link.unordered < 0 || g.opts.link.maxUnordered < 0 || g.opts.link.idleTimeout < 0 {
+		log.Fatal("link pool options can not be negative")
+	}
+
+	switch g.opts.login.policy {
+	case session.LoginReplace, session.LoginKick, session.LoginReject, session.LoginMulti:
+	default:
+		log.Fatalf("invalid login policy: %s", g.opts.login.policy)
+	}
+
+	g.tracer.install()
 }
 
 // Start 启动
@@ -82,23 +169,84 @@ func (g *Gate) Start() {
 
 	g.proxy.watch()
 
+	g.acl.watch()
+
+	g.filter.watch()
+
+	g.resumer.watch()
+
 	g.printInfo()
 
 }
 
 // Close 关闭节点
 func (g *Gate) Close() {
+	if !g.closing.CompareAndSwap(false, true) {
+		return
+	}
+
 	if !g.state.CompareAndSwap(int32(cluster.Work), int32(cluster.Hang)) {
 		if !g.state.CompareAndSwap(int32(cluster.Busy), int32(cluster.Hang)) {
-			return
+			// 可能已被远程挂起
+			if g.getState() != cluster.Hang {
+				return
+			}
 		}
 	}
 
-	g.refreshServiceInstance()
+	if err := g.refreshServiceInstance(); err != nil {
+		log.Fatalf("refresh cluster instance failed: %v", err)
+	}
+
+	g.drainer.drain()
+
+	g.resumer.flush()
 
 	g.wg.Wait()
 }
 
+// LimitStats 获取限流统计
+func (g *Gate) LimitStats() LimitStats {
+	return g.limiter.stats()
+}
+
+// OutboundStats 获取所有服务器的发送队列统计
+func (g *Gate) OutboundStats() network.OutboundStats {
+	var stats network.OutboundStats
+
+	for _, server := range g.opts.servers {
+		if stater, ok := server.(network.OutboundStater); ok {
+			s := stater.OutboundStats()
+			stats.DroppedOldest += s.DroppedOldest
+			stats.DroppedNewest += s.DroppedNewest
+			stats.Disconnected += s.Disconnected
+			stats.DroppedBytes += s.DroppedBytes
+		}
+	}
+
+	return stats
+}
+
+// BanIP 封禁IP，duration为封禁时长，封禁期间该IP的新连接将被拒绝
+func (g *Gate) BanIP(ip string, duration time.Duration, reason string) {
+	g.filter.ban(ip, duration, reason)
+}
+
+// UnbanIP 解封IP
+func (g *Gate) UnbanIP(ip string) bool {
+	return g.filter.unban(ip)
+}
+
+// IPBans 获取当前所有封禁记录
+func (g *Gate) IPBans() []IPBan {
+	return g.filter.list()
+}
+
+// DrainProgress 获取排空进度
+func (g *Gate) DrainProgress() DrainProgress {
+	return g.drainer.progress()
+}
+
 // Destroy 销毁组件
 func (g *Gate) Destroy() {
 	if !g.state.CompareAndSwap(int32(cluster.Hang), int32(cluster.Shut)) {
@@ -111,62 +259,151 @@ func (g *Gate) Destroy() {
 
 	g.stopLinkerServer()
 
+	g.reliable.close()
+
+	g.tracer.close()
+
+	if g.loader != nil {
+		_ = g.loader.Close()
+	}
+
 	g.cancel()
 }
 
 func (g *Gate) startNetworkServer() {
-	//定义回调
-	g.opts.server.OnConnect(g.handleConnect)
-	g.opts.server.OnDisconnect(g.handleDisconnect)
-	g.opts.server.OnReceive(g.handleReceive)
-
-	//启动服务
-	if err := g.opts.server.Start(); err != nil {
-		log.Fatalf("network server start failed: %v", err)
+	for i, server := range g.opts.servers {
+		//定义回调，不同监听器的连接ID经过包装后全局唯一
+		server.OnConnect(func(conn network.Conn) {
+			g.handleConnect(session.NewConn(conn, i))
+		})
+		server.OnDisconnect(func(conn network.Conn) {
+			g.handleDisconnect(session.NewConn(conn, i))
+		})
+		server.OnReceive(func(conn network.Conn, data []byte) {
+			g.handleReceive(session.NewConn(conn, i), data)
+		})
+
+		//启动服务
+		if err := server.Start(); err != nil {
+			log.Fatalf("network server start failed, protocol: %s addr: %s err: %v", server.Protocol(), server.Addr(), err)
+		}
 	}
 }
 
 // 停止网关服务器
 func (g *Gate) stopNetworkServer() {
-	if err := g.opts.server.Stop(); err != nil {
-		log.Errorf("network server stop failed: %v", err)
+	for _, server := range g.opts.servers {
+		if err := server.Stop(); err != nil {
+			log.Errorf("network server stop failed, protocol: %s addr: %s err: %v", server.Protocol(), server.Addr(), err)
+		}
 	}
 }
 
 // 处理连接打开
 func (g *Gate) handleConnect(conn network.Conn) {
+	// 挂起状态下不再接收新连接，已有连接不受影响
+	if g.getState() == cluster.Hang {
+		_ = conn.Close(true)
+		return
+	}
+
+	if ip, err := conn.RemoteIP(); err != nil || !g.filter.allow(ip) {
+		log.Warnf("connection rejected by ip filter, ip: %s", ip)
+		_ = conn.Close(true)
+		return
+	}
+
 	g.wg.Add(1)
 
 	g.session.AddConn(conn)
 
+	g.drainer.active(conn.ID())
+
+	g.guard.watch(conn.ID())
+
 	cid, uid := conn.ID(), conn.UID()
 
 	ctx, cancel := context.WithTimeout(g.ctx, g.opts.timeout)
-	g.proxy.trigger(ctx, cluster.Connect, cid, uid)
+	g.proxy.triggerAttrs(ctx, cluster.Connect, cid, uid, g.attrs(cid))
 	cancel()
 }
 
 // 处理断开连接
 func (g *Gate) handleDisconnect(conn network.Conn) {
-	g.session.RemConn(conn)
+	var ok, suspended bool
+
+	// 会话属性随连接移除，需在移除前获取
+	attrs := g.attrs(conn.ID())
+
+	// 启用断线恢复时挂起已绑定的用户，关闭过程中不再挂起
+	if g.resumer.enabled() && conn.UID() != 0 && !g.closing.Load() {
+		ok, suspended = g.session.Suspend(conn, g.opts.resume.bufferSize)
+	} else {
+		ok = g.session.RemConn(conn)
+	}
+
+	// 未被接收的连接无需处理
+	if !ok {
+		return
+	}
+
+	g.drainer.remove(conn.ID())
+
+	g.guard.remove(conn.ID())
+
+	g.sticky.remove(conn.ID())
+
+	g.filter.remove(conn.ID())
+
+	cid, uid := conn.ID(), conn.UID()
+
+	// 用户仍有其他设备在线时保留用户限流及网关绑定关系
+	online := false
+	if uid != 0 && !suspended {
+		online, _ = g.session.Has(session.User, uid)
+	}
+
+	if online {
+		g.limiter.remove(cid, 0)
+	} else {
+		g.limiter.remove(cid, uid)
+	}
+
+	if suspended {
+		g.resumer.suspend(cid, uid)
+	} else if uid != 0 && !online {
+		g.reliable.reset(uid)
 
-	if cid, uid := conn.ID(), conn.UID(); uid != 0 {
 		ctx, cancel := context.WithTimeout(g.ctx, g.opts.timeout)
 		_ = g.proxy.unbindGate(ctx, cid, uid)
-		g.proxy.trigger(ctx, cluster.Disconnect, cid, uid)
+		g.proxy.triggerAttrs(ctx, cluster.Disconnect, cid, uid, attrs)
 		cancel()
 	} else {
 		ctx, cancel := context.WithTimeout(g.ctx, g.opts.timeout)
-		g.proxy.trigger(ctx, cluster.Disconnect, cid, uid)
+		g.proxy.triggerAttrs(ctx, cluster.Disconnect, cid, uid, attrs)
 		cancel()
 	}
 
 	g.wg.Done()
 }
 
+// 获取触发事件时携带的会话属性，未开启时返回nil
+func (g *Gate) attrs(cid int64) map[string]string {
+	if !g.opts.attrs {
+		return nil
+	}
+
+	attrs, _ := g.session.Attrs(session.Conn, cid)
+
+	return attrs
+}
+
 // 处理接收到的消息
 func (g *Gate) handleReceive(conn network.Conn, data []byte) {
 	cid, uid := conn.ID(), conn.UID()
+
+	g.drainer.active(cid)
+
 	ctx, cancel := context.WithTimeout(g.ctx, g.opts.timeout)
 	g.proxy.deliver(ctx, cid, uid, data)
 	cancel()
@@ -175,7 +412,12 @@ func (g *Gate) handleReceive(conn network.Conn, data []byte) {
 // 启动传输服务器
 func (g *Gate) startLinkerServer() {
 	//创建服务器
-	transporter, err := gate.NewServer(g.opts.addr, &provider{gate: g})
+	var tlsConfig []*tls.Config
+	if g.loader != nil {
+		tlsConfig = append(tlsConfig, g.loader.ServerConfig())
+	}
+
+	transporter, err := gate.NewServer(g.opts.addr, &provider{gate: g}, tlsConfig...)
 	if err != nil {
 		log.Fatalf("link server create failed: %v", err)
 	}
@@ -197,6 +439,9 @@ func (g *Gate) stopLinkerServer() {
 }
 
 func (g *Gate) registerServiceInstance() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	g.instance = &registry.ServiceInstance{
 		ID:       g.opts.id,
 		Name:     cluster.Gate.String(),
@@ -205,6 +450,7 @@ func (g *Gate) registerServiceInstance() {
 		State:    g.getState().String(),
 		Weight:   g.opts.weight,
 		Endpoint: g.linker.Endpoint().String(),
+		Metadata: map[string]string{listenersMetadataKey: strings.Join(g.listeners(), ",")},
 	}
 
 	ctx, cancel := context.WithTimeout(g.ctx, defaultTimeout)
@@ -216,9 +462,12 @@ func (g *Gate) registerServiceInstance() {
 }
 
 // 刷新服务实例状态
-func (g *Gate) refreshServiceInstance() {
+func (g *Gate) refreshServiceInstance() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	if g.instance == nil {
-		return
+		return nil
 	}
 
 	g.instance.State = g.getState().String()
@@ -226,9 +475,7 @@ func (g *Gate) refreshServiceInstance() {
 	ctx, cancel := context.WithTimeout(g.ctx, defaultTimeout)
 	defer cancel()
 
-	if err := g.opts.registry.Register(ctx, g.instance); err != nil {
-		log.Fatalf("refresh cluster instance failed: %v", err)
-	}
+	return g.opts.registry.Register(ctx, g.instance)
 }
 
 // 解注册服务实例
@@ -246,13 +493,65 @@ func (g *Gate) getState() cluster.State {
 	return cluster.State(g.state.Load())
 }
 
+// 设置状态
+// 仅允许在工作、繁忙、挂起状态间切换，关闭状态由网关生命周期管理
+func (g *Gate) setState(state cluster.State) error {
+	switch state {
+	case cluster.Work, cluster.Busy, cluster.Hang:
+	default:
+		return errors.ErrIllegalOperation
+	}
+
+	for {
+		if g.closing.Load() {
+			return errors.ErrIllegalOperation
+		}
+
+		old := g.getState()
+
+		switch old {
+		case cluster.Work, cluster.Busy, cluster.Hang:
+		default:
+			return errors.ErrIllegalOperation
+		}
+
+		if old == state {
+			return nil
+		}
+
+		if g.state.CompareAndSwap(int32(old), int32(state)) {
+			log.Infof("gate state changed: %s -> %s", old, state)
+			break
+		}
+	}
+
+	if err := g.refreshServiceInstance(); err != nil {
+		log.Errorf("refresh cluster instance failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// 获取所有监听器，格式为：protocol://addr
+func (g *Gate) listeners() []string {
+	listeners := make([]string, 0, len(g.opts.servers))
+	for _, server := range g.opts.servers {
+		listeners = append(listeners, fmt.Sprintf("%s://%s", server.Protocol(), net.FulfillAddr(server.Addr())))
+	}
+
+	return listeners
+}
+
 // 打印组件信息
 func (g *Gate) printInfo() {
-	infos := make([]string, 0, 6)
+	infos := make([]string, 0, 5+len(g.opts.servers))
 	infos = append(infos, fmt.Sprintf("ID: %s", g.opts.id))
 	infos = append(infos, fmt.Sprintf("Name: %s", g.Name()))
 	infos = append(infos, fmt.Sprintf("Link: %s", g.linker.ExposeAddr()))
-	infos = append(infos, fmt.Sprintf("Server: [%s] %s", g.opts.server.Protocol(), net.FulfillAddr(g.opts.server.Addr())))
+	for _, server := range g.opts.servers {
+		infos = append(infos, fmt.Sprintf("Server: [%s] %s", server.Protocol(), net.FulfillAddr(server.Addr())))
+	}
 	infos = append(infos, fmt.Sprintf("Locator: %s", g.opts.locator.Name()))
 	infos = append(infos, fmt.Sprintf("Registry: %s", g.opts.registry.Name()))
 
@@ -0,0 +1,252 @@
+package gate
+
+import (
+	"gatesvr/codes"
+	"gatesvr/log"
+	"gatesvr/packet"
+	"gatesvr/session"
+	"gatesvr/utils/xtime"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	LimitDrop       LimitPolicy = "drop"       // 丢弃被限流的消息
+	LimitReply      LimitPolicy = "reply"      // 丢弃被限流的消息并回复TooManyRequests错误
+	LimitDisconnect LimitPolicy = "disconnect" // 丢弃被限流的消息，连接被限流次数达到阈值后断开连接
+)
+
+type LimitPolicy string
+
+type Limit struct {
+	Rate  float64 `json:"rate"`  // 每秒产生的令牌数，为0时不限流
+	Burst int     `json:"burst"` // 令牌桶容量，小于1时取1
+}
+
+type RouteLimit struct {
+	Route int32   `json:"route"` // 路由ID
+	Rate  float64 `json:"rate"`  // 每秒产生的令牌数
+	Burst int     `json:"burst"` // 令牌桶容量
+}
+
+type LimitStats struct {
+	Throttled    int64           // 被限流的消息总数
+	Global       int64           // 被全局限流的消息数
+	Route        int64           // 被路由限流的消息数
+	User         int64           // 被用户限流的消息数
+	Conn         int64           // 被连接限流的消息数
+	Replied      int64           // 回复TooManyRequests错误的消息数
+	Disconnected int64           // 因限流被断开的连接数
+	Routes       map[int32]int64 // 配置了路由限流的各路由被限流的消息数
+	Other        int64           // 未配置路由限流的路由被限流的消息数
+}
+
+type limiter struct {
+	gate            *Gate
+	global          *bucket                 // 全局令牌桶
+	routes          map[int32]*bucket       // 路由令牌桶
+	conns           sync.Map                // 连接令牌桶（连接ID -> *connBucket）
+	users           sync.Map                // 用户令牌桶（用户ID -> *bucket）
+	throttled       atomic.Int64            // 被限流的消息总数
+	globalThrottled atomic.Int64            // 被全局限流的消息数
+	routeThrottled  atomic.Int64            // 被路由限流的消息数
+	userThrottled   atomic.Int64            // 被用户限流的消息数
+	connThrottled   atomic.Int64            // 被连接限流的消息数
+	replied         atomic.Int64            // 回复TooManyRequests错误的消息数
+	disconnected    atomic.Int64            // 因限流被断开的连接数
+	routeCounts     map[int32]*atomic.Int64 // 配置了路由限流的路由的限流计数，创建后不再修改
+	otherThrottled  atomic.Int64            // 未配置路由限流的路由的限流计数
+}
+
+type connBucket struct {
+	*bucket
+	offenses atomic.Int64 // 被限流次数
+}
+
+func newLimiter(gate *Gate) *limiter {
+	opts := gate.opts.limit
+
+	l := &limiter{gate: gate, global: newBucket(opts.global)}
+
+	if len(opts.routes) > 0 {
+		l.routes = make(map[int32]*bucket, len(opts.routes))
+		l.routeCounts = make(map[int32]*atomic.Int64, len(opts.routes))
+		for route, limit := range opts.routes {
+			if b := newBucket(limit); b != nil {
+				l.routes[route] = b
+				l.routeCounts[route] = &atomic.Int64{}
+			}
+		}
+	}
+
+	return l
+}
+
+// 是否允许通过，依次校验连接、用户、路由及全局限流
+func (l *limiter) allow(cid, uid int64, route int32) bool {
+	now := xtime.Now().UnixNano()
+	opts := l.gate.opts.limit
+
+	if opts.conn.Rate > 0 {
+		v, ok := l.conns.Load(cid)
+		if !ok {
+			v, _ = l.conns.LoadOrStore(cid, &connBucket{bucket: newBucket(opts.conn)})
+		}
+
+		if !v.(*connBucket).take(now) {
+			l.connThrottled.Add(1)
+			return l.reject(route)
+		}
+	}
+
+	if uid != 0 && opts.user.Rate > 0 {
+		v, ok := l.users.Load(uid)
+		if !ok {
+			v, _ = l.users.LoadOrStore(uid, newBucket(opts.user))
+		}
+
+		if !v.(*bucket).take(now) {
+			l.userThrottled.Add(1)
+			return l.reject(route)
+		}
+	}
+
+	if b, ok := l.routes[route]; ok && !b.take(now) {
+		l.routeThrottled.Add(1)
+		return l.reject(route)
+	}
+
+	if l.global != nil && !l.global.take(now) {
+		l.globalThrottled.Add(1)
+		return l.reject(route)
+	}
+
+	return true
+}
+
+// 记录限流
+// 仅为配置了路由限流的路由单独计数，避免客户端以任意路由ID无限占用内存
+func (l *limiter) reject(route int32) bool {
+	l.throttled.Add(1)
+
+	if count, ok := l.routeCounts[route]; ok {
+		count.Add(1)
+	} else {
+		l.otherThrottled.Add(1)
+	}
+
+	return false
+}
+
+// 处理被限流的消息
+func (l *limiter) throttle(cid, uid int64, msg *packet.Message) {
+	switch l.gate.opts.limit.policy {
+	case LimitReply:
+		if err := l.gate.proxy.respond(cid, msg.Seq, msg.Route, codes.TooManyRequests); err != nil {
+			log.Warnf("reply too many requests failed, cid: %d uid: %d route: %d err: %v", cid, uid, msg.Route, err)
+		} else {
+			l.replied.Add(1)
+		}
+	case LimitDisconnect:
+		v, ok := l.conns.Load(cid)
+		if !ok {
+			v, _ = l.conns.LoadOrStore(cid, &connBucket{})
+		}
+
+		bucket := v.(*connBucket)
+
+		// 达到阈值后的每次限流都尝试断开，避免关闭失败后连接不再被断开
+		if bucket.offenses.Add(1) < int64(l.gate.opts.limit.offenses) {
+			return
+		}
+
+		log.Warnf("connection is disconnected due to too many requests, cid: %d uid: %d", cid, uid)
+
+		if err := l.gate.session.Close(session.Conn, cid, true); err == nil {
+			bucket.offenses.Store(0)
+			l.disconnected.Add(1)
+		}
+	}
+}
+
+// 移除连接及用户的令牌桶
+func (l *limiter) remove(cid, uid int64) {
+	l.conns.Delete(cid)
+
+	if uid != 0 {
+		l.users.Delete(uid)
+	}
+}
+
+// 获取限流统计
+func (l *limiter) stats() LimitStats {
+	stats := LimitStats{
+		Throttled:    l.throttled.Load(),
+		Global:       l.globalThrottled.Load(),
+		Route:        l.routeThrottled.Load(),
+		User:         l.userThrottled.Load(),
+		Conn:         l.connThrottled.Load(),
+		Replied:      l.replied.Load(),
+		Disconnected: l.disconnected.Load(),
+		Routes:       make(map[int32]int64, len(l.routeCounts)),
+		Other:        l.otherThrottled.Load(),
+	}
+
+	for route, count := range l.routeCounts {
+		stats.Routes[route] = count.Load()
+	}
+
+	return stats
+}
+
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64 // 每纳秒产生的令牌数
+	burst  float64 // 令牌桶容量
+	tokens float64 // 当前令牌数
+	last   int64   // 上次更新时间
+}
+
+func newBucket(limit Limit) *bucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &bucket{
+		rate:   limit.Rate / 1e9,
+		burst:  burst,
+		tokens: burst,
+		last:   xtime.Now().UnixNano(),
+	}
+}
+
+// 获取令牌
+func (b *bucket) take(now int64) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now - b.last; elapsed > 0 {
+		b.tokens += float64(elapsed) * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
@@ -0,0 +1,42 @@
+package gate
+
+import (
+	"gatesvr/log"
+	"gatesvr/network"
+	"gatesvr/packet"
+	"gatesvr/utils/xcall"
+)
+
+// 踢出用户的旧设备，配置了踢出通知时先推送通知消息再关闭连接
+func (g *Gate) kick(uid int64, conns []network.Conn) {
+	if len(conns) == 0 {
+		return
+	}
+
+	var msg []byte
+	if route := g.opts.login.kickRoute; route != 0 {
+		buf, err := packet.PackMessage(&packet.Message{Route: route, Buffer: g.opts.login.kickMessage})
+		if err != nil {
+			log.Warnf("pack kick message failed, uid: %d err: %v", uid, err)
+		} else {
+			msg = buf
+		}
+	}
+
+	for _, conn := range conns {
+		if msg != nil {
+			if err := conn.Push(msg); err != nil {
+				log.Warnf("push kick message failed, cid: %d uid: %d err: %v", conn.ID(), uid, err)
+			}
+		}
+
+		// 非强制关闭，保证通知消息发送完成后再关闭连接；异步关闭，避免阻塞绑定流程
+		xcall.Go(func() {
+			if err := conn.Close(); err != nil {
+				log.Warnf("close kicked connection failed, cid: %d uid: %d err: %v", conn.ID(), uid, err)
+			}
+		})
+
+		log.Infof("device kicked, cid: %d uid: %d", conn.ID(), uid)
+	}
+}
@@ -2,20 +2,43 @@ package gate
 
 import (
 	"context"
+	"gatesvr/encoding"
 	"gatesvr/etc"
 	"gatesvr/locate"
 	"gatesvr/locate/redis"
 	"gatesvr/network"
 	"gatesvr/registry"
+	"gatesvr/session"
+	"gatesvr/trace"
 	"gatesvr/utils/xuuid"
 	"time"
 )
 
 const (
-	defaultName    = "gate"          // 默认名称
-	defaultAddr    = ":0"            // 连接器监听地址
-	defaultTimeout = 3 * time.Second // 默认超时时间
-	defaultWeight  = 1               // 默认权重
+	defaultName          = "gate"           // 默认名称
+	defaultAddr          = ":0"             // 连接器监听地址
+	defaultTimeout       = 3 * time.Second  // 默认超时时间
+	defaultWeight        = 1                // 默认权重
+	defaultDrainWindow   = 30 * time.Second // 默认排空窗口时间
+	defaultDrainIdleTime = 5 * time.Second  // 默认排空时连接的空闲判定时间
+	defaultDrainGrace    = 10 * time.Second // 默认排空窗口结束后至强制关闭剩余连接的等待时间
+	defaultLimitPolicy   = LimitDrop        // 默认限流策略
+	defaultLimitOffenses = 10               // 默认断开连接前允许的被限流次数
+	defaultResumeBuffer  = 100              // 默认挂起期间缓存的消息数
+	defaultAuthTimeout   = 10 * time.Second // 默认认证超时时间
+	defaultBanDuration   = 10 * time.Minute // 默认自动封禁时长
+	defaultLoginPolicy   = session.LoginReplace
+	defaultAckWindow     = 64              // 默认单个用户的待确认消息数上限
+	defaultAckTimeout    = 5 * time.Second // 默认确认超时时间
+	defaultAckRetries    = 3               // 默认确认超时后的重传次数
+	defaultReplyRoute    = -1              // 默认错误回复路由
+	defaultReplyCodec    = "json"          // 默认错误回复编解码器
+)
+
+var defaultKickMessage = []byte(`{"reason":"logged in on another device"}`) // 默认踢出通知消息
+
+const (
+	listenersMetadataKey = "listeners" // 监听器元数据键
 )
 
 const (
@@ -26,6 +49,96 @@ const (
 	defaultWeightKey  = "etc.cluster.gate.weight"
 )
 
+const (
+	defaultEventAttrsKey = "etc.cluster.gate.eventAttrs"
+)
+
+const (
+	defaultTLSCertFileKey   = "etc.cluster.gate.tls.certFile"
+	defaultTLSKeyFileKey    = "etc.cluster.gate.tls.keyFile"
+	defaultTLSCAFileKey     = "etc.cluster.gate.tls.caFile"
+	defaultTLSServerNameKey = "etc.cluster.gate.tls.serverName"
+)
+
+const (
+	defaultDrainRouteKey          = "etc.cluster.gate.drain.route"
+	defaultDrainWindowKey         = "etc.cluster.gate.drain.window"
+	defaultDrainIdleTimeKey       = "etc.cluster.gate.drain.idleTime"
+	defaultShutdownMaxWaitTimeKey = "etc.shutdownMaxWaitTime"
+)
+
+const (
+	defaultLimitGlobalKey   = "etc.cluster.gate.limit.global"
+	defaultLimitConnKey     = "etc.cluster.gate.limit.conn"
+	defaultLimitUserKey     = "etc.cluster.gate.limit.user"
+	defaultLimitRoutesKey   = "etc.cluster.gate.limit.routes"
+	defaultLimitPolicyKey   = "etc.cluster.gate.limit.policy"
+	defaultLimitOffensesKey = "etc.cluster.gate.limit.offenses"
+)
+
+const (
+	defaultResumeWindowKey     = "etc.cluster.gate.resume.window"
+	defaultResumeSecretKey     = "etc.cluster.gate.resume.secret"
+	defaultResumeRouteKey      = "etc.cluster.gate.resume.route"
+	defaultResumeBufferSizeKey = "etc.cluster.gate.resume.bufferSize"
+)
+
+const (
+	defaultAuthRouteKey   = "etc.cluster.gate.auth.route"
+	defaultAuthSecretKey  = "etc.cluster.gate.auth.secret"
+	defaultAuthTimeoutKey = "etc.cluster.gate.auth.timeout"
+)
+
+const (
+	defaultReplyRouteKey    = "etc.cluster.gate.reply.route"
+	defaultReplyDisabledKey = "etc.cluster.gate.reply.disabled"
+	defaultReplyCodecKey    = "etc.cluster.gate.reply.codec"
+)
+
+const (
+	defaultStickyRouteKey = "etc.cluster.gate.sticky.route"
+)
+
+const (
+	defaultIPKey             = "etc.cluster.gate.ip"
+	defaultIPConfigKey       = "etc.cluster.gate.ip.config"
+	defaultIPBanThresholdKey = "etc.cluster.gate.ip.banThreshold"
+	defaultIPBanDurationKey  = "etc.cluster.gate.ip.banDuration"
+)
+
+const (
+	defaultLoginPolicyKey      = "etc.cluster.gate.login.policy"
+	defaultLoginDevicesKey     = "etc.cluster.gate.login.devices"
+	defaultLoginKickRouteKey   = "etc.cluster.gate.login.kickRoute"
+	defaultLoginKickMessageKey = "etc.cluster.gate.login.kickMessage"
+)
+
+const (
+	defaultReliableAckKey     = "etc.cluster.gate.reliable.ack"
+	defaultReliableRoutesKey  = "etc.cluster.gate.reliable.routes"
+	defaultReliableWindowKey  = "etc.cluster.gate.reliable.window"
+	defaultReliableTimeoutKey = "etc.cluster.gate.reliable.timeout"
+	defaultReliableRetriesKey = "etc.cluster.gate.reliable.retries"
+)
+
+const (
+	defaultLinkOrderedKey      = "etc.cluster.gate.link.ordered"
+	defaultLinkUnorderedKey    = "etc.cluster.gate.link.unordered"
+	defaultLinkMaxUnorderedKey = "etc.cluster.gate.link.maxUnordered"
+	defaultLinkIdleTimeoutKey  = "etc.cluster.gate.link.idleTimeout"
+)
+
+const (
+	defaultTraceEnableKey = "etc.cluster.gate.trace.enable"
+	defaultTraceRouteKey  = "etc.cluster.gate.trace.route"
+	defaultTraceFileKey   = "etc.cluster.gate.trace.file"
+)
+
+const (
+	defaultACLKey       = "etc.cluster.gate.acl"
+	defaultACLConfigKey = "etc.cluster.gate.acl.config"
+)
+
 type options struct {
 	ctx      context.Context   // 上下文
 	id       string            // 实例ID
@@ -33,9 +146,110 @@ type options struct {
 	addr     string            // 监听地址
 	timeout  time.Duration     // RPC调用超时时间
 	weight   int               // 权重
-	server   network.Server    // 网关服务器
+	servers  []network.Server  // 网关服务器
 	locator  locate.Locator    // 用户定位器
 	registry registry.Registry // 服务注册器
+	tls      tlsOptions        // 内部链接TLS配置
+	drain    drainOptions      // 排空配置
+	limit    limitOptions      // 限流配置
+	acl      aclOptions        // 访问控制配置
+	resume   resumeOptions     // 断线恢复配置
+	auth     authOptions       // 客户端认证配置
+	reply    replyOptions      // 错误回复配置
+	sticky   stickyOptions     // 节点指定配置
+	ip       ipOptions         // 地址过滤配置
+	login    loginOptions      // 多设备登录配置
+	reliable reliableOptions   // 可靠推送配置
+	link     linkOptions       // 内部链接连接池配置
+	trace    traceOptions      // 链路追踪配置
+	attrs    bool              // 触发连接事件时是否携带会话属性
+}
+
+type tlsOptions struct {
+	certFile   string // 证书文件，配置后内部链接启用TLS
+	keyFile    string // 秘钥文件
+	caFile     string // CA证书文件，配置后启用双向认证
+	serverName string // 校验对端证书时使用的服务器名称
+}
+
+type drainOptions struct {
+	route       int32         // 重连通知消息路由，为0时不推送重连通知
+	window      time.Duration // 排空窗口时间，窗口内逐步关闭空闲连接，窗口结束后关闭所有连接
+	idleTime    time.Duration // 空闲判定时间，超过该时间未收到消息的连接视为空闲连接
+	maxWaitTime time.Duration // 最大等待时间，超时后强制关闭剩余连接，为0时在排空窗口结束后等待defaultDrainGrace强制关闭
+}
+
+type aclOptions struct {
+	rules  *ACLRules // 访问控制规则
+	config string    // 配置中心中访问控制规则的配置路径，配置后优先使用配置中心的规则
+}
+
+type resumeOptions struct {
+	window     time.Duration // 断线恢复窗口时间，为0时不启用断线恢复
+	secret     string        // 恢复令牌签名秘钥，同一集群内的网关需保持一致
+	route      int32         // 恢复令牌下发及恢复请求的消息路由
+	bufferSize int           // 挂起期间缓存的消息数，超出后丢弃最早的消息
+}
+
+type authOptions struct {
+	authenticator Authenticator // 认证器，为nil时不启用认证
+	route         int32         // 认证消息路由
+	timeout       time.Duration // 认证超时时间，连接打开后超时未完成认证时关闭连接
+}
+
+type replyOptions struct {
+	route  int32          // 错误回复路由，不可为0
+	routes map[int32]bool // 路由的错误回复开关，未配置的路由默认回复
+	codec  encoding.Codec // 错误回复消息的编解码器
+}
+
+type stickyOptions struct {
+	route int32 // 节点指定消息路由，为0时不允许客户端指定节点
+}
+
+type loginOptions struct {
+	policy      session.LoginPolicy // 多设备登录策略
+	devices     int                 // multi策略下最大同时在线设备数，为0时不限制
+	kickRoute   int32               // 踢出通知消息路由，为0时不通知直接关闭连接
+	kickMessage []byte              // 踢出通知消息内容
+}
+
+type reliableOptions struct {
+	ack     int32          // 客户端确认消息路由，为0时不启用可靠推送
+	routes  map[int32]bool // 需要可靠推送的路由
+	window  int            // 单个用户的待确认消息数上限，超出后拒绝推送
+	timeout time.Duration  // 确认超时时间，超时后重传消息
+	retries int            // 确认超时后的重传次数，耗尽后投递失败
+}
+
+type linkOptions struct {
+	ordered      int           // 每个目标实例的有序消息连接数，为0时使用默认值20
+	unordered    int           // 每个目标实例的无序消息连接数，为0时使用默认值10
+	maxUnordered int           // 每个目标实例的最大无序消息连接数，写入积压时按需扩容，不大于unordered时不扩容
+	idleTimeout  time.Duration // 空闲连接回收时间，为0时不回收
+}
+
+type traceOptions struct {
+	enable   bool           // 是否启用链路追踪，启用后为每条投递的消息记录跨度
+	route    int32          // 链路封装消息路由，客户端可通过该路由传入链路上下文，为0时不接收客户端链路
+	file     string         // 跨度导出文件，为空时使用默认导出文件
+	exporter trace.Exporter // 跨度导出器，设置后优先于导出文件
+}
+
+type ipOptions struct {
+	rules        *IPRules      // 地址过滤规则
+	config       string        // 配置中心中地址过滤规则的配置路径，配置后优先使用配置中心的规则
+	banThreshold int           // 单个连接发送畸形消息达到该数量后封禁其IP，为0时不自动封禁
+	banDuration  time.Duration // 自动封禁时长
+}
+
+type limitOptions struct {
+	global   Limit           // 全局限流
+	conn     Limit           // 单个连接限流
+	user     Limit           // 单个用户限流
+	routes   map[int32]Limit // 路由限流
+	policy   LimitPolicy     // 限流策略，默认drop
+	offenses int             // disconnect策略下断开连接前允许的被限流次数，默认10
 }
 type Option func(o *options)
 
@@ -46,6 +260,32 @@ func defaultOptions() *options {
 		addr:    defaultAddr,
 		timeout: defaultTimeout,
 		weight:  defaultWeight,
+		drain: drainOptions{
+			window:   defaultDrainWindow,
+			idleTime: defaultDrainIdleTime,
+		},
+		limit: limitOptions{
+			policy:   defaultLimitPolicy,
+			offenses: defaultLimitOffenses,
+		},
+		resume: resumeOptions{
+			bufferSize: defaultResumeBuffer,
+		},
+		auth: authOptions{
+			timeout: defaultAuthTimeout,
+		},
+		ip: ipOptions{
+			banDuration: defaultBanDuration,
+		},
+		login: loginOptions{
+			policy:      defaultLoginPolicy,
+			kickMessage: defaultKickMessage,
+		},
+		reliable: reliableOptions{
+			window:  defaultAckWindow,
+			timeout: defaultAckTimeout,
+			retries: defaultAckRetries,
+		},
 	}
 
 	if id := etc.Get(defaultIDKey).String(); id != "" {
@@ -70,6 +310,125 @@ func defaultOptions() *options {
 		opts.weight = weight
 	}
 
+	opts.attrs = etc.Get(defaultEventAttrsKey).Bool()
+
+	opts.tls.certFile = etc.Get(defaultTLSCertFileKey).String()
+	opts.tls.keyFile = etc.Get(defaultTLSKeyFileKey).String()
+	opts.tls.caFile = etc.Get(defaultTLSCAFileKey).String()
+	opts.tls.serverName = etc.Get(defaultTLSServerNameKey).String()
+
+	opts.drain.route = etc.Get(defaultDrainRouteKey).Int32()
+	opts.drain.maxWaitTime = etc.Get(defaultShutdownMaxWaitTimeKey).Duration()
+
+	if window := etc.Get(defaultDrainWindowKey).Duration(); window > 0 {
+		opts.drain.window = window
+	}
+
+	if idleTime := etc.Get(defaultDrainIdleTimeKey).Duration(); idleTime > 0 {
+		opts.drain.idleTime = idleTime
+	}
+
+	_ = etc.Get(defaultLimitGlobalKey).Scan(&opts.limit.global)
+	_ = etc.Get(defaultLimitConnKey).Scan(&opts.limit.conn)
+	_ = etc.Get(defaultLimitUserKey).Scan(&opts.limit.user)
+
+	var routes []RouteLimit
+	if err := etc.Get(defaultLimitRoutesKey).Scan(&routes); err == nil {
+		for _, r := range routes {
+			WithLimitRoute(r.Route, r.Rate, r.Burst)(opts)
+		}
+	}
+
+	if policy := etc.Get(defaultLimitPolicyKey).String(); policy != "" {
+		opts.limit.policy = LimitPolicy(policy)
+	}
+
+	if offenses := etc.Get(defaultLimitOffensesKey).Int(); offenses > 0 {
+		opts.limit.offenses = offenses
+	}
+
+	opts.acl.config = etc.Get(defaultACLConfigKey).String()
+
+	opts.resume.window = etc.Get(defaultResumeWindowKey).Duration()
+	opts.resume.secret = etc.Get(defaultResumeSecretKey).String()
+	opts.resume.route = etc.Get(defaultResumeRouteKey).Int32()
+
+	if bufferSize := etc.Get(defaultResumeBufferSizeKey).Int(); bufferSize > 0 {
+		opts.resume.bufferSize = bufferSize
+	}
+
+	opts.reply.route = defaultReplyRoute
+	if route := etc.Get(defaultReplyRouteKey).Int32(); route != 0 {
+		opts.reply.route = route
+	}
+
+	var disabled []int32
+	if err := etc.Get(defaultReplyDisabledKey).Scan(&disabled); err == nil {
+		for _, route := range disabled {
+			WithErrorReplySwitch(route, false)(opts)
+		}
+	}
+
+	opts.reply.codec = encoding.Invoke(etc.Get(defaultReplyCodecKey, defaultReplyCodec).String())
+
+	opts.ip.config = etc.Get(defaultIPConfigKey).String()
+	opts.ip.banThreshold = etc.Get(defaultIPBanThresholdKey).Int()
+
+	if banDuration := etc.Get(defaultIPBanDurationKey).Duration(); banDuration > 0 {
+		opts.ip.banDuration = banDuration
+	}
+
+	opts.sticky.route = etc.Get(defaultStickyRouteKey).Int32()
+
+	if policy := etc.Get(defaultLoginPolicyKey).String(); policy != "" {
+		opts.login.policy = session.LoginPolicy(policy)
+	}
+
+	opts.login.devices = etc.Get(defaultLoginDevicesKey).Int()
+	opts.login.kickRoute = etc.Get(defaultLoginKickRouteKey).Int32()
+
+	if message := etc.Get(defaultLoginKickMessageKey).String(); message != "" {
+		opts.login.kickMessage = []byte(message)
+	}
+
+	opts.reliable.ack = etc.Get(defaultReliableAckKey).Int32()
+
+	var reliableRoutes []int32
+	if err := etc.Get(defaultReliableRoutesKey).Scan(&reliableRoutes); err == nil && len(reliableRoutes) > 0 {
+		WithReliablePush(opts.reliable.ack, reliableRoutes...)(opts)
+	}
+
+	if window := etc.Get(defaultReliableWindowKey).Int(); window > 0 {
+		opts.reliable.window = window
+	}
+
+	if timeout := etc.Get(defaultReliableTimeoutKey).Duration(); timeout > 0 {
+		opts.reliable.timeout = timeout
+	}
+
+	if retries := etc.Get(defaultReliableRetriesKey).Int(); retries > 0 {
+		opts.reliable.retries = retries
+	}
+
+	opts.link.ordered = etc.Get(defaultLinkOrderedKey).Int()
+	opts.link.unordered = etc.Get(defaultLinkUnorderedKey).Int()
+	opts.link.maxUnordered = etc.Get(defaultLinkMaxUnorderedKey).Int()
+	opts.link.idleTimeout = etc.Get(defaultLinkIdleTimeoutKey).Duration()
+
+	opts.trace.enable = etc.Get(defaultTraceEnableKey).Bool()
+	opts.trace.route = etc.Get(defaultTraceRouteKey).Int32()
+	opts.trace.file = etc.Get(defaultTraceFileKey).String()
+
+	opts.auth.route = etc.Get(defaultAuthRouteKey).Int32()
+
+	if secret := etc.Get(defaultAuthSecretKey).String(); secret != "" {
+		opts.auth.authenticator = NewTokenAuthenticator(secret)
+	}
+
+	if timeout := etc.Get(defaultAuthTimeoutKey).Duration(); timeout > 0 {
+		opts.auth.timeout = timeout
+	}
+
 	return opts
 }
 
@@ -88,9 +447,9 @@ func WithContext(ctx context.Context) Option {
 	return func(o *options) { o.ctx = ctx }
 }
 
-// WithServer 设置服务器
-func WithServer(server network.Server) Option {
-	return func(o *options) { o.server = server }
+// WithServer 设置服务器，可设置多个不同协议的服务器同时对外提供服务
+func WithServer(servers ...network.Server) Option {
+	return func(o *options) { o.servers = append(o.servers, servers...) }
 }
 
 // WithTimeout 设置RPC调用超时时间
@@ -112,3 +471,204 @@ func WithRegistry(r registry.Registry) Option {
 func WithWeight(weight int) Option {
 	return func(o *options) { o.weight = weight }
 }
+
+// WithEventAttrs 设置触发连接事件时是否携带会话属性
+// 携带会话属性的事件通过独立路由投递，节点需支持该路由后才可开启
+func WithEventAttrs(enable bool) Option {
+	return func(o *options) { o.attrs = enable }
+}
+
+// WithTLS 设置内部链接的证书、秘钥及CA证书，配置CA证书后启用双向认证
+func WithTLS(certFile, keyFile string, caFile ...string) Option {
+	return func(o *options) {
+		o.tls.certFile, o.tls.keyFile = certFile, keyFile
+
+		if len(caFile) > 0 {
+			o.tls.caFile = caFile[0]
+		}
+	}
+}
+
+// WithTLSServerName 设置内部链接校验对端证书时使用的服务器名称
+func WithTLSServerName(serverName string) Option {
+	return func(o *options) { o.tls.serverName = serverName }
+}
+
+// WithDrainRoute 设置排空时推送的重连通知消息路由
+func WithDrainRoute(route int32) Option {
+	return func(o *options) { o.drain.route = route }
+}
+
+// WithDrainWindow 设置排空窗口时间
+func WithDrainWindow(window time.Duration) Option {
+	return func(o *options) { o.drain.window = window }
+}
+
+// WithDrainIdleTime 设置排空时连接的空闲判定时间
+func WithDrainIdleTime(idleTime time.Duration) Option {
+	return func(o *options) { o.drain.idleTime = idleTime }
+}
+
+// WithLimitGlobal 设置全局限流，rate为每秒允许的消息数，burst为允许的突发消息数
+func WithLimitGlobal(rate float64, burst int) Option {
+	return func(o *options) { o.limit.global = Limit{Rate: rate, Burst: burst} }
+}
+
+// WithLimitConn 设置单个连接的限流
+func WithLimitConn(rate float64, burst int) Option {
+	return func(o *options) { o.limit.conn = Limit{Rate: rate, Burst: burst} }
+}
+
+// WithLimitUser 设置单个用户的限流，仅对已绑定用户的连接生效
+func WithLimitUser(rate float64, burst int) Option {
+	return func(o *options) { o.limit.user = Limit{Rate: rate, Burst: burst} }
+}
+
+// WithLimitRoute 设置路由的限流，该路由上所有连接的消息共享同一限额
+func WithLimitRoute(route int32, rate float64, burst int) Option {
+	return func(o *options) {
+		if o.limit.routes == nil {
+			o.limit.routes = make(map[int32]Limit)
+		}
+		o.limit.routes[route] = Limit{Rate: rate, Burst: burst}
+	}
+}
+
+// WithLimitPolicy 设置限流策略
+func WithLimitPolicy(policy LimitPolicy) Option {
+	return func(o *options) { o.limit.policy = policy }
+}
+
+// WithLimitOffenses 设置disconnect策略下断开连接前允许的被限流次数
+func WithLimitOffenses(offenses int) Option {
+	return func(o *options) { o.limit.offenses = offenses }
+}
+
+// WithACLRules 设置访问控制规则，设置后不再使用etc中配置的规则
+func WithACLRules(rules *ACLRules) Option {
+	return func(o *options) { o.acl.rules = rules }
+}
+
+// WithACLConfig 设置配置中心中访问控制规则的配置路径，如：acl 或 gate.acl
+func WithACLConfig(pattern string) Option {
+	return func(o *options) { o.acl.config = pattern }
+}
+
+// WithResume 启用断线恢复，window为断线恢复窗口时间，secret为恢复令牌签名秘钥，route为恢复令牌下发及恢复请求的消息路由
+func WithResume(window time.Duration, secret string, route int32) Option {
+	return func(o *options) { o.resume.window, o.resume.secret, o.resume.route = window, secret, route }
+}
+
+// WithResumeBufferSize 设置断线恢复窗口内缓存的消息数
+func WithResumeBufferSize(bufferSize int) Option {
+	return func(o *options) { o.resume.bufferSize = bufferSize }
+}
+
+// WithAuthenticator 设置客户端认证器，route为认证消息路由
+// 启用后连接需先发送认证消息，认证成功后自动绑定用户，认证完成前的其他消息将被拒绝
+func WithAuthenticator(authenticator Authenticator, route int32) Option {
+	return func(o *options) { o.auth.authenticator, o.auth.route = authenticator, route }
+}
+
+// WithAuthTimeout 设置认证超时时间
+func WithAuthTimeout(timeout time.Duration) Option {
+	return func(o *options) { o.auth.timeout = timeout }
+}
+
+// WithErrorReplyRoute 设置错误回复路由，需与业务路由区分，默认为-1
+func WithErrorReplyRoute(route int32) Option {
+	return func(o *options) { o.reply.route = route }
+}
+
+// WithErrorReplySwitch 设置路由的错误回复开关，关闭后该路由上的消息处理失败时不再回复客户端
+func WithErrorReplySwitch(route int32, enable bool) Option {
+	return func(o *options) {
+		if o.reply.routes == nil {
+			o.reply.routes = make(map[int32]bool)
+		}
+		o.reply.routes[route] = enable
+	}
+}
+
+// WithErrorReplyCodec 设置错误回复消息的编解码器，需与客户端解码消息体的方式一致，默认为json
+func WithErrorReplyCodec(codec encoding.Codec) Option {
+	return func(o *options) { o.reply.codec = codec }
+}
+
+// WithStickyRoute 设置节点指定消息路由，客户端可通过该路由为连接指定节点组的目标节点，后续该组路由的消息直接投递至该节点
+func WithStickyRoute(route int32) Option {
+	return func(o *options) { o.sticky.route = route }
+}
+
+// WithIPRules 设置地址过滤规则，设置后不再使用etc中配置的规则
+func WithIPRules(rules *IPRules) Option {
+	return func(o *options) { o.ip.rules = rules }
+}
+
+// WithIPConfig 设置配置中心中地址过滤规则的配置路径，如：ip 或 gate.ip
+func WithIPConfig(pattern string) Option {
+	return func(o *options) { o.ip.config = pattern }
+}
+
+// WithIPAutoBan 设置自动封禁，单个连接发送畸形消息达到threshold后封禁其IP，duration为封禁时长
+func WithIPAutoBan(threshold int, duration time.Duration) Option {
+	return func(o *options) { o.ip.banThreshold, o.ip.banDuration = threshold, duration }
+}
+
+// WithLoginPolicy 设置多设备登录策略，devices为multi策略下最大同时在线设备数，为0时不限制
+func WithLoginPolicy(policy session.LoginPolicy, devices int) Option {
+	return func(o *options) { o.login.policy, o.login.devices = policy, devices }
+}
+
+// WithLoginKick 设置踢出通知，设备被踢出时先通过route推送message再关闭连接，route为0时不通知
+func WithLoginKick(route int32, message []byte) Option {
+	return func(o *options) { o.login.kickRoute, o.login.kickMessage = route, message }
+}
+
+// WithReliablePush 启用可靠推送，ack为客户端确认消息路由，routes为需要可靠推送的路由
+// 可靠推送的消息序列号将被替换为投递ID，客户端需通过ack路由回复相同序列号的确认消息，并按投递ID去重
+func WithReliablePush(ack int32, routes ...int32) Option {
+	return func(o *options) {
+		o.reliable.ack = ack
+		if o.reliable.routes == nil {
+			o.reliable.routes = make(map[int32]bool, len(routes))
+		}
+		for _, route := range routes {
+			o.reliable.routes[route] = true
+		}
+	}
+}
+
+// WithReliableWindow 设置单个用户的待确认消息数上限
+func WithReliableWindow(window int) Option {
+	return func(o *options) { o.reliable.window = window }
+}
+
+// WithReliableRetry 设置确认超时时间及超时后的重传次数
+func WithReliableRetry(timeout time.Duration, retries int) Option {
+	return func(o *options) { o.reliable.timeout, o.reliable.retries = timeout, retries }
+}
+
+// WithLinkPool 设置内部链接中每个目标实例的有序及无序消息连接数，为0时使用默认值
+// 连接在首次使用时建立，同一目标的有序消息始终经由同一连接发送
+func WithLinkPool(ordered, unordered int) Option {
+	return func(o *options) { o.link.ordered, o.link.unordered = ordered, unordered }
+}
+
+// WithLinkPoolGrowth 设置内部链接的无序消息连接扩容上限及空闲连接回收时间
+// 写入积压时无序消息连接按需扩容至maxUnordered，连接空闲超过idleTimeout后关闭，为0时不回收
+func WithLinkPoolGrowth(maxUnordered int, idleTimeout time.Duration) Option {
+	return func(o *options) { o.link.maxUnordered, o.link.idleTimeout = maxUnordered, idleTimeout }
+}
+
+// WithTrace 设置链路追踪，enable为是否为投递的消息记录跨度，route为链路封装消息路由，为0时不接收客户端链路
+// 客户端通过route发送traceparent（55字节）与已打包的原始消息拼接而成的消息，网关解析后以其链路作为父链路投递原始消息
+// 消息包格式未预留扩展字段，故以封装路由代替包扩展传入客户端链路
+func WithTrace(enable bool, route int32) Option {
+	return func(o *options) { o.trace.enable, o.trace.route = enable, route }
+}
+
+// WithTraceExporter 设置跨度导出器，设置后不再使用文件导出器，网关销毁时关闭导出器
+func WithTraceExporter(exporter trace.Exporter) Option {
+	return func(o *options) { o.trace.exporter = exporter }
+}
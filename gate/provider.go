@@ -19,17 +19,28 @@ func (p *provider) Bind(ctx context.Context, cid, uid int64) error {
 		return errors.ErrInvalidArgument
 	}
 
-	err := p.gate.session.Bind(cid, uid)
+	evicted, kick, err := p.gate.session.Bind(cid, uid)
 	if err != nil {
 		return err
 	}
 
 	err = p.gate.proxy.bindGate(ctx, cid, uid)
 	if err != nil {
-		_, _ = p.gate.session.Unbind(uid)
+		_ = p.gate.session.UnbindConn(cid)
+		p.gate.session.Restore(uid, evicted)
+		return err
 	}
 
-	return err
+	if kick {
+		p.gate.kick(uid, evicted)
+	}
+
+	// 由节点完成认证绑定时结束认证计时
+	p.gate.guard.remove(cid)
+
+	p.gate.resumer.issue(cid, uid)
+
+	return nil
 }
 
 // Unbind 解绑用户与网关间的关系
@@ -66,12 +77,26 @@ func (p *provider) Disconnect(ctx context.Context, kind session.Kind, target int
 	return p.gate.session.Close(kind, target, force)
 }
 
-// Push 发送消息
+// Push 发送消息，配置为可靠推送的路由将等待客户端确认
 func (p *provider) Push(ctx context.Context, kind session.Kind, target int64, message []byte) error {
-	err := p.gate.session.Push(kind, target, message)
+	span := p.gate.tracer.follow(ctx, "gate.push")
+	defer span.End()
+
+	var err error
+	if kind == session.User && p.gate.reliable.selected(message) {
+		err = p.gate.reliable.push(target, message, nil)
+	} else {
+		err = p.gate.session.Push(kind, target, message)
+	}
+
+	span.SetError(err)
 
 	if kind == session.User && errors.Is(err, errors.ErrNotFoundSession) {
 		xcall.Go(func() {
+			// 异步解绑不受请求处理结束的影响，保留ctx中的链路信息
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.gate.opts.timeout)
+			defer cancel()
+
 			if err := p.gate.opts.locator.UnbindGate(ctx, target, p.gate.opts.id); err != nil {
 				log.Errorf("unbind gate failed, uid = %d gid = %s err = %v", target, p.gate.opts.id, err)
 			}
@@ -91,12 +116,57 @@ func (p *provider) Broadcast(ctx context.Context, kind session.Kind, message []b
 	return p.gate.session.Broadcast(kind, message)
 }
 
+// PushReliable 可靠推送消息，done不为nil时在客户端确认或投递失败后回调
+func (p *provider) PushReliable(ctx context.Context, uid int64, message []byte, done func(err error)) error {
+	return p.gate.reliable.push(uid, message, done)
+}
+
+// PushDevice 推送用户指定设备消息
+func (p *provider) PushDevice(ctx context.Context, uid int64, device string, message []byte) (int64, error) {
+	return p.gate.session.PushDevice(uid, device, message)
+}
+
+// JoinGroup 加入分组
+func (p *provider) JoinGroup(ctx context.Context, group string, kind session.Kind, target int64) error {
+	return p.gate.session.JoinGroup(group, kind, target)
+}
+
+// LeaveGroup 退出分组
+func (p *provider) LeaveGroup(ctx context.Context, group string, kind session.Kind, target int64) error {
+	return p.gate.session.LeaveGroup(group, kind, target)
+}
+
+// PushGroup 推送分组消息
+func (p *provider) PushGroup(ctx context.Context, group string, message []byte) (int64, error) {
+	return p.gate.session.PushGroup(group, message)
+}
+
+// GetAttr 获取会话属性
+func (p *provider) GetAttr(ctx context.Context, kind session.Kind, target int64, key string) (string, error) {
+	v, err := p.gate.session.GetAttr(kind, target, key)
+	if err != nil {
+		return "", err
+	}
+
+	return v.String(), nil
+}
+
+// SetAttr 设置会话属性
+func (p *provider) SetAttr(ctx context.Context, kind session.Kind, target int64, key, value string) error {
+	return p.gate.session.SetAttr(kind, target, key, value)
+}
+
+// Resume 释放用户在当前网关的挂起状态，供用户在网关gid恢复，返回挂起期间缓存的消息
+func (p *provider) Resume(ctx context.Context, gid string, uid, cid int64) ([][]byte, error) {
+	return p.gate.resumer.release(gid, uid, cid)
+}
+
 // GetState 获取状态
 func (p *provider) GetState() (cluster.State, error) {
-	return cluster.Work, nil
+	return p.gate.getState(), nil
 }
 
 // SetState 设置状态
 func (p *provider) SetState(state cluster.State) error {
-	return nil
+	return p.gate.setState(state)
 }
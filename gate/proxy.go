@@ -3,25 +3,50 @@ package gate
 import (
 	"context"
 	"gatesvr/cluster"
+	"gatesvr/codes"
 	"gatesvr/errors"
 	"gatesvr/internal/link"
 	"gatesvr/log"
 	"gatesvr/mode"
 	"gatesvr/packet"
+	"gatesvr/session"
 )
 
+type errorMessage struct {
+	Code    int    `json:"code"`    // 错误码
+	Message string `json:"message"` // 错误消息
+	Route   int32  `json:"route"`   // 原始消息路由
+}
+
 type proxy struct {
 	gate       *Gate            // 网关服
 	nodeLinker *link.NodeLinker // 节点链接器
 }
 
 func newProxy(gate *Gate) *proxy {
-	return &proxy{gate: gate, nodeLinker: link.NewNodeLinker(gate.ctx, &link.Options{
+	opts := &link.Options{
 		InsID:    gate.opts.id,
 		InsKind:  cluster.Gate,
 		Locator:  gate.opts.locator,
 		Registry: gate.opts.registry,
-	})}
+		Pool:     gate.opts.link.pool(),
+	}
+
+	if gate.loader != nil {
+		opts.TLSConfig = gate.loader.ClientConfig(gate.opts.tls.serverName)
+	}
+
+	return &proxy{gate: gate, nodeLinker: link.NewNodeLinker(gate.ctx, opts)}
+}
+
+// 内部链接连接池配置
+func (o *linkOptions) pool() link.PoolOptions {
+	return link.PoolOptions{
+		Ordered:      o.ordered,
+		Unordered:    o.unordered,
+		MaxUnordered: o.maxUnordered,
+		IdleTimeout:  o.idleTimeout,
+	}
 }
 
 // 绑定用户与网关间的关系
@@ -33,6 +58,8 @@ func (p *proxy) bindGate(ctx context.Context, cid, uid int64) error {
 
 	p.trigger(ctx, cluster.Reconnect, cid, uid)
 
+	p.gate.reliable.resend(uid)
+
 	return nil
 }
 
@@ -48,6 +75,11 @@ func (p *proxy) unbindGate(ctx context.Context, cid, uid int64) error {
 
 // 触发事件
 func (p *proxy) trigger(ctx context.Context, event cluster.Event, cid, uid int64) {
+	p.triggerAttrs(ctx, event, cid, uid, nil)
+}
+
+// 触发携带会话属性的事件
+func (p *proxy) triggerAttrs(ctx context.Context, event cluster.Event, cid, uid int64, attrs map[string]string) {
 	if mode.IsDebugMode() {
 		log.Debugf("trigger event, event: %v cid: %d uid: %d", event.String(), cid, uid)
 	}
@@ -56,6 +88,7 @@ func (p *proxy) trigger(ctx context.Context, event cluster.Event, cid, uid int64
 		Event: event,
 		CID:   cid,
 		UID:   uid,
+		Attrs: attrs,
 	}); err != nil {
 		switch {
 		case errors.Is(err, errors.ErrNotFoundEvent), errors.Is(err, errors.ErrNotFoundUserLocation):
@@ -71,6 +104,23 @@ func (p *proxy) deliver(ctx context.Context, cid, uid int64, message []byte) {
 	msg, err := packet.UnpackMessage(message)
 	if err != nil {
 		log.Errorf("unpack message failed: %v", err)
+
+		// 无法解析出原始消息的序列号，客户端无从关联回复，不进行回复
+		p.gate.filter.malformed(cid)
+		return
+	}
+
+	ctx, span, msg, message, err := p.gate.tracer.start(ctx, cid, uid, msg, message)
+	defer span.End()
+
+	if err != nil {
+		log.Errorf("unpack trace message failed: %v", err)
+
+		p.gate.filter.malformed(cid)
+
+		if err = p.respond(cid, msg.Seq, msg.Route, codes.InvalidArgument); err != nil {
+			log.Warnf("reply invalid message failed, cid: %d uid: %d err: %v", cid, uid, err)
+		}
 		return
 	}
 
@@ -78,21 +128,106 @@ func (p *proxy) deliver(ctx context.Context, cid, uid int64, message []byte) {
 		log.Debugf("deliver message, cid: %d uid: %d seq: %d route: %d buffer: %s", cid, uid, msg.Seq, msg.Route, string(msg.Buffer))
 	}
 
+	// 限流先于所有路由的处理，认证、恢复等网关路由同样受限
+	if !p.gate.limiter.allow(cid, uid, msg.Route) {
+		p.gate.limiter.throttle(cid, uid, msg)
+		return
+	}
+
+	if p.gate.resumer.enabled() && msg.Route == p.gate.opts.resume.route {
+		p.gate.resumer.resume(ctx, cid, uid, msg)
+		return
+	}
+
+	if p.gate.guard.enabled() && uid == 0 {
+		if msg.Route == p.gate.opts.auth.route {
+			p.gate.guard.authenticate(ctx, cid, msg)
+		} else if err = p.respond(cid, msg.Seq, msg.Route, codes.Unauthorized); err != nil {
+			log.Warnf("reply unauthorized message failed, cid: %d route: %d err: %v", cid, msg.Route, err)
+		}
+		return
+	}
+
+	if p.gate.reliable.enabled() && msg.Route == p.gate.opts.reliable.ack {
+		p.gate.reliable.ack(uid, msg.Seq)
+		return
+	}
+
+	// 访问控制先于节点组指定消息的处理，可靠推送确认消息由网关处理，不受访问控制约束
+	if code := p.gate.acl.check(uid, msg.Route); code != nil {
+		span.SetAttr("code", code.String())
+		log.WithContext(ctx).Warnf("deliver message rejected, cid: %d uid: %d seq: %d route: %d code: %s", cid, uid, msg.Seq, msg.Route, code)
+
+		if err = p.respond(cid, msg.Seq, msg.Route, code); err != nil {
+			log.Warnf("reply rejected message failed, cid: %d uid: %d route: %d err: %v", cid, uid, msg.Route, err)
+		}
+		return
+	}
+
+	if p.gate.sticky.enabled() && msg.Route == p.gate.opts.sticky.route {
+		p.gate.sticky.handle(cid, msg)
+		return
+	}
+
+	// 连接指定了路由所属节点组的节点时直接投递至该节点
+	group, nid := p.gate.sticky.find(cid, msg.Route)
+
 	if err = p.nodeLinker.Deliver(ctx, &link.DeliverArgs{
+		NID:     nid,
 		CID:     cid,
 		UID:     uid,
 		Route:   msg.Route,
 		Message: message,
 	}); err != nil {
+		span.SetError(err)
+
+		code := codes.InternalError
+
 		switch {
+		case errors.Is(err, errors.ErrIllegalRequest):
+			code = codes.IllegalRequest
+			log.WithContext(ctx).Warnf("deliver message failed, cid: %d uid: %d seq: %d route: %d nid: %s err: %v", cid, uid, msg.Seq, msg.Route, nid, err)
 		case errors.Is(err, errors.ErrNotFoundRoute), errors.Is(err, errors.ErrNotFoundEndpoint):
-			log.Warnf("deliver message failed, cid: %d uid: %d seq: %d route: %d err: %v", cid, uid, msg.Seq, msg.Route, err)
+			code = codes.NotFound
+			// 指定的节点已下线或不提供该路由时取消指定，后续消息恢复默认路由
+			if nid != "" {
+				p.gate.sticky.unstick(cid, group, nid)
+			}
+			log.WithContext(ctx).Warnf("deliver message failed, cid: %d uid: %d seq: %d route: %d err: %v", cid, uid, msg.Seq, msg.Route, err)
 		default:
-			log.Errorf("deliver message failed, cid: %d uid: %d seq: %d route: %d err: %v", cid, uid, msg.Seq, msg.Route, err)
+			log.WithContext(ctx).Errorf("deliver message failed, cid: %d uid: %d seq: %d route: %d err: %v", cid, uid, msg.Seq, msg.Route, err)
+		}
+
+		if err = p.respond(cid, msg.Seq, msg.Route, code); err != nil {
+			log.Warnf("reply failed message failed, cid: %d uid: %d route: %d err: %v", cid, uid, msg.Route, err)
 		}
 	}
 }
 
+// 回复错误码
+// 通过错误回复路由回复，消息体中携带原始消息路由；未携带序列号的消息及关闭了回复的路由不进行回复
+func (p *proxy) respond(cid int64, seq, route int32, code *codes.Code) error {
+	if seq == 0 {
+		return nil
+	}
+
+	if enable, ok := p.gate.opts.reply.routes[route]; ok && !enable {
+		return nil
+	}
+
+	buf, err := p.gate.opts.reply.codec.Marshal(&errorMessage{Code: code.Code(), Message: code.Message(), Route: route})
+	if err != nil {
+		return err
+	}
+
+	msg, err := packet.PackMessage(&packet.Message{Seq: seq, Route: p.gate.opts.reply.route, Buffer: buf})
+	if err != nil {
+		return err
+	}
+
+	return p.gate.session.Push(session.Conn, cid, msg)
+}
+
 // 开始监听
 func (p *proxy) watch() {
 	p.nodeLinker.WatchUserLocate()
@@ -0,0 +1,278 @@
+package gate
+
+import (
+	"gatesvr/errors"
+	"gatesvr/log"
+	"gatesvr/packet"
+	"gatesvr/session"
+	"math"
+	"sync"
+	"time"
+)
+
+type reliable struct {
+	gate     *Gate
+	maxID    int32    // 投递ID上限，由消息序列号的编码宽度决定
+	outboxes sync.Map // 用户的待确认消息（用户ID -> *outbox）
+	counters sync.Map // 用户的投递ID计数，发件箱移除后保留，用户下线后清除（用户ID -> *int32）
+}
+
+type outbox struct {
+	mu         sync.Mutex
+	closed     bool        // 是否已移除
+	deliveries []*delivery // 按投递顺序排列的待确认消息
+}
+
+type delivery struct {
+	id      int32           // 投递ID，即推送给客户端的消息序列号
+	msg     []byte          // 已打包的消息
+	retries int             // 已重传次数
+	timer   *time.Timer     // 确认超时定时器
+	done    func(err error) // 投递完成回调，客户端确认时err为nil
+}
+
+func newReliable(gate *Gate) *reliable {
+	return &reliable{gate: gate}
+}
+
+// 是否启用可靠推送
+func (r *reliable) enabled() bool {
+	return r.gate.opts.reliable.ack != 0
+}
+
+// 探测消息序列号的编码宽度以确定投递ID上限，未开启序列号编码时返回false
+func (r *reliable) probe() bool {
+	for _, id := range []int32{math.MaxInt32, math.MaxInt16, math.MaxInt8} {
+		buf, err := packet.PackMessage(&packet.Message{Seq: id})
+		if err != nil {
+			continue
+		}
+
+		msg, err := packet.UnpackMessage(buf)
+		if err != nil || msg.Seq != id {
+			return false
+		}
+
+		r.maxID = id
+
+		return true
+	}
+
+	return false
+}
+
+// 检测消息是否需要可靠推送
+func (r *reliable) selected(message []byte) bool {
+	if !r.enabled() || len(r.gate.opts.reliable.routes) == 0 {
+		return false
+	}
+
+	msg, err := packet.UnpackMessage(message)
+	if err != nil {
+		return false
+	}
+
+	return r.gate.opts.reliable.routes[msg.Route]
+}
+
+// 可靠推送消息，消息序列号将被替换为投递ID
+// 客户端确认、重传次数耗尽或投递失败时调用done，done为nil时不回调
+func (r *reliable) push(uid int64, message []byte, done func(err error)) error {
+	if !r.enabled() {
+		return errors.ErrIllegalOperation
+	}
+
+	msg, err := packet.UnpackMessage(message)
+	if err != nil {
+		return err
+	}
+
+	for {
+		v, _ := r.outboxes.LoadOrStore(uid, &outbox{})
+		box := v.(*outbox)
+
+		box.mu.Lock()
+
+		// 发件箱已被移除时重新获取
+		if box.closed {
+			box.mu.Unlock()
+			continue
+		}
+
+		if len(box.deliveries) >= r.gate.opts.reliable.window {
+			r.release(uid, box)
+			box.mu.Unlock()
+			return errors.ErrOutboundQueueFull
+		}
+
+		msg.Seq = r.nextID(uid)
+
+		buf, err := packet.PackMessage(msg)
+		if err != nil {
+			r.release(uid, box)
+			box.mu.Unlock()
+			return err
+		}
+
+		d := &delivery{id: msg.Seq, msg: buf, done: done}
+		d.timer = time.AfterFunc(r.gate.opts.reliable.timeout, func() { r.retry(uid, box, d) })
+		box.deliveries = append(box.deliveries, d)
+		box.mu.Unlock()
+
+		// 用户不在线时立即失败，挂起中的用户将在恢复后收到消息
+		if err = r.gate.session.Push(session.User, uid, buf); errors.Is(err, errors.ErrNotFoundSession) {
+			if r.remove(uid, box, d.id) != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// 处理客户端确认
+func (r *reliable) ack(uid int64, id int32) {
+	v, ok := r.outboxes.Load(uid)
+	if !ok {
+		return
+	}
+
+	if d := r.remove(uid, v.(*outbox), id); d != nil && d.done != nil {
+		d.done(nil)
+	}
+}
+
+// 重传用户所有待确认的消息，用户重连后调用
+func (r *reliable) resend(uid int64) {
+	v, ok := r.outboxes.Load(uid)
+	if !ok {
+		return
+	}
+
+	box := v.(*outbox)
+	box.mu.Lock()
+	msgs := make([][]byte, 0, len(box.deliveries))
+	for _, d := range box.deliveries {
+		d.timer.Reset(r.gate.opts.reliable.timeout)
+		msgs = append(msgs, d.msg)
+	}
+	box.mu.Unlock()
+
+	for _, msg := range msgs {
+		if err := r.gate.session.Push(session.User, uid, msg); err != nil {
+			log.Warnf("resend reliable message failed, uid: %d err: %v", uid, err)
+			return
+		}
+	}
+}
+
+// 确认超时，重传次数未耗尽时重传消息，否则投递失败
+func (r *reliable) retry(uid int64, box *outbox, d *delivery) {
+	box.mu.Lock()
+
+	if !box.has(d) {
+		box.mu.Unlock()
+		return
+	}
+
+	if d.retries >= r.gate.opts.reliable.retries {
+		box.mu.Unlock()
+
+		if r.remove(uid, box, d.id) != nil {
+			log.Warnf("reliable message unacknowledged, uid: %d id: %d retries: %d", uid, d.id, d.retries)
+
+			if d.done != nil {
+				d.done(errors.ErrDeadlineExceeded)
+			}
+		}
+		return
+	}
+
+	d.retries++
+	d.timer.Reset(r.gate.opts.reliable.timeout)
+	box.mu.Unlock()
+
+	_ = r.gate.session.Push(session.User, uid, d.msg)
+}
+
+// 移除待确认的消息，返回被移除的消息
+func (r *reliable) remove(uid int64, box *outbox, id int32) *delivery {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+
+	for i, d := range box.deliveries {
+		if d.id != id {
+			continue
+		}
+
+		d.timer.Stop()
+		box.deliveries = append(box.deliveries[:i:i], box.deliveries[i+1:]...)
+		r.release(uid, box)
+
+		return d
+	}
+
+	return nil
+}
+
+// 发件箱无待确认消息时移除发件箱，调用方需持有发件箱锁
+func (r *reliable) release(uid int64, box *outbox) {
+	if len(box.deliveries) == 0 && !box.closed {
+		box.closed = true
+		r.outboxes.CompareAndDelete(uid, box)
+	}
+}
+
+// 清除用户的投递ID计数，用户下线后调用；仍有待确认消息时保留
+func (r *reliable) reset(uid int64) {
+	if _, ok := r.outboxes.Load(uid); !ok {
+		r.counters.Delete(uid)
+	}
+}
+
+// 结束所有待确认的消息，网关关闭时调用
+func (r *reliable) close() {
+	r.outboxes.Range(func(key, value any) bool {
+		box := value.(*outbox)
+
+		box.mu.Lock()
+		deliveries := box.deliveries
+		box.deliveries = nil
+		r.release(key.(int64), box)
+		box.mu.Unlock()
+
+		for _, d := range deliveries {
+			d.timer.Stop()
+
+			if d.done != nil {
+				d.done(errors.ErrClientClosed)
+			}
+		}
+
+		return true
+	})
+}
+
+// 生成投递ID，超出上限后从1重新开始，调用方需持有发件箱锁
+func (r *reliable) nextID(uid int64) int32 {
+	v, _ := r.counters.LoadOrStore(uid, new(int32))
+	next := v.(*int32)
+
+	if *next >= r.maxID {
+		*next = 0
+	}
+	*next++
+
+	return *next
+}
+
+// 是否存在待确认的消息，调用方需持有发件箱锁
+func (b *outbox) has(d *delivery) bool {
+	for _, item := range b.deliveries {
+		if item == d {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,32 @@
+package gate
+
+import "testing"
+
+func TestReliable_NextID(t *testing.T) {
+	r := &reliable{gate: &Gate{}}
+
+	if !r.probe() || r.maxID <= 0 {
+		t.Fatalf("probe seq width failed, max id: %d", r.maxID)
+	}
+
+	// 超出上限后从1重新开始
+	r.maxID = 3
+	for i, want := range []int32{1, 2, 3, 1} {
+		if id := r.nextID(100); id != want {
+			t.Fatalf("unexpected id at %d: %d", i, id)
+		}
+	}
+
+	// 用户下线前计数不受发件箱移除影响，下线后重新计数
+	r.outboxes.Store(int64(100), &outbox{})
+	r.reset(100)
+	if id := r.nextID(100); id != 2 {
+		t.Fatalf("counter was reset with pending deliveries: %d", id)
+	}
+
+	r.outboxes.Delete(int64(100))
+	r.reset(100)
+	if id := r.nextID(100); id != 1 {
+		t.Fatalf("counter was not reset: %d", id)
+	}
+}
@@ -0,0 +1,436 @@
+package gate
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"gatesvr/cluster"
+	"gatesvr/codes"
+	"gatesvr/encoding/json"
+	"gatesvr/errors"
+	"gatesvr/internal/link"
+	"gatesvr/locate"
+	"gatesvr/log"
+	"gatesvr/packet"
+	"gatesvr/session"
+	"gatesvr/utils/xtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+type resumeToken struct {
+	UID int64  `json:"uid"` // 用户ID
+	GID string `json:"gid"` // 签发令牌的网关ID
+	CID int64  `json:"cid"` // 签发令牌时的连接ID
+	IAT int64  `json:"iat"` // 签发时间
+}
+
+type resumeRequest struct {
+	Token string `json:"token"` // 恢复令牌
+}
+
+type resumeMessage struct {
+	Code    int    `json:"code"`            // 错误码
+	Message string `json:"message"`         // 错误消息
+	Token   string `json:"token,omitempty"` // 恢复令牌
+}
+
+type resumer struct {
+	gate       *Gate
+	gateLinker *link.GateLinker // 网关链接器，用于向签发网关恢复挂起状态及向新网关转发挂起期间缓存的消息
+	timers     sync.Map         // 挂起超时定时器（用户ID -> *suspended）
+}
+
+type suspended struct {
+	cid   int64       // 挂起前的连接ID
+	timer *time.Timer // 超时定时器
+}
+
+func newResumer(gate *Gate) *resumer {
+	r := &resumer{gate: gate}
+
+	if !r.enabled() {
+		return r
+	}
+
+	opts := &link.Options{
+		InsID:    gate.opts.id,
+		InsKind:  cluster.Gate,
+		Locator:  gate.opts.locator,
+		Registry: gate.opts.registry,
+		Pool:     gate.opts.link.pool(),
+	}
+
+	if gate.loader != nil {
+		opts.TLSConfig = gate.loader.ClientConfig(gate.opts.tls.serverName)
+	}
+
+	r.gateLinker = link.NewGateLinker(gate.ctx, opts)
+
+	return r
+}
+
+// 是否启用断线恢复
+func (r *resumer) enabled() bool {
+	return r.gate.opts.resume.window > 0
+}
+
+// 监听网关实例及用户定位变化
+func (r *resumer) watch() {
+	if !r.enabled() {
+		return
+	}
+
+	r.gateLinker.WatchClusterInstance()
+
+	go r.refresh()
+
+	ctx, cancel := context.WithTimeout(r.gate.ctx, defaultTimeout)
+	watcher, err := r.gate.opts.locator.Watch(ctx, cluster.Gate.String())
+	cancel()
+	if err != nil {
+		log.Fatalf("user locate event watch failed: %v", err)
+	}
+
+	go func() {
+		defer watcher.Stop()
+		for {
+			select {
+			case <-r.gate.ctx.Done():
+				return
+			default:
+				// exec watch
+			}
+
+			events, err := watcher.Next()
+			if err != nil {
+				continue
+			}
+
+			for _, event := range events {
+				if event.Type == locate.BindGate && event.InsID != r.gate.opts.id {
+					r.handover(event.UID, event.InsID)
+				}
+			}
+		}
+	}()
+}
+
+// 挂起用户，窗口时间内未恢复时解绑网关并触发断开连接事件
+func (r *resumer) suspend(cid, uid int64) {
+	s := &suspended{cid: cid}
+	s.timer = time.AfterFunc(r.gate.opts.resume.window, func() {
+		r.timers.CompareAndDelete(uid, s)
+		r.expire(cid, uid)
+	})
+
+	if v, ok := r.timers.Swap(uid, s); ok {
+		v.(*suspended).timer.Stop()
+	}
+}
+
+// 挂起超时
+func (r *resumer) expire(cid, uid int64) {
+	attrs, ok := r.gate.session.Expire(uid, cid)
+	if !ok {
+		return
+	}
+
+	r.gate.reliable.reset(uid)
+
+	if !r.gate.opts.attrs {
+		attrs = nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.gate.ctx, r.gate.opts.timeout)
+	_ = r.gate.proxy.unbindGate(ctx, cid, uid)
+	r.gate.proxy.triggerAttrs(ctx, cluster.Disconnect, cid, uid, attrs)
+	cancel()
+}
+
+// 停止挂起超时定时器
+func (r *resumer) stop(uid int64) {
+	if v, ok := r.timers.LoadAndDelete(uid); ok {
+		v.(*suspended).timer.Stop()
+	}
+}
+
+// 立即结束所有挂起的用户
+func (r *resumer) flush() {
+	r.timers.Range(func(key, value any) bool {
+		uid, s := key.(int64), value.(*suspended)
+
+		if r.timers.CompareAndDelete(uid, s) && s.timer.Stop() {
+			r.expire(s.cid, uid)
+		}
+
+		return true
+	})
+}
+
+// 用户在其他网关登录后，将挂起期间缓存的消息转发至新网关
+func (r *resumer) handover(uid int64, gid string) {
+	buffer, ok := r.gate.session.Release(uid, 0)
+	if !ok {
+		return
+	}
+
+	r.stop(uid)
+
+	r.gate.reliable.reset(uid)
+
+	log.Infof("user logged in on another gate, uid: %d gid: %s buffered: %d", uid, gid, len(buffer))
+
+	ctx, cancel := context.WithTimeout(r.gate.ctx, r.gate.opts.timeout)
+	defer cancel()
+
+	r.forward(ctx, uid, gid, buffer)
+}
+
+// 释放由连接cid断开产生的挂起状态，供用户在其他网关恢复，返回挂起期间缓存的消息
+// 挂起状态仅可被释放一次，用户未挂起或挂起前的连接不匹配时返回ErrNotFoundSession
+func (r *resumer) release(gid string, uid, cid int64) ([][]byte, error) {
+	buffer, ok := r.gate.session.Release(uid, cid)
+	if !ok {
+		return nil, errors.ErrNotFoundSession
+	}
+
+	r.stop(uid)
+
+	r.gate.reliable.reset(uid)
+
+	log.Infof("user resumed on another gate, uid: %d gid: %s buffered: %d", uid, gid, len(buffer))
+
+	return buffer, nil
+}
+
+// 转发挂起期间缓存的消息
+func (r *resumer) forward(ctx context.Context, uid int64, gid string, buffer [][]byte) {
+	for _, buf := range buffer {
+		msg, err := packet.UnpackMessage(buf)
+		if err != nil {
+			log.Errorf("unpack buffered message failed: %v", err)
+			continue
+		}
+
+		if err = r.gateLinker.Push(ctx, &link.PushArgs{
+			GID:    gid,
+			Kind:   session.User,
+			Target: uid,
+			Message: &link.Message{
+				Seq:   msg.Seq,
+				Route: msg.Route,
+				Data:  msg.Buffer,
+			},
+		}); err != nil {
+			log.Errorf("forward buffered message failed, uid: %d gid: %s err: %v", uid, gid, err)
+		}
+	}
+}
+
+// 签发恢复令牌并推送给客户端
+func (r *resumer) issue(cid, uid int64) {
+	if !r.enabled() {
+		return
+	}
+
+	if err := r.reply(cid, 0, codes.OK, uid); err != nil {
+		log.Warnf("push resume token failed, cid: %d uid: %d err: %v", cid, uid, err)
+	}
+}
+
+// 定期为已绑定用户的连接刷新恢复令牌
+// 每个窗口时间刷新一次，保证连接断开时客户端持有的令牌签发时间不早于一个窗口时间之前
+func (r *resumer) refresh() {
+	ticker := time.NewTicker(r.gate.opts.resume.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.gate.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, conn := range r.gate.session.Conns() {
+				if uid := conn.UID(); uid != 0 {
+					r.issue(conn.ID(), uid)
+				}
+			}
+		}
+	}
+}
+
+// 处理恢复请求
+func (r *resumer) resume(ctx context.Context, cid, uid int64, msg *packet.Message) {
+	code, uid := r.doResume(ctx, cid, uid, msg.Buffer)
+
+	if code != codes.OK {
+		log.Warnf("resume session failed, cid: %d uid: %d code: %s", cid, uid, code)
+	}
+
+	if err := r.reply(cid, msg.Seq, code, uid); err != nil {
+		log.Warnf("reply resume message failed, cid: %d uid: %d err: %v", cid, uid, err)
+	}
+}
+
+// 执行恢复，返回结果码及恢复的用户ID
+func (r *resumer) doResume(ctx context.Context, cid, uid int64, data []byte) (*codes.Code, int64) {
+	if uid != 0 {
+		return codes.IllegalRequest, uid
+	}
+
+	req := &resumeRequest{}
+	if err := json.Unmarshal(data, req); err != nil {
+		return codes.InvalidArgument, 0
+	}
+
+	token, ok := r.verify(req.Token)
+	if !ok {
+		return codes.Unauthorized, 0
+	}
+
+	if token.GID == r.gate.opts.id {
+		// 仅恢复由签发令牌的连接断开产生的挂起状态，恢复后挂起状态被移除，令牌随之失效
+		n, err := r.gate.session.Resume(cid, token.UID, token.CID)
+		if err != nil {
+			if errors.Is(err, errors.ErrNotFoundSession) {
+				return codes.Unauthorized, 0
+			}
+			return codes.InternalError, 0
+		}
+
+		r.stop(token.UID)
+
+		log.Infof("user resumed, cid: %d uid: %d replayed: %d", cid, token.UID, n)
+
+		// 重新绑定网关，触发重连事件
+		if err = r.gate.proxy.bindGate(ctx, cid, token.UID); err != nil {
+			_ = r.gate.session.UnbindConn(cid)
+			return codes.InternalError, 0
+		}
+
+		// 恢复成功的连接无需再认证，结束认证计时
+		r.gate.guard.remove(cid)
+
+		return codes.OK, token.UID
+	}
+
+	// 先由签发网关校验并释放该连接断开产生的挂起状态，校验通过前不改动本网关的绑定关系
+	// 挂起状态仅可被释放一次，令牌随之失效
+	buffer, err := r.gateLinker.Resume(ctx, &link.ResumeArgs{GID: token.GID, UID: token.UID, CID: token.CID})
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFoundSession) {
+			return codes.Unauthorized, 0
+		}
+		return codes.InternalError, 0
+	}
+
+	evicted, kick, err := r.gate.session.Bind(cid, token.UID)
+	if err != nil {
+		if errors.Is(err, errors.ErrAlreadyLoggedIn) {
+			return codes.AlreadyLoggedIn, 0
+		}
+		return codes.InternalError, 0
+	}
+
+	// 重放签发网关缓存的消息
+	for _, msg := range buffer {
+		if err = r.gate.session.Push(session.Conn, cid, msg); err != nil {
+			log.Warnf("replay buffered message failed, cid: %d uid: %d err: %v", cid, token.UID, err)
+		}
+	}
+
+	log.Infof("user resumed from another gate, cid: %d uid: %d gid: %s replayed: %d", cid, token.UID, token.GID, len(buffer))
+
+	// 重新绑定网关，触发重连事件
+	if err = r.gate.proxy.bindGate(ctx, cid, token.UID); err != nil {
+		_ = r.gate.session.UnbindConn(cid)
+		r.gate.session.Restore(token.UID, evicted)
+		return codes.InternalError, 0
+	}
+
+	if kick {
+		r.gate.kick(token.UID, evicted)
+	}
+
+	// 恢复成功的连接无需再认证，结束认证计时
+	r.gate.guard.remove(cid)
+
+	return codes.OK, token.UID
+}
+
+// 回复恢复消息，成功时携带新的恢复令牌
+func (r *resumer) reply(cid int64, seq int32, code *codes.Code, uid int64) error {
+	message := &resumeMessage{Code: code.Code(), Message: code.Message()}
+
+	if code == codes.OK {
+		token, err := r.sign(&resumeToken{UID: uid, GID: r.gate.opts.id, CID: cid, IAT: xtime.Now().Unix()})
+		if err != nil {
+			return err
+		}
+		message.Token = token
+	}
+
+	buf, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	msg, err := packet.PackMessage(&packet.Message{Seq: seq, Route: r.gate.opts.resume.route, Buffer: buf})
+	if err != nil {
+		return err
+	}
+
+	return r.gate.session.Push(session.Conn, cid, msg)
+}
+
+// 签名恢复令牌，格式为：base64(payload).base64(signature)
+func (r *resumer) sign(token *resumeToken) (string, error) {
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(r.mac(encoded)), nil
+}
+
+// 校验恢复令牌
+func (r *resumer) verify(token string) (*resumeToken, bool) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, false
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, r.mac(encoded)) {
+		return nil, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+
+	t := &resumeToken{}
+	if err = json.Unmarshal(payload, t); err != nil || t.UID <= 0 || t.GID == "" || t.CID <= 0 {
+		return nil, false
+	}
+
+	// 令牌每个窗口时间刷新一次，挂起状态最长保持一个窗口时间，签发超过两倍窗口时间的令牌已无对应的挂起状态
+	if xtime.Now().Sub(time.Unix(t.IAT, 0)) > 2*r.gate.opts.resume.window {
+		return nil, false
+	}
+
+	return t, true
+}
+
+// 计算签名
+func (r *resumer) mac(encoded string) []byte {
+	h := hmac.New(sha256.New, []byte(r.gate.opts.resume.secret))
+	h.Write([]byte(encoded))
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,206 @@
+package gate
+
+import (
+	"context"
+	"errors"
+	"gatesvr/cluster"
+	"gatesvr/codes"
+	"gatesvr/encoding/json"
+	"gatesvr/internal/link"
+	"gatesvr/locate"
+	"gatesvr/network"
+	"gatesvr/session"
+	"gatesvr/utils/xtime"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type conn struct {
+	id  int64
+	uid atomic.Int64
+}
+
+func (c *conn) ID() int64                     { return c.id }
+func (c *conn) UID() int64                    { return c.uid.Load() }
+func (c *conn) Bind(uid int64)                { c.uid.Store(uid) }
+func (c *conn) Unbind()                       { c.uid.Store(0) }
+func (c *conn) Send(msg []byte) error         { return nil }
+func (c *conn) Push(msg []byte) error         { return nil }
+func (c *conn) State() network.ConnState      { return network.ConnOpened }
+func (c *conn) Close(force ...bool) error     { return nil }
+func (c *conn) LocalIP() (string, error)      { return "127.0.0.1", nil }
+func (c *conn) LocalAddr() (net.Addr, error)  { return nil, nil }
+func (c *conn) RemoteIP() (string, error)     { return "127.0.0.1", nil }
+func (c *conn) RemoteAddr() (net.Addr, error) { return nil, nil }
+
+// 绑定网关返回指定错误的定位器
+type locator struct {
+	locate.Locator
+	err error
+}
+
+func (l *locator) BindGate(ctx context.Context, uid int64, gid string) error {
+	return l.err
+}
+
+func newTestResumer() *resumer {
+	g := &Gate{
+		opts: &options{
+			id:      "gate-1",
+			timeout: time.Second,
+			locator: &locator{err: errors.New("locator unavailable")},
+			resume:  resumeOptions{window: time.Minute, secret: "secret", route: 1, bufferSize: 10},
+		},
+		ctx:     context.Background(),
+		session: session.NewSession(),
+	}
+	g.proxy = &proxy{gate: g, nodeLinker: link.NewNodeLinker(g.ctx, &link.Options{InsID: g.opts.id, InsKind: cluster.Gate})}
+	g.guard = &guard{gate: g}
+	g.reliable = &reliable{gate: g}
+	g.resumer = &resumer{gate: g}
+
+	return g.resumer
+}
+
+func TestResumer_Verify(t *testing.T) {
+	r := newTestResumer()
+
+	token, err := r.sign(&resumeToken{UID: 100, GID: "gate-1", CID: 1, IAT: xtime.Now().Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := r.verify(token); !ok || v.UID != 100 || v.CID != 1 {
+		t.Fatalf("verify failed: %+v", v)
+	}
+
+	// 篡改签名
+	encoded, _, _ := strings.Cut(token, ".")
+	if _, ok := r.verify(encoded + ".AAAA"); ok {
+		t.Fatal("tampered token was accepted")
+	}
+
+	// 其他秘钥签发
+	other := newTestResumer()
+	other.gate.opts.resume.secret = "other"
+	if _, ok := other.verify(token); ok {
+		t.Fatal("token signed with another secret was accepted")
+	}
+
+	// 过期令牌
+	expired, err := r.sign(&resumeToken{UID: 100, GID: "gate-1", CID: 1, IAT: xtime.Now().Add(-3 * time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := r.verify(expired); ok {
+		t.Fatal("expired token was accepted")
+	}
+}
+
+func TestResumer_Resume(t *testing.T) {
+	r := newTestResumer()
+	s := r.gate.session
+
+	c1 := &conn{id: 1}
+	s.AddConn(c1)
+	if _, _, err := s.Bind(c1.ID(), 100); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, suspended := s.Suspend(c1, 10); !suspended {
+		t.Fatal("user was not suspended")
+	}
+
+	resume := func(cid int64, from int64) *codes.Code {
+		token, err := r.sign(&resumeToken{UID: 100, GID: "gate-1", CID: from, IAT: xtime.Now().Unix()})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := json.Marshal(&resumeRequest{Token: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, _ := r.doResume(context.Background(), cid, 0, data)
+
+		return code
+	}
+
+	c2 := &conn{id: 2}
+	s.AddConn(c2)
+
+	// 非该挂起状态签发的令牌
+	if code := resume(c2.ID(), 99); code != codes.Unauthorized {
+		t.Fatalf("unexpected code: %v", code)
+	}
+
+	if !s.IsSuspended(100) {
+		t.Fatal("suspension was consumed by a mismatched token")
+	}
+
+	// 绑定网关失败时仅回滚当前连接
+	if code := resume(c2.ID(), c1.ID()); code != codes.InternalError {
+		t.Fatalf("unexpected code: %v", code)
+	}
+
+	if c2.UID() != 0 {
+		t.Fatalf("connection was not unbound, uid: %d", c2.UID())
+	}
+
+	// 令牌使用后失效
+	c3 := &conn{id: 3}
+	s.AddConn(c3)
+
+	if code := resume(c3.ID(), c1.ID()); code != codes.Unauthorized {
+		t.Fatalf("replayed token was accepted, code: %v", code)
+	}
+}
+
+func TestResumer_ResumeWithGuard(t *testing.T) {
+	r := newTestResumer()
+	r.gate.opts.locator = &locator{}
+	r.gate.opts.auth = authOptions{
+		authenticator: AuthenticatorFunc(func(ctx context.Context, args *AuthArgs) (int64, error) { return 0, nil }),
+		route:         2,
+		timeout:       time.Minute,
+	}
+	s := r.gate.session
+
+	c1 := &conn{id: 1}
+	s.AddConn(c1)
+	if _, _, err := s.Bind(c1.ID(), 100); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, suspended := s.Suspend(c1, 10); !suspended {
+		t.Fatal("user was not suspended")
+	}
+
+	c2 := &conn{id: 2}
+	s.AddConn(c2)
+	r.gate.guard.watch(c2.ID())
+
+	token, err := r.sign(&resumeToken{UID: 100, GID: "gate-1", CID: c1.ID(), IAT: xtime.Now().Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(&resumeRequest{Token: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code, uid := r.doResume(context.Background(), c2.ID(), 0, data); code != codes.OK || uid != 100 {
+		t.Fatalf("unexpected result: %v %d", code, uid)
+	}
+
+	// 恢复成功后结束认证计时，避免连接因认证超时被关闭
+	if _, ok := r.gate.guard.pending.Load(c2.ID()); ok {
+		t.Fatal("authentication timer was not removed")
+	}
+}
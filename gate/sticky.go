@@ -0,0 +1,160 @@
+package gate
+
+import (
+	"gatesvr/codes"
+	"gatesvr/encoding/json"
+	"gatesvr/log"
+	"gatesvr/packet"
+	"gatesvr/session"
+	"sync"
+)
+
+const maxStickyGroups = 32 // 单个连接可指定节点的节点组数上限
+
+type stickyRequest struct {
+	Group string `json:"group"` // 节点组，即节点名称
+	NID   string `json:"nid"`   // 节点ID，为空时取消该组的指定节点
+}
+
+type stickyMessage struct {
+	Code    int    `json:"code"`    // 错误码
+	Message string `json:"message"` // 错误消息
+}
+
+type sticky struct {
+	gate  *Gate
+	nodes sync.Map // 连接指定的节点（连接ID -> map[节点组]节点ID）
+}
+
+func newSticky(gate *Gate) *sticky {
+	return &sticky{gate: gate}
+}
+
+// 是否启用节点指定
+func (s *sticky) enabled() bool {
+	return s.gate.opts.sticky.route != 0
+}
+
+// 处理节点指定消息
+func (s *sticky) handle(cid int64, msg *packet.Message) {
+	code := s.doHandle(cid, msg.Buffer)
+
+	if code != codes.OK {
+		log.Warnf("stick node failed, cid: %d code: %s", cid, code)
+	}
+
+	if err := s.reply(cid, msg.Seq, code); err != nil {
+		log.Warnf("reply sticky message failed, cid: %d err: %v", cid, err)
+	}
+}
+
+// 执行节点指定
+func (s *sticky) doHandle(cid int64, data []byte) *codes.Code {
+	req := &stickyRequest{}
+	if err := json.Unmarshal(data, req); err != nil || req.Group == "" {
+		return codes.InvalidArgument
+	}
+
+	if req.NID == "" {
+		s.unstick(cid, req.Group, "")
+		return codes.OK
+	}
+
+	// 仅允许指定集群中已知的节点
+	if !s.gate.proxy.nodeLinker.Has(req.NID) {
+		return codes.NotFound
+	}
+
+	for {
+		v, loaded := s.nodes.Load(cid)
+
+		groups := make(map[string]string)
+		if loaded {
+			for group, nid := range v.(map[string]string) {
+				groups[group] = nid
+			}
+		}
+
+		// 限制连接可指定的节点组数，避免客户端以任意节点组名无限占用内存
+		if _, ok := groups[req.Group]; !ok && len(groups) >= maxStickyGroups {
+			return codes.IllegalRequest
+		}
+
+		groups[req.Group] = req.NID
+
+		if loaded && s.nodes.CompareAndSwap(cid, v, groups) {
+			return codes.OK
+		}
+
+		if !loaded {
+			if _, loaded = s.nodes.LoadOrStore(cid, groups); !loaded {
+				return codes.OK
+			}
+		}
+	}
+}
+
+// 取消连接在节点组上指定的节点，nid不为空时仅在指定节点匹配时取消
+func (s *sticky) unstick(cid int64, group, nid string) {
+	for {
+		v, ok := s.nodes.Load(cid)
+		if !ok {
+			return
+		}
+
+		old := v.(map[string]string)
+		if curr, ok := old[group]; !ok || (nid != "" && curr != nid) {
+			return
+		}
+
+		groups := make(map[string]string, len(old))
+		for g, n := range old {
+			if g != group {
+				groups[g] = n
+			}
+		}
+
+		if len(groups) == 0 {
+			if s.nodes.CompareAndDelete(cid, v) {
+				return
+			}
+		} else if s.nodes.CompareAndSwap(cid, v, groups) {
+			return
+		}
+	}
+}
+
+// 查找连接为路由所属节点组指定的节点
+func (s *sticky) find(cid int64, route int32) (string, string) {
+	v, ok := s.nodes.Load(cid)
+	if !ok {
+		return "", ""
+	}
+
+	group, ok := s.gate.proxy.nodeLinker.RouteGroup(route)
+	if !ok {
+		return "", ""
+	}
+
+	return group, v.(map[string]string)[group]
+}
+
+// 移除连接指定的所有节点
+func (s *sticky) remove(cid int64) {
+	s.nodes.Delete(cid)
+}
+
+// 回复节点指定消息
+func (s *sticky) reply(cid int64, seq int32, code *codes.Code) error {
+	buf, err := json.Marshal(&stickyMessage{Code: code.Code(), Message: code.Message()})
+	if err != nil {
+		return err
+	}
+
+	msg, err := packet.PackMessage(&packet.Message{Seq: seq, Route: s.gate.opts.sticky.route, Buffer: buf})
+	if err != nil {
+		return err
+	}
+
+	return s.gate.session.Push(session.Conn, cid, msg)
+}
@@ -0,0 +1,106 @@
+package gate
+
+import (
+	"context"
+	"gatesvr/errors"
+	"gatesvr/log"
+	"gatesvr/packet"
+	"gatesvr/trace"
+	"strconv"
+)
+
+type tracer struct {
+	gate     *Gate
+	exporter trace.Exporter // 网关设置的导出器，销毁时关闭
+}
+
+func newTracer(gate *Gate) *tracer {
+	return &tracer{gate: gate}
+}
+
+// 是否启用链路追踪
+func (t *tracer) enabled() bool {
+	return t.gate.opts.trace.enable
+}
+
+// 设置导出器
+// 未设置导出器及导出文件时使用trace包的默认文件导出器
+func (t *tracer) install() {
+	if !t.enabled() {
+		return
+	}
+
+	switch {
+	case t.gate.opts.trace.exporter != nil:
+		t.exporter = t.gate.opts.trace.exporter
+	case t.gate.opts.trace.file != "":
+		t.exporter = trace.NewFileExporter(t.gate.opts.trace.file)
+	default:
+		t.exporter = trace.GetExporter()
+	}
+
+	trace.SetExporter(t.exporter)
+}
+
+// 开始投递跨度
+// 消息为链路封装消息时解析出客户端传入的链路上下文及原始消息，并以其作为父链路；否则开启新的链路
+// 封装消息协议：traceparent（55字节） + 原始消息（已打包）
+// 客户端消息包格式由packet包定义，包头未预留扩展字段，且变更包格式将导致现有客户端无法解析，故以独立的封装路由代替包扩展承载客户端链路
+func (t *tracer) start(ctx context.Context, cid, uid int64, msg *packet.Message, message []byte) (context.Context, *trace.Span, *packet.Message, []byte, error) {
+	if t.gate.opts.trace.route != 0 && msg.Route == t.gate.opts.trace.route {
+		if len(msg.Buffer) < trace.TraceparentLen {
+			return ctx, nil, msg, message, errors.ErrInvalidTraceparent
+		}
+
+		sc, err := trace.ParseTraceparent(string(msg.Buffer[:trace.TraceparentLen]))
+		if err != nil {
+			return ctx, nil, msg, message, err
+		}
+
+		inner, err := packet.UnpackMessage(msg.Buffer[trace.TraceparentLen:])
+		if err != nil {
+			return ctx, nil, msg, message, err
+		}
+
+		ctx, msg, message = trace.NewContext(ctx, sc), inner, msg.Buffer[trace.TraceparentLen:]
+	}
+
+	if !t.enabled() {
+		return ctx, nil, msg, message, nil
+	}
+
+	ctx, span := trace.Start(ctx, "gate.deliver")
+	span.SetAttr("cid", strconv.FormatInt(cid, 10))
+	span.SetAttr("uid", strconv.FormatInt(uid, 10))
+	span.SetAttr("route", strconv.FormatInt(int64(msg.Route), 10))
+	span.SetAttr("seq", strconv.FormatInt(int64(msg.Seq), 10))
+
+	return ctx, span, msg, message, nil
+}
+
+// 延续节点传入的链路
+// 仅在上下文携带链路上下文时开启子跨度，未携带时返回nil
+func (t *tracer) follow(ctx context.Context, name string) *trace.Span {
+	if !t.enabled() {
+		return nil
+	}
+
+	if _, ok := trace.FromContext(ctx); !ok {
+		return nil
+	}
+
+	_, span := trace.Start(ctx, name)
+
+	return span
+}
+
+// 关闭导出器
+func (t *tracer) close() {
+	if t.exporter == nil {
+		return
+	}
+
+	if err := t.exporter.Close(); err != nil {
+		log.Warnf("trace exporter close failed: %v", err)
+	}
+}
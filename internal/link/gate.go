@@ -5,7 +5,9 @@ import (
 	"gatesvr/cluster"
 	"gatesvr/core/buffer"
 	"gatesvr/core/endpoint"
+	"gatesvr/core/value"
 	"gatesvr/errors"
+	"gatesvr/etc"
 	"gatesvr/internal/transporter/gate"
 	"gatesvr/locate"
 	"gatesvr/log"
@@ -14,6 +16,7 @@ import (
 
 	"gatesvr/internal/dispatcher"
 	"gatesvr/session"
+	"gatesvr/utils/xcall"
 
 	"golang.org/x/sync/errgroup"
 	"sync"
@@ -21,22 +24,47 @@ import (
 	"time"
 )
 
+const (
+	defaultReliableTimeout = 5 * time.Second // 网关默认的可靠推送确认超时时间
+	defaultReliableRetries = 3               // 网关默认的可靠推送重传次数
+)
+
+// 与网关读取相同的可靠推送配置
+const (
+	defaultReliableTimeoutKey = "etc.cluster.gate.reliable.timeout"
+	defaultReliableRetriesKey = "etc.cluster.gate.reliable.retries"
+)
+
 type GateLinker struct {
 	ctx        context.Context        // 上下文
 	opts       *Options               // 参数项
 	sources    sync.Map               // 用户源
 	builder    *gate.Builder          // 构建器
 	dispatcher *dispatcher.Dispatcher // 分发器
+	reliable   time.Duration          // 异步等待可靠推送确认的默认超时时间
 }
 
 func NewGateLinker(ctx context.Context, opts *Options) *GateLinker {
 	l := &GateLinker{
 		ctx:        ctx,
 		opts:       opts,
-		builder:    gate.NewBuilder(&gate.Options{InsID: opts.InsID, InsKind: opts.InsKind}),
+		builder:    gate.NewBuilder(&gate.Options{InsID: opts.InsID, InsKind: opts.InsKind, TLSConfig: opts.TLSConfig, Pool: opts.Pool}),
 		dispatcher: dispatcher.NewDispatcher(opts.BalanceStrategy),
 	}
 
+	timeout, retries := defaultReliableTimeout, defaultReliableRetries
+
+	if v := etc.Get(defaultReliableTimeoutKey).Duration(); v > 0 {
+		timeout = v
+	}
+
+	if v := etc.Get(defaultReliableRetriesKey).Int(); v > 0 {
+		retries = v
+	}
+
+	// 最长等待网关完成所有重传
+	l.reliable = timeout * time.Duration(retries+1)
+
 	return l
 }
 
@@ -207,7 +235,7 @@ func (l *GateLinker) Stat(ctx context.Context, kind session.Kind) (int64, error)
 
 	l.dispatcher.IterateEndpoint(func(_ string, ep *endpoint.Endpoint) bool {
 		eg.Go(func() error {
-			client, err := l.builder.Build(ep.Address())
+			client, err := l.builder.Build(ep)
 			if err != nil {
 				return err
 			}
@@ -350,6 +378,80 @@ func (l *GateLinker) doIndirectPush(ctx context.Context, args *PushArgs) error {
 	return err
 }
 
+// PushReliable 可靠推送消息，网关等待客户端确认，超时未确认时重传
+func (l *GateLinker) PushReliable(ctx context.Context, args *PushReliableArgs) error {
+	message, err := l.PackMessage(args.Message, true)
+	if err != nil {
+		return err
+	}
+
+	await := args.Await || args.Failed != nil
+
+	call := func(ctx context.Context) error {
+		fn := func(client *gate.Client) (bool, interface{}, error) {
+			miss, err := client.PushReliable(ctx, args.UID, message, await)
+			return miss, nil, err
+		}
+
+		if args.GID == "" {
+			_, err := l.doRPC(ctx, args.UID, fn)
+			return err
+		}
+
+		client, err := l.doBuildClient(args.GID)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = fn(client)
+		return err
+	}
+
+	if args.Await || args.Failed == nil {
+		return call(ctx)
+	}
+
+	// 异步等待确认时不受调用方ctx结束的影响，最长等待网关完成所有重传
+	timeout := args.Timeout
+	if timeout <= 0 {
+		timeout = l.reliable
+	}
+
+	xcall.Go(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		if err := call(ctx); err != nil {
+			args.Failed(err)
+		}
+	})
+
+	return nil
+}
+
+// PushDevice 推送消息至用户指定设备标签的设备
+func (l *GateLinker) PushDevice(ctx context.Context, args *PushDeviceArgs) error {
+	message, err := l.PackMessage(args.Message, true)
+	if err != nil {
+		return err
+	}
+
+	if args.GID != "" {
+		client, err := l.doBuildClient(args.GID)
+		if err != nil {
+			return err
+		}
+
+		return client.PushDevice(ctx, args.UID, args.Device, message)
+	}
+
+	_, err = l.doRPC(ctx, args.UID, func(client *gate.Client) (bool, interface{}, error) {
+		return false, nil, client.PushDevice(ctx, args.UID, args.Device, message)
+	})
+
+	return err
+}
+
 // Multicast 推送组播消息
 func (l *GateLinker) Multicast(ctx context.Context, args *MulticastArgs) error {
 	switch args.Kind {
@@ -441,7 +543,7 @@ func (l *GateLinker) Broadcast(ctx context.Context, args *BroadcastArgs) error {
 				return err
 			}
 
-			client, err := l.builder.Build(ep.Address())
+			client, err := l.builder.Build(ep)
 			if err != nil {
 				return err
 			}
@@ -455,6 +557,194 @@ func (l *GateLinker) Broadcast(ctx context.Context, args *BroadcastArgs) error {
 	return eg.Wait()
 }
 
+// JoinGroup 加入分组
+func (l *GateLinker) JoinGroup(ctx context.Context, args *JoinGroupArgs) error {
+	switch args.Kind {
+	case session.Conn:
+		return l.doDirectCall(args.GID, func(client *gate.Client) (bool, error) {
+			return client.JoinGroup(ctx, args.Group, args.Kind, args.Target)
+		})
+	case session.User:
+		if args.GID == "" {
+			return l.doIndirectCall(ctx, args.Target, func(client *gate.Client) (bool, error) {
+				return client.JoinGroup(ctx, args.Group, args.Kind, args.Target)
+			})
+		} else {
+			return l.doDirectCall(args.GID, func(client *gate.Client) (bool, error) {
+				return client.JoinGroup(ctx, args.Group, args.Kind, args.Target)
+			})
+		}
+	default:
+		return errors.ErrInvalidSessionKind
+	}
+}
+
+// LeaveGroup 退出分组
+func (l *GateLinker) LeaveGroup(ctx context.Context, args *LeaveGroupArgs) error {
+	switch args.Kind {
+	case session.Conn:
+		return l.doDirectCall(args.GID, func(client *gate.Client) (bool, error) {
+			return client.LeaveGroup(ctx, args.Group, args.Kind, args.Target)
+		})
+	case session.User:
+		if args.GID == "" {
+			return l.doIndirectCall(ctx, args.Target, func(client *gate.Client) (bool, error) {
+				return client.LeaveGroup(ctx, args.Group, args.Kind, args.Target)
+			})
+		} else {
+			return l.doDirectCall(args.GID, func(client *gate.Client) (bool, error) {
+				return client.LeaveGroup(ctx, args.Group, args.Kind, args.Target)
+			})
+		}
+	default:
+		return errors.ErrInvalidSessionKind
+	}
+}
+
+// 直接调用指定网关
+func (l *GateLinker) doDirectCall(gid string, fn func(client *gate.Client) (bool, error)) error {
+	client, err := l.doBuildClient(gid)
+	if err != nil {
+		return err
+	}
+
+	_, err = fn(client)
+
+	return err
+}
+
+// 间接调用用户所在网关
+func (l *GateLinker) doIndirectCall(ctx context.Context, uid int64, fn func(client *gate.Client) (bool, error)) error {
+	_, err := l.doRPC(ctx, uid, func(client *gate.Client) (bool, interface{}, error) {
+		miss, err := fn(client)
+		return miss, nil, err
+	})
+
+	return err
+}
+
+// Resume 释放在指定网关挂起的用户，返回挂起期间缓存的消息，用户未挂起或挂起前的连接不匹配时返回ErrNotFoundSession
+func (l *GateLinker) Resume(ctx context.Context, args *ResumeArgs) ([][]byte, error) {
+	client, err := l.doBuildClient(args.GID)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Resume(ctx, args.UID, args.CID)
+}
+
+// GetAttr 获取会话属性，属性不存在时返回空值
+func (l *GateLinker) GetAttr(ctx context.Context, args *GetAttrArgs) (value.Value, error) {
+	var (
+		v   string
+		err error
+	)
+
+	fn := func(client *gate.Client) (bool, interface{}, error) {
+		v, miss, err := client.GetAttr(ctx, args.Kind, args.Target, args.Key)
+		return miss, v, err
+	}
+
+	switch args.Kind {
+	case session.Conn:
+		v, err = l.doDirectGetAttr(args.GID, fn)
+	case session.User:
+		if args.GID == "" {
+			var reply interface{}
+			if reply, err = l.doRPC(ctx, args.Target, fn); err == nil {
+				v = reply.(string)
+			}
+		} else {
+			v, err = l.doDirectGetAttr(args.GID, fn)
+		}
+	default:
+		err = errors.ErrInvalidSessionKind
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if v == "" {
+		return value.NewValue(), nil
+	}
+
+	return value.NewValue(v), nil
+}
+
+// 直接获取会话属性
+func (l *GateLinker) doDirectGetAttr(gid string, fn func(client *gate.Client) (bool, interface{}, error)) (string, error) {
+	client, err := l.doBuildClient(gid)
+	if err != nil {
+		return "", err
+	}
+
+	_, v, err := fn(client)
+	if err != nil {
+		return "", err
+	}
+
+	return v.(string), nil
+}
+
+// SetAttr 设置会话属性
+func (l *GateLinker) SetAttr(ctx context.Context, args *SetAttrArgs) error {
+	fn := func(client *gate.Client) (bool, error) {
+		return client.SetAttr(ctx, args.Kind, args.Target, args.Key, args.Value)
+	}
+
+	switch args.Kind {
+	case session.Conn:
+		return l.doDirectCall(args.GID, fn)
+	case session.User:
+		if args.GID == "" {
+			return l.doIndirectCall(ctx, args.Target, fn)
+		} else {
+			return l.doDirectCall(args.GID, fn)
+		}
+	default:
+		return errors.ErrInvalidSessionKind
+	}
+}
+
+// PushGroup 推送分组消息，每个网关仅进行一次调用
+func (l *GateLinker) PushGroup(ctx context.Context, args *PushGroupArgs) error {
+	if args.Group == "" {
+		return errors.ErrInvalidArgument
+	}
+
+	buf, err := l.PackBuffer(args.Message.Data, true)
+	if err != nil {
+		return err
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	l.dispatcher.IterateEndpoint(func(_ string, ep *endpoint.Endpoint) bool {
+		eg.Go(func() error {
+			message, err := packet.PackBuffer(&packet.Message{
+				Seq:    args.Message.Seq,
+				Route:  args.Message.Route,
+				Buffer: buf,
+			})
+			if err != nil {
+				return err
+			}
+
+			client, err := l.builder.Build(ep)
+			if err != nil {
+				return err
+			}
+
+			return client.PushGroup(ctx, args.Group, message)
+		})
+
+		return true
+	})
+
+	return eg.Wait()
+}
+
 // 执行RPC调用
 func (l *GateLinker) doRPC(ctx context.Context, uid int64, fn func(client *gate.Client) (bool, interface{}, error)) (interface{}, error) {
 	var (
@@ -505,7 +795,7 @@ func (l *GateLinker) doBuildClient(gid string) (*gate.Client, error) {
 		return nil, err
 	}
 
-	return l.builder.Build(ep.Address())
+	return l.builder.Build(ep)
 }
 
 // PackMessage 打包消息
@@ -30,7 +30,7 @@ func NewNodeLinker(ctx context.Context, opts *Options) *NodeLinker {
 	l := &NodeLinker{
 		ctx:        ctx,
 		opts:       opts,
-		builder:    node.NewBuilder(&node.Options{InsID: opts.InsID, InsKind: opts.InsKind}),
+		builder:    node.NewBuilder(&node.Options{InsID: opts.InsID, InsKind: opts.InsKind, TLSConfig: opts.TLSConfig, Pool: opts.Pool}),
 		dispatcher: dispatcher.NewDispatcher(opts.BalanceStrategy),
 		sources:    make(map[int64]map[string]string),
 	}
@@ -68,6 +68,16 @@ func (l *NodeLinker) Has(nid string) bool {
 	return err == nil
 }
  
+// RouteGroup 获取路由所属组
+func (l *NodeLinker) RouteGroup(routeID int32) (string, bool) {
+	route, err := l.dispatcher.FindRoute(routeID)
+	if err != nil {
+		return "", false
+	}
+
+	return route.Group(), true
+}
+
 // Locate 定位用户所在节点
 func (l *NodeLinker) Locate(ctx context.Context, uid int64, name string) (string, error) {
 	if l.opts.Locator == nil {
@@ -147,7 +157,7 @@ func (l *NodeLinker) Deliver(ctx context.Context, args *DeliverArgs) error {
 	}
 
 	if args.NID != "" {
-		client, err := l.doBuildClient(args.NID)
+		client, err := l.doBuildRouteClient(args.Route, args.NID)
 		if err != nil {
 			return err
 		}
@@ -176,11 +186,15 @@ func (l *NodeLinker) Trigger(ctx context.Context, args *TriggerArgs) error {
 
 	event.IterateEndpoint(func(_ string, ep *endpoint.Endpoint) bool {
 		eg.Go(func() error {
-			client, err := l.builder.Build(ep.Address())
+			client, err := l.builder.Build(ep)
 			if err != nil {
 				return err
 			}
 
+			if len(args.Attrs) > 0 {
+				return client.TriggerAttrs(ctx, args.Event, args.CID, args.UID, args.Attrs)
+			}
+
 			return client.Trigger(ctx, args.Event, args.CID, args.UID)
 		})
 
@@ -273,7 +287,7 @@ func (l *NodeLinker) doRPC(ctx context.Context, routeID int32, uid int64, fn fun
 			return nil, err
 		}
 
-		client, err = l.builder.Build(ep.Address())
+		client, err = l.builder.Build(ep)
 		if err != nil {
 			return nil, err
 		}
@@ -303,7 +317,27 @@ func (l *NodeLinker) doBuildClient(nid string) (*node.Client, error) {
 		return nil, err
 	}
 
-	return l.builder.Build(ep.Address())
+	return l.builder.Build(ep)
+}
+
+// 构建提供指定路由的节点客户端
+// 节点必须为分发器中该路由的已知端点，网关不允许直接投递内部路由
+func (l *NodeLinker) doBuildRouteClient(routeID int32, nid string) (*node.Client, error) {
+	route, err := l.dispatcher.FindRoute(routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.opts.InsKind == cluster.Gate && route.Internal() {
+		return nil, errors.ErrIllegalRequest
+	}
+
+	ep, err := route.FindEndpoint(nid)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.builder.Build(ep)
 }
 
 // 打包消息
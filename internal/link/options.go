@@ -1,14 +1,18 @@
 package link
 
 import (
+	"crypto/tls"
 	"gatesvr/cluster"
 	"gatesvr/crypto"
 	"gatesvr/encoding"
 	"gatesvr/internal/dispatcher"
+	"gatesvr/internal/transporter/gate"
 	"gatesvr/locate"
 	"gatesvr/registry"
 )
 
+type PoolOptions = gate.PoolOptions
+
 type Options struct {
 	InsID           string                     // 实例ID
 	InsKind         cluster.Kind               // 实例类型
@@ -17,4 +21,6 @@ type Options struct {
 	Registry        registry.Registry          // 注册器
 	Encryptor       crypto.Encryptor           // 加密器
 	BalanceStrategy dispatcher.BalanceStrategy // 负载均衡策略
+	TLSConfig       *tls.Config                // TLS配置，连接安全端点时使用
+	Pool            PoolOptions                // 连接池配置，为零值时使用默认配置
 }
@@ -2,6 +2,8 @@ package link
 
 import (
 	"gatesvr/cluster"
+	"gatesvr/session"
+	"time"
 )
 
 type (
@@ -23,7 +25,64 @@ type DeliverArgs struct {
 }
 
 type TriggerArgs struct {
-	Event cluster.Event // 事件
-	CID   int64         // 连接ID
-	UID   int64         // 用户ID
+	Event cluster.Event     // 事件
+	CID   int64             // 连接ID
+	UID   int64             // 用户ID
+	Attrs map[string]string // 会话属性，不为空时以携带会话属性的方式触发事件
+}
+
+type JoinGroupArgs struct {
+	GID    string       // 网关ID，会话类型为用户时可忽略此参数
+	Kind   session.Kind // 会话类型，session.Conn 或 session.User
+	Target int64        // 会话目标，CID 或 UID
+	Group  string       // 分组名称
+}
+
+type LeaveGroupArgs struct {
+	GID    string       // 网关ID，会话类型为用户时可忽略此参数
+	Kind   session.Kind // 会话类型，session.Conn 或 session.User
+	Target int64        // 会话目标，CID 或 UID
+	Group  string       // 分组名称
+}
+
+type PushGroupArgs struct {
+	Group   string   // 分组名称
+	Message *Message // 消息
+}
+
+type GetAttrArgs struct {
+	GID    string       // 网关ID，会话类型为用户时可忽略此参数
+	Kind   session.Kind // 会话类型，session.Conn 或 session.User
+	Target int64        // 会话目标，CID 或 UID
+	Key    string       // 属性名
+}
+
+type SetAttrArgs struct {
+	GID    string       // 网关ID，会话类型为用户时可忽略此参数
+	Kind   session.Kind // 会话类型，session.Conn 或 session.User
+	Target int64        // 会话目标，CID 或 UID
+	Key    string       // 属性名
+	Value  string       // 属性值，为空时删除该属性
+}
+
+type PushDeviceArgs struct {
+	GID     string   // 网关ID，为空时定位用户所在网关
+	UID     int64    // 用户ID
+	Device  string   // 设备标签
+	Message *Message // 消息
+}
+
+type PushReliableArgs struct {
+	GID     string          // 网关ID，为空时定位用户所在网关
+	UID     int64           // 用户ID
+	Message *Message        // 消息
+	Await   bool            // 是否等待客户端确认，等待时客户端确认后返回，投递失败时返回错误
+	Failed  func(err error) // 投递失败回调，不等待客户端确认时异步回调，Timeout内未收到确认亦视为失败
+	Timeout time.Duration   // 异步回调时等待确认的最长时间，应为网关的确认超时时间×(重传次数+1)，为0时按配置中网关的确认超时时间及重传次数计算
+}
+
+type ResumeArgs struct {
+	GID string // 用户挂起所在的网关ID
+	UID int64  // 用户ID
+	CID int64  // 挂起前的连接ID
 }
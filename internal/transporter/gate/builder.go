@@ -1,15 +1,22 @@
 package gate
 
 import (
+	"crypto/tls"
 	"gatesvr/cluster"
+	"gatesvr/core/endpoint"
+	"gatesvr/errors"
 	"gatesvr/internal/transporter/internal/client"
 	"golang.org/x/sync/singleflight"
 	"sync"
 )
 
+type PoolOptions = client.PoolOptions
+
 type Options struct {
-	InsID   string       // 实例ID
-	InsKind cluster.Kind // 实例类型
+	InsID     string       // 实例ID
+	InsKind   cluster.Kind // 实例类型
+	TLSConfig *tls.Config  // TLS配置，连接安全端点时使用
+	Pool      PoolOptions  // 连接池配置
 }
 
 type Builder struct {
@@ -25,18 +32,32 @@ func NewBuilder(opts *Options) *Builder {
 }
 
 // Build 构建客户端
-func (b *Builder) Build(addr string) (*Client, error) {
+// 端点为安全端点时使用TLS建立连接
+func (b *Builder) Build(ep *endpoint.Endpoint) (*Client, error) {
+	addr := ep.Address()
+
 	if cli, ok := b.clients.Load(addr); ok {
 		return cli.(*Client), nil
 	}
 
 	cli, err, _ := b.sfg.Do(addr, func() (interface{}, error) {
-		cli := NewClient(client.NewClient(&client.Options{
+		opts := &client.Options{
 			Addr:         addr,
 			InsID:        b.opts.InsID,
 			InsKind:      b.opts.InsKind,
 			CloseHandler: func() { b.clients.Delete(addr) },
-		}))
+			Pool:         b.opts.Pool,
+		}
+
+		if ep.IsSecure() {
+			if b.opts.TLSConfig == nil {
+				return nil, errors.ErrMissingTLSConfig
+			}
+
+			opts.TLSConfig = b.opts.TLSConfig
+		}
+
+		cli := NewClient(client.NewClient(opts))
 
 		b.clients.Store(addr, cli)
 
@@ -3,6 +3,7 @@ package gate_test
 import (
 	"context"
 	"gatesvr/cluster"
+	"gatesvr/core/endpoint"
 	"gatesvr/session"
 	"gatesvr/utils/xuuid"
 
@@ -17,7 +18,7 @@ func TestBuilder(t *testing.T) {
 		InsKind: cluster.Node,
 	})
 
-	client, err := builder.Build("127.0.0.1:49899")
+	client, err := builder.Build(endpoint.NewEndpoint("drpc", "127.0.0.1:49899", false))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -139,6 +139,136 @@ func (c *Client) Broadcast(ctx context.Context, kind session.Kind, message buffe
 	return c.cli.Send(ctx, protocol.EncodeBroadcastReq(0, kind, message))
 }
 
+// JoinGroup 加入分组
+func (c *Client) JoinGroup(ctx context.Context, group string, kind session.Kind, target int64) (bool, error) {
+	seq := c.doGenSequence()
+
+	buf, err := protocol.EncodeJoinGroupReq(seq, kind, target, group)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.cli.Call(ctx, seq, buf)
+	if err != nil {
+		return false, err
+	}
+
+	code, err := protocol.DecodeJoinGroupRes(res)
+	if err != nil {
+		return false, err
+	}
+
+	return code == codes.NotFoundSession, codes.CodeToError(code)
+}
+
+// LeaveGroup 退出分组
+func (c *Client) LeaveGroup(ctx context.Context, group string, kind session.Kind, target int64) (bool, error) {
+	seq := c.doGenSequence()
+
+	buf, err := protocol.EncodeLeaveGroupReq(seq, kind, target, group)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.cli.Call(ctx, seq, buf)
+	if err != nil {
+		return false, err
+	}
+
+	code, err := protocol.DecodeLeaveGroupRes(res)
+	if err != nil {
+		return false, err
+	}
+
+	return code == codes.NotFoundSession, codes.CodeToError(code)
+}
+
+// PushGroup 推送分组消息
+func (c *Client) PushGroup(ctx context.Context, group string, message buffer.Buffer) error {
+	buf, err := protocol.EncodePushGroupReq(0, group, message)
+	if err != nil {
+		return err
+	}
+
+	return c.cli.Send(ctx, buf)
+}
+
+// PushReliable 可靠推送消息，await为true时等待客户端确认或投递失败后返回，ctx需设置等待截止时间
+func (c *Client) PushReliable(ctx context.Context, uid int64, message buffer.Buffer, await bool) (bool, error) {
+	if !await {
+		return false, c.cli.Send(ctx, protocol.EncodePushReliableReq(0, uid, message), uid)
+	}
+
+	seq := c.doGenSequence()
+
+	// 等待客户端确认的时长不确定，仅受ctx控制
+	res, err := c.cli.Await(ctx, seq, protocol.EncodePushReliableReq(seq, uid, message), uid)
+	if err != nil {
+		return false, err
+	}
+
+	code, err := protocol.DecodePushReliableRes(res)
+	if err != nil {
+		return false, err
+	}
+
+	return code == codes.NotFoundSession, codes.CodeToError(code)
+}
+
+// PushDevice 推送用户指定设备消息
+func (c *Client) PushDevice(ctx context.Context, uid int64, device string, message buffer.Buffer) error {
+	buf, err := protocol.EncodePushDeviceReq(0, uid, device, message)
+	if err != nil {
+		return err
+	}
+
+	return c.cli.Send(ctx, buf, uid)
+}
+
+// GetAttr 获取会话属性
+func (c *Client) GetAttr(ctx context.Context, kind session.Kind, target int64, key string) (string, bool, error) {
+	seq := c.doGenSequence()
+
+	buf, err := protocol.EncodeGetAttrReq(seq, kind, target, key)
+	if err != nil {
+		return "", false, err
+	}
+
+	res, err := c.cli.Call(ctx, seq, buf)
+	if err != nil {
+		return "", false, err
+	}
+
+	code, value, err := protocol.DecodeGetAttrRes(res)
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, code == codes.NotFoundSession, codes.CodeToError(code)
+}
+
+// SetAttr 设置会话属性
+func (c *Client) SetAttr(ctx context.Context, kind session.Kind, target int64, key, value string) (bool, error) {
+	seq := c.doGenSequence()
+
+	buf, err := protocol.EncodeSetAttrReq(seq, kind, target, key, value)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.cli.Call(ctx, seq, buf)
+	if err != nil {
+		return false, err
+	}
+
+	code, err := protocol.DecodeSetAttrRes(res)
+	if err != nil {
+		return false, err
+	}
+
+	return code == codes.NotFoundSession, codes.CodeToError(code)
+}
+
 // GetState 获取状态
 func (c *Client) GetState(ctx context.Context) (cluster.State, error) {
 	seq := c.doGenSequence()
@@ -177,6 +307,25 @@ func (c *Client) SetState(ctx context.Context, state cluster.State) error {
 	return codes.CodeToError(code)
 }
 
+// Resume 恢复在对端网关挂起的用户，cid为挂起前的连接ID
+func (c *Client) Resume(ctx context.Context, uid, cid int64) ([][]byte, error) {
+	seq := c.doGenSequence()
+
+	buf := protocol.EncodeResumeReq(seq, uid, cid)
+
+	res, err := c.cli.Call(ctx, seq, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	code, messages, err := protocol.DecodeResumeRes(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, codes.CodeToError(code)
+}
+
 // 生成序列号，规避生成序列号为0的编号
 func (c *Client) doGenSequence() (seq uint64) {
 	for {
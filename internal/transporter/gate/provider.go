@@ -25,6 +25,22 @@ type Provider interface {
 	Multicast(ctx context.Context, kind session.Kind, targets []int64, message []byte) (total int64, err error)
 	// Broadcast 推送广播消息
 	Broadcast(ctx context.Context, kind session.Kind, message []byte) (total int64, err error)
+	// JoinGroup 加入分组
+	JoinGroup(ctx context.Context, group string, kind session.Kind, target int64) error
+	// LeaveGroup 退出分组
+	LeaveGroup(ctx context.Context, group string, kind session.Kind, target int64) error
+	// PushGroup 推送分组消息
+	PushGroup(ctx context.Context, group string, message []byte) (total int64, err error)
+	// PushReliable 可靠推送消息，done不为nil时在客户端确认或投递失败后回调
+	PushReliable(ctx context.Context, uid int64, message []byte, done func(err error)) error
+	// PushDevice 推送用户指定设备消息
+	PushDevice(ctx context.Context, uid int64, device string, message []byte) (total int64, err error)
+	// GetAttr 获取会话属性
+	GetAttr(ctx context.Context, kind session.Kind, target int64, key string) (value string, err error)
+	// SetAttr 设置会话属性
+	SetAttr(ctx context.Context, kind session.Kind, target int64, key, value string) error
+	// Resume 释放在本网关挂起的用户，供用户在网关gid恢复，cid为挂起前的连接ID，返回挂起期间缓存的消息
+	Resume(ctx context.Context, gid string, uid, cid int64) (messages [][]byte, err error)
 	// GetState 获取状态
 	GetState() (cluster.State, error)
 	// SetState 设置状态
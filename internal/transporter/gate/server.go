@@ -2,6 +2,9 @@ package gate
 
 import (
 	"context"
+	"crypto/tls"
+	"gatesvr/cluster"
+	"gatesvr/errors"
 	"gatesvr/internal/transporter/internal/codes"
 	"gatesvr/internal/transporter/internal/protocol"
 	"gatesvr/internal/transporter/internal/route"
@@ -13,8 +16,13 @@ type Server struct {
 	provider Provider
 }
 
-func NewServer(addr string, provider Provider) (*Server, error) {
-	serv, err := server.NewServer(&server.Options{Addr: addr})
+func NewServer(addr string, provider Provider, tlsConfig ...*tls.Config) (*Server, error) {
+	opts := &server.Options{Addr: addr}
+	if len(tlsConfig) > 0 {
+		opts.TLSConfig = tlsConfig[0]
+	}
+
+	serv, err := server.NewServer(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -26,25 +34,39 @@ func NewServer(addr string, provider Provider) (*Server, error) {
 }
 
 func (s *Server) init() {
-	s.RegisterHandler(route.Bind, s.bind)
-	s.RegisterHandler(route.Unbind, s.unbind)
-	s.RegisterHandler(route.GetIP, s.getIP)
-	s.RegisterHandler(route.Stat, s.stat)
-	s.RegisterHandler(route.IsOnline, s.isOnline)
-	s.RegisterHandler(route.Disconnect, s.disconnect)
-	s.RegisterHandler(route.Push, s.push)
-	s.RegisterHandler(route.Multicast, s.multicast)
-	s.RegisterHandler(route.Broadcast, s.broadcast)
+	s.RegisterContextHandler(route.Bind, s.bind)
+	s.RegisterContextHandler(route.Unbind, s.unbind)
+	s.RegisterContextHandler(route.GetIP, s.getIP)
+	s.RegisterContextHandler(route.Stat, s.stat)
+	s.RegisterContextHandler(route.IsOnline, s.isOnline)
+	s.RegisterContextHandler(route.Disconnect, s.disconnect)
+	s.RegisterContextHandler(route.Push, s.push)
+	s.RegisterContextHandler(route.Multicast, s.multicast)
+	s.RegisterContextHandler(route.Broadcast, s.broadcast)
+	s.RegisterContextHandler(route.GetState, s.getState)
+	s.RegisterContextHandler(route.SetState, s.setState)
+	s.RegisterContextHandler(route.JoinGroup, s.joinGroup)
+	s.RegisterContextHandler(route.LeaveGroup, s.leaveGroup)
+	s.RegisterContextHandler(route.PushGroup, s.pushGroup)
+	s.RegisterContextHandler(route.GetAttr, s.getAttr)
+	s.RegisterContextHandler(route.SetAttr, s.setAttr)
+	s.RegisterContextHandler(route.PushDevice, s.pushDevice)
+	s.RegisterContextHandler(route.PushReliable, s.pushReliable)
+	s.RegisterContextHandler(route.Resume, s.resume)
 }
 
 // 绑定用户
-func (s *Server) bind(conn *server.Conn, data []byte) error {
+func (s *Server) bind(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, cid, uid, err := protocol.DecodeBindReq(data)
 	if err != nil {
 		return err
 	}
 
-	if err = s.provider.Bind(context.Background(), cid, uid); seq == 0 {
+	if err = expired(ctx); err == nil {
+		err = s.provider.Bind(ctx, cid, uid)
+	}
+
+	if seq == 0 {
 		return err
 	} else {
 		return conn.Send(protocol.EncodeBindRes(seq, codes.ErrorToCode(err)))
@@ -52,13 +74,17 @@ func (s *Server) bind(conn *server.Conn, data []byte) error {
 }
 
 // 解绑用户
-func (s *Server) unbind(conn *server.Conn, data []byte) error {
+func (s *Server) unbind(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, uid, err := protocol.DecodeUnbindReq(data)
 	if err != nil {
 		return err
 	}
 
-	if err = s.provider.Unbind(context.Background(), uid); seq == 0 {
+	if err = expired(ctx); err == nil {
+		err = s.provider.Unbind(ctx, uid)
+	}
+
+	if seq == 0 {
 		return err
 	} else {
 		return conn.Send(protocol.EncodeUnbindRes(seq, codes.ErrorToCode(err)))
@@ -66,13 +92,18 @@ func (s *Server) unbind(conn *server.Conn, data []byte) error {
 }
 
 // 获取IP地址
-func (s *Server) getIP(conn *server.Conn, data []byte) error {
+func (s *Server) getIP(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, kind, target, err := protocol.DecodeGetIPReq(data)
 	if err != nil {
 		return err
 	}
 
-	if ip, err := s.provider.GetIP(context.Background(), kind, target); seq == 0 {
+	var ip string
+	if err = expired(ctx); err == nil {
+		ip, err = s.provider.GetIP(ctx, kind, target)
+	}
+
+	if seq == 0 {
 		return err
 	} else {
 		return conn.Send(protocol.EncodeGetIPRes(seq, codes.ErrorToCode(err), ip))
@@ -80,13 +111,18 @@ func (s *Server) getIP(conn *server.Conn, data []byte) error {
 }
 
 // 统计在线人数
-func (s *Server) stat(conn *server.Conn, data []byte) error {
+func (s *Server) stat(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, kind, err := protocol.DecodeStatReq(data)
 	if err != nil {
 		return err
 	}
 
-	if total, err := s.provider.Stat(context.Background(), kind); seq == 0 {
+	var total int64
+	if err = expired(ctx); err == nil {
+		total, err = s.provider.Stat(ctx, kind)
+	}
+
+	if seq == 0 {
 		return err
 	} else {
 		return conn.Send(protocol.EncodeStatRes(seq, codes.ErrorToCode(err), uint64(total)))
@@ -94,13 +130,18 @@ func (s *Server) stat(conn *server.Conn, data []byte) error {
 }
 
 // 检测用户是否在线
-func (s *Server) isOnline(conn *server.Conn, data []byte) error {
+func (s *Server) isOnline(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, kind, target, err := protocol.DecodeIsOnlineReq(data)
 	if err != nil {
 		return err
 	}
 
-	if isOnline, err := s.provider.IsOnline(context.Background(), kind, target); seq == 0 {
+	var isOnline bool
+	if err = expired(ctx); err == nil {
+		isOnline, err = s.provider.IsOnline(ctx, kind, target)
+	}
+
+	if seq == 0 {
 		return err
 	} else {
 		return conn.Send(protocol.EncodeIsOnlineRes(seq, codes.ErrorToCode(err), isOnline))
@@ -108,13 +149,17 @@ func (s *Server) isOnline(conn *server.Conn, data []byte) error {
 }
 
 // 断开连接
-func (s *Server) disconnect(conn *server.Conn, data []byte) error {
+func (s *Server) disconnect(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, kind, target, force, err := protocol.DecodeDisconnectReq(data)
 	if err != nil {
 		return err
 	}
 
-	if err = s.provider.Disconnect(context.Background(), kind, target, force); seq == 0 {
+	if err = expired(ctx); err == nil {
+		err = s.provider.Disconnect(ctx, kind, target, force)
+	}
+
+	if seq == 0 {
 		return err
 	} else {
 		return conn.Send(protocol.EncodeDisconnectRes(seq, codes.ErrorToCode(err)))
@@ -122,13 +167,17 @@ func (s *Server) disconnect(conn *server.Conn, data []byte) error {
 }
 
 // 推送单个消息
-func (s *Server) push(conn *server.Conn, data []byte) error {
+func (s *Server) push(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, kind, target, message, err := protocol.DecodePushReq(data)
 	if err != nil {
 		return err
 	}
 
-	if err = s.provider.Push(context.Background(), kind, target, message); seq == 0 {
+	if err = expired(ctx); err == nil {
+		err = s.provider.Push(ctx, kind, target, message)
+	}
+
+	if seq == 0 {
 		return err
 	} else {
 		return conn.Send(protocol.EncodePushRes(seq, codes.ErrorToCode(err)))
@@ -136,13 +185,18 @@ func (s *Server) push(conn *server.Conn, data []byte) error {
 }
 
 // 推送组播消息
-func (s *Server) multicast(conn *server.Conn, data []byte) error {
+func (s *Server) multicast(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, kind, targets, message, err := protocol.DecodeMulticastReq(data)
 	if err != nil {
 		return err
 	}
 
-	if total, err := s.provider.Multicast(context.Background(), kind, targets, message); seq == 0 {
+	var total int64
+	if err = expired(ctx); err == nil {
+		total, err = s.provider.Multicast(ctx, kind, targets, message)
+	}
+
+	if seq == 0 {
 		return err
 	} else {
 		return conn.Send(protocol.EncodeMulticastRes(seq, codes.ErrorToCode(err), uint64(total)))
@@ -150,13 +204,18 @@ func (s *Server) multicast(conn *server.Conn, data []byte) error {
 }
 
 // 推送广播消息
-func (s *Server) broadcast(conn *server.Conn, data []byte) error {
+func (s *Server) broadcast(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, kind, message, err := protocol.DecodeBroadcastReq(data)
 	if err != nil {
 		return err
 	}
 
-	if total, err := s.provider.Broadcast(context.Background(), kind, message); seq == 0 {
+	var total int64
+	if err = expired(ctx); err == nil {
+		total, err = s.provider.Broadcast(ctx, kind, message)
+	}
+
+	if seq == 0 {
 		return err
 	} else {
 		return conn.Send(protocol.EncodeBroadcastRes(seq, codes.ErrorToCode(err), uint64(total)))
@@ -164,25 +223,204 @@ func (s *Server) broadcast(conn *server.Conn, data []byte) error {
 }
 
 // 获取状态
-func (s *Server) getState(conn *server.Conn, data []byte) error {
+func (s *Server) getState(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, err := protocol.DecodeGetStateReq(data)
 	if err != nil {
 		return err
 	}
 
-	state, err := s.provider.GetState()
+	var state cluster.State
+	if err = expired(ctx); err == nil {
+		state, err = s.provider.GetState()
+	}
 
 	return conn.Send(protocol.EncodeGetStateRes(seq, codes.ErrorToCode(err), state))
 }
 
 // 设置状态
-func (s *Server) setState(conn *server.Conn, data []byte) error {
+func (s *Server) setState(ctx context.Context, conn *server.Conn, data []byte) error {
 	seq, state, err := protocol.DecodeSetStateReq(data)
 	if err != nil {
 		return err
 	}
 
-	err = s.provider.SetState(state)
+	if err = expired(ctx); err == nil {
+		err = s.provider.SetState(state)
+	}
 
 	return conn.Send(protocol.EncodeSetStateRes(seq, codes.ErrorToCode(err)))
 }
+
+// 加入分组
+func (s *Server) joinGroup(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, kind, target, group, err := protocol.DecodeJoinGroupReq(data)
+	if err != nil {
+		return err
+	}
+
+	if err = expired(ctx); err == nil {
+		err = s.provider.JoinGroup(ctx, group, kind, target)
+	}
+
+	if seq == 0 {
+		return err
+	} else {
+		return conn.Send(protocol.EncodeJoinGroupRes(seq, codes.ErrorToCode(err)))
+	}
+}
+
+// 退出分组
+func (s *Server) leaveGroup(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, kind, target, group, err := protocol.DecodeLeaveGroupReq(data)
+	if err != nil {
+		return err
+	}
+
+	if err = expired(ctx); err == nil {
+		err = s.provider.LeaveGroup(ctx, group, kind, target)
+	}
+
+	if seq == 0 {
+		return err
+	} else {
+		return conn.Send(protocol.EncodeLeaveGroupRes(seq, codes.ErrorToCode(err)))
+	}
+}
+
+// 推送分组消息
+func (s *Server) pushGroup(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, group, message, err := protocol.DecodePushGroupReq(data)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	if err = expired(ctx); err == nil {
+		total, err = s.provider.PushGroup(ctx, group, message)
+	}
+
+	if seq == 0 {
+		return err
+	} else {
+		return conn.Send(protocol.EncodePushGroupRes(seq, codes.ErrorToCode(err), uint64(total)))
+	}
+}
+
+// 获取会话属性
+func (s *Server) getAttr(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, kind, target, key, err := protocol.DecodeGetAttrReq(data)
+	if err != nil {
+		return err
+	}
+
+	var value string
+	if err = expired(ctx); err == nil {
+		value, err = s.provider.GetAttr(ctx, kind, target, key)
+	}
+
+	if seq == 0 {
+		return err
+	} else {
+		return conn.Send(protocol.EncodeGetAttrRes(seq, codes.ErrorToCode(err), value))
+	}
+}
+
+// 设置会话属性
+func (s *Server) setAttr(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, kind, target, key, value, err := protocol.DecodeSetAttrReq(data)
+	if err != nil {
+		return err
+	}
+
+	if err = expired(ctx); err == nil {
+		err = s.provider.SetAttr(ctx, kind, target, key, value)
+	}
+
+	if seq == 0 {
+		return err
+	} else {
+		return conn.Send(protocol.EncodeSetAttrRes(seq, codes.ErrorToCode(err)))
+	}
+}
+
+// 推送用户指定设备消息
+func (s *Server) pushDevice(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, uid, device, message, err := protocol.DecodePushDeviceReq(data)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	if err = expired(ctx); err == nil {
+		total, err = s.provider.PushDevice(ctx, uid, device, message)
+	}
+
+	if seq == 0 {
+		return err
+	} else {
+		return conn.Send(protocol.EncodePushDeviceRes(seq, codes.ErrorToCode(err), uint64(total)))
+	}
+}
+
+// 可靠推送消息，客户端确认或投递失败后响应
+func (s *Server) pushReliable(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, uid, message, err := protocol.DecodePushReliableReq(data)
+	if err != nil {
+		return err
+	}
+
+	if err = expired(ctx); err != nil {
+		if seq == 0 {
+			return err
+		}
+
+		return conn.Send(protocol.EncodePushReliableRes(seq, codes.ErrorToCode(err)))
+	}
+
+	// 消息需保留至客户端确认
+	message = append([]byte(nil), message...)
+
+	if seq == 0 {
+		return s.provider.PushReliable(ctx, uid, message, nil)
+	}
+
+	if err = s.provider.PushReliable(ctx, uid, message, func(err error) {
+		_ = conn.Send(protocol.EncodePushReliableRes(seq, codes.ErrorToCode(err)))
+	}); err != nil {
+		return conn.Send(protocol.EncodePushReliableRes(seq, codes.ErrorToCode(err)))
+	}
+
+	return nil
+}
+
+// 恢复挂起的用户
+func (s *Server) resume(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, uid, cid, err := protocol.DecodeResumeReq(data)
+	if err != nil {
+		return err
+	}
+
+	if conn.InsKind != cluster.Gate {
+		return errors.ErrIllegalRequest
+	}
+
+	var messages [][]byte
+	if err = expired(ctx); err == nil {
+		messages, err = s.provider.Resume(ctx, conn.InsID, uid, cid)
+	}
+
+	if seq == 0 {
+		return err
+	} else {
+		return conn.Send(protocol.EncodeResumeRes(seq, codes.ErrorToCode(err), messages))
+	}
+}
+
+// 检测请求是否已超时，已超时的请求跳过执行并以DeadlineExceeded响应
+func expired(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return errors.ErrDeadlineExceeded
+	}
+
+	return nil
+}
@@ -76,6 +76,46 @@ func (p *provider) Disconnect(ctx context.Context, kind session.Kind, target int
 	return nil
 }
 
+// JoinGroup 加入分组
+func (p *provider) JoinGroup(ctx context.Context, group string, kind session.Kind, target int64) error {
+	return nil
+}
+
+// LeaveGroup 退出分组
+func (p *provider) LeaveGroup(ctx context.Context, group string, kind session.Kind, target int64) error {
+	return nil
+}
+
+// PushGroup 推送分组消息（异步）
+func (p *provider) PushGroup(ctx context.Context, group string, message []byte) (total int64, err error) {
+	return
+}
+
+// PushReliable 可靠推送消息
+func (p *provider) PushReliable(ctx context.Context, uid int64, message []byte, done func(err error)) error {
+	return nil
+}
+
+// PushDevice 推送用户指定设备消息（异步）
+func (p *provider) PushDevice(ctx context.Context, uid int64, device string, message []byte) (total int64, err error) {
+	return
+}
+
+// GetAttr 获取会话属性
+func (p *provider) GetAttr(ctx context.Context, kind session.Kind, target int64, key string) (value string, err error) {
+	return
+}
+
+// SetAttr 设置会话属性
+func (p *provider) SetAttr(ctx context.Context, kind session.Kind, target int64, key, value string) error {
+	return nil
+}
+
+// Resume 释放挂起的用户
+func (p *provider) Resume(ctx context.Context, gid string, uid, cid int64) (messages [][]byte, err error) {
+	return
+}
+
 // GetState 获取状态
 func (p *provider) GetState() (cluster.State, error) {
 	return cluster.Work, nil
@@ -4,19 +4,16 @@ import (
 	"context"
 	"gatesvr/core/buffer"
 	"gatesvr/errors"
+	"gatesvr/internal/transporter/internal/protocol"
 
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-const (
-	ordered   = 20 // 有序消息连接数
-	unordered = 10 // 无序消息连接数
-)
-
 const (
 	defaultTimeout = 3 * time.Second // 调用超时时间
+	growThreshold  = 1024            // 无序消息写入队列积压超过该值时扩容连接
 )
 
 type chWrite struct {
@@ -27,52 +24,62 @@ type chWrite struct {
 }
 
 type Client struct {
-	opts        *Options       // 配置
-	chWrite     chan *chWrite  // 写入队列
-	connections []*Conn        // 连接
-	wg          sync.WaitGroup // 等待组
-	closed      atomic.Bool    // 已关闭
+	opts      *Options      // 配置
+	chWrite   chan *chWrite // 无序消息写入队列
+	rw        sync.RWMutex  // 连接池读写锁
+	ordered   []*Conn       // 有序消息连接，按需建立
+	unordered []*Conn       // 无序消息连接，按需建立与扩容
+	growing   atomic.Bool   // 扩容中
+	closed    atomic.Bool   // 已关闭
+
+	negotiated atomic.Pointer[negotiation] // 握手协商结果
+}
+
+type negotiation struct {
+	version      uint16              // 协议版本
+	capabilities protocol.Capability // 协议能力
 }
 
 func NewClient(opts *Options) *Client {
 	c := &Client{}
 	c.opts = opts
 	c.chWrite = make(chan *chWrite, 10240)
-	c.connections = make([]*Conn, 0, ordered+unordered)
-	c.init()
+	c.ordered = make([]*Conn, opts.Pool.ordered())
+	c.unordered = make([]*Conn, 0, opts.Pool.maxUnordered())
 
 	return c
 }
 
-// Call 调用
+// Call 调用，ctx未设置截止时间时使用默认超时时间
+// 对端支持时ctx的剩余时间随请求传递至对端，对端据此跳过已超时的请求
 func (c *Client) Call(ctx context.Context, seq uint64, buf buffer.Buffer, idx ...int64) ([]byte, error) {
-	if c.closed.Load() {
-		return nil, errors.ErrClientClosed
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
 	}
 
-	call := make(chan []byte)
+	return c.Await(ctx, seq, buf, idx...)
+}
 
-	conn := c.load(idx...)
+// Await 调用并等待响应，等待时长仅受ctx控制，适用于响应时间不确定的调用
+func (c *Client) Await(ctx context.Context, seq uint64, buf buffer.Buffer, idx ...int64) ([]byte, error) {
+	call := make(chan []byte)
 
-	if err := conn.send(&chWrite{
+	conn, err := c.send(&chWrite{
 		ctx:  ctx,
 		seq:  seq,
 		buf:  buf,
 		call: call,
-	}); err != nil {
+	}, idx...)
+	if err != nil {
 		return nil, err
 	}
 
-	ctx1, cancel1 := context.WithTimeout(ctx, defaultTimeout)
-	defer cancel1()
-
 	select {
 	case <-ctx.Done():
 		conn.cancel(seq)
 		return nil, ctx.Err()
-	case <-ctx1.Done():
-		conn.cancel(seq)
-		return nil, ctx1.Err()
 	case data := <-call:
 		return data, nil
 	}
@@ -80,56 +87,199 @@ func (c *Client) Call(ctx context.Context, seq uint64, buf buffer.Buffer, idx ..
 
 // Send 发送
 func (c *Client) Send(ctx context.Context, buf buffer.Buffer, idx ...int64) error {
+	_, err := c.send(&chWrite{
+		ctx: ctx,
+		buf: buf,
+	}, idx...)
+
+	return err
+}
+
+// Version 获取与对端协商的协议版本，尚未完成握手时返回0
+func (c *Client) Version() uint16 {
+	if n := c.negotiated.Load(); n != nil {
+		return n.version
+	}
+
+	return 0
+}
+
+// Capabilities 获取与对端协商的协议能力，尚未完成握手时返回0
+// 连接按需建立，首个连接完成握手前无法确定对端能力，调用方应按不支持处理
+func (c *Client) Capabilities() protocol.Capability {
+	if n := c.negotiated.Load(); n != nil {
+		return n.capabilities
+	}
+
+	return 0
+}
+
+// 发送消息
+// 持有连接池读锁期间尝试写入队列，避免连接在回收后仍有消息写入
+// 队列已满时释放读锁后再等待写入，避免阻塞连接池写锁；队列非空时连接不会被回收
+func (c *Client) send(ch *chWrite, idx ...int64) (*Conn, error) {
 	if c.closed.Load() {
-		return errors.ErrClientClosed
+		return nil, errors.ErrClientClosed
 	}
 
-	conn := c.load(idx...)
+	c.rw.RLock()
+	conn, ok := c.load(idx...)
+	if !ok {
+		c.rw.RUnlock()
+		conn = c.create(idx...)
+		c.rw.RLock()
 
-	return conn.send(&chWrite{
-		ctx: ctx,
-		buf: buf,
-	})
+		// 建立期间连接可能已被回收
+		if curr, ok := c.load(idx...); ok {
+			conn = curr
+		} else {
+			c.rw.RUnlock()
+			return nil, errors.ErrConnectionClosed
+		}
+	}
+	ok, err := conn.trySend(ch)
+	c.rw.RUnlock()
+
+	if err == nil && !ok {
+		err = conn.send(ch)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(idx) == 0 && len(c.chWrite) > growThreshold {
+		c.grow()
+	}
+
+	return conn, nil
+}
+
+// 获取连接，调用方需持有连接池读锁
+// 有序消息按目标固定到同一连接上，以保证同一目标的消息有序
+func (c *Client) load(idx ...int64) (*Conn, bool) {
+	if len(idx) > 0 {
+		conn := c.ordered[c.slot(idx[0])]
+		return conn, conn != nil
+	}
+
+	if len(c.unordered) == 0 {
+		return nil, false
+	}
+
+	return c.unordered[0], true
 }
 
-// 获取连接
-func (c *Client) load(idx ...int64) *Conn {
+// 建立连接
+func (c *Client) create(idx ...int64) *Conn {
+	c.rw.Lock()
+	defer c.rw.Unlock()
+
 	if len(idx) > 0 {
-		return c.connections[idx[0]%ordered]
-	} else {
-		return c.connections[ordered]
+		slot := c.slot(idx[0])
+
+		if c.ordered[slot] == nil {
+			c.ordered[slot] = newConn(c)
+		}
+
+		return c.ordered[slot]
+	}
+
+	for len(c.unordered) < c.opts.Pool.unordered() {
+		c.unordered = append(c.unordered, newConn(c, c.chWrite))
+	}
+
+	return c.unordered[0]
+}
+
+// 扩容无序消息连接
+func (c *Client) grow() {
+	if !c.growing.CompareAndSwap(false, true) {
+		return
+	}
+	defer c.growing.Store(false)
+
+	if !c.rw.TryLock() {
+		return
+	}
+	defer c.rw.Unlock()
+
+	if len(c.unordered) > 0 && len(c.unordered) < c.opts.Pool.maxUnordered() {
+		c.unordered = append(c.unordered, newConn(c, c.chWrite))
 	}
 }
 
-// 新建连接
-func (c *Client) init() {
-	c.wg.Add(ordered + unordered)
+// 获取有序消息连接槽位
+func (c *Client) slot(idx int64) int {
+	slot := idx % int64(len(c.ordered))
+	if slot < 0 {
+		slot = -slot
+	}
+
+	return int(slot)
+}
+
+// 回收空闲连接
+// 仅在写入队列与等待队列均为空时回收；无法立即获取连接池锁时放弃本次回收，避免与阻塞在该连接队列上的发送方死锁
+func (c *Client) release(conn *Conn) bool {
+	if !c.rw.TryLock() {
+		return false
+	}
+	defer c.rw.Unlock()
+
+	if len(conn.chWrite) > 0 || !conn.pending.empty() {
+		return false
+	}
 
-	go c.wait()
+	return c.remove(conn)
+}
 
-	for i := 0; i < ordered; i++ {
-		c.connections = append(c.connections, newConn(c))
+// 移除连接，调用方需持有连接池写锁
+func (c *Client) remove(conn *Conn) bool {
+	for i, item := range c.ordered {
+		if item == conn {
+			c.ordered[i] = nil
+			return true
+		}
 	}
 
-	for i := 0; i < unordered; i++ {
-		c.connections = append(c.connections, newConn(c, c.chWrite))
+	for i, item := range c.unordered {
+		if item == conn {
+			c.unordered = append(c.unordered[:i], c.unordered[i+1:]...)
+			return true
+		}
 	}
+
+	return false
 }
 
-// 连接断开
-func (c *Client) done() {
-	c.wg.Done()
+// 连接池是否为空，调用方需持有连接池锁
+func (c *Client) empty() bool {
+	for _, conn := range c.ordered {
+		if conn != nil {
+			return false
+		}
+	}
+
+	return len(c.unordered) == 0
+}
+
+// 记录握手协商结果
+func (c *Client) negotiate(version uint16, capabilities protocol.Capability) {
+	c.negotiated.Store(&negotiation{version: version, capabilities: capabilities})
 }
 
-// 等待客户端连接全部关闭
-func (c *Client) wait() {
-	c.wg.Wait()
-	c.closed.Store(true)
-	c.connections = nil
+// 连接拨号或握手失败
+// 连接池中已无其他连接时关闭客户端，其余连接在写入协程检测到客户端关闭后自行关闭
+func (c *Client) fail(conn *Conn) {
+	c.rw.Lock()
+	c.remove(conn)
+	empty := c.empty()
+	c.rw.Unlock()
 
-	time.AfterFunc(time.Second, func() {
-		close(c.chWrite)
-	})
+	if !empty || !c.closed.CompareAndSwap(false, true) {
+		return
+	}
 
 	if c.opts.CloseHandler != nil {
 		c.opts.CloseHandler()
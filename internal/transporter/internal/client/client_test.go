@@ -0,0 +1,341 @@
+package client
+
+import (
+	"context"
+	"gatesvr/core/buffer"
+	"gatesvr/internal/transporter/internal/protocol"
+	"gatesvr/internal/transporter/internal/route"
+	"gatesvr/internal/transporter/internal/server"
+	"gatesvr/session"
+	"gatesvr/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient_Pool(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		targets []int64
+	)
+
+	srv, err := server.NewServer(&server.Options{Addr: "127.0.0.1:49897"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.RegisterHandler(route.Push, func(conn *server.Conn, data []byte) error {
+		_, _, target, _, err := protocol.DecodePushReq(data)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		targets = append(targets, target)
+		mu.Unlock()
+
+		return nil
+	})
+
+	go srv.Start()
+	defer srv.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	cli := NewClient(&Options{
+		Addr:  "127.0.0.1:49897",
+		Pool:  PoolOptions{Ordered: 4, Unordered: 2, IdleTimeout: 200 * time.Millisecond},
+		InsID: "test",
+	})
+
+	if conns := cli.count(); conns != 0 {
+		t.Fatalf("expected no connections before first use, got %d", conns)
+	}
+
+	send := func(n int) {
+		for i := 0; i < n; i++ {
+			buf := protocol.EncodePushReq(0, session.User, int64(i), buffer.NewNocopyBuffer())
+			if err := cli.Send(context.Background(), buf, 7); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	received := func(n int) {
+		deadline := time.Now().Add(3 * time.Second)
+		for {
+			mu.Lock()
+			count := len(targets)
+			mu.Unlock()
+
+			if count >= n {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d messages, got %d", n, count)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	send(100)
+	received(100)
+
+	if conns := cli.count(); conns != 1 {
+		t.Fatalf("expected 1 connection, got %d", conns)
+	}
+
+	if version := cli.Version(); version != protocol.Version {
+		t.Fatalf("expected negotiated version %d, got %d", protocol.Version, version)
+	}
+
+	time.Sleep(600 * time.Millisecond)
+
+	if conns := cli.count(); conns != 0 {
+		t.Fatalf("expected idle connection to be reaped, got %d", conns)
+	}
+
+	send(100)
+	received(200)
+
+	for i, target := range targets {
+		if target != int64(i%100) {
+			t.Fatalf("message out of order at %d: %d", i, target)
+		}
+	}
+
+	if cli.closed.Load() {
+		t.Fatal("client closed after reaping idle connections")
+	}
+}
+
+func TestClient_Deadline(t *testing.T) {
+	timeouts := make(chan time.Duration, 2)
+
+	srv, err := server.NewServer(&server.Options{Addr: "127.0.0.1:49895"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.RegisterContextHandler(route.Push, func(ctx context.Context, conn *server.Conn, data []byte) error {
+		if _, _, _, _, err := protocol.DecodePushReq(data); err != nil {
+			return err
+		}
+
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			timeouts <- 0
+		} else {
+			timeouts <- time.Until(deadline)
+		}
+
+		return nil
+	})
+
+	go srv.Start()
+	defer srv.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	cli := NewClient(&Options{Addr: "127.0.0.1:49895", InsID: "test"})
+
+	// 未设置截止时间的请求，处理器上下文无截止时间
+	if err = cli.Send(context.Background(), protocol.EncodePushReq(0, session.User, 1, buffer.NewNocopyBuffer()), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if timeout := <-timeouts; timeout != 0 {
+		t.Fatalf("expected no deadline, got %v", timeout)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err = cli.Send(canceled, protocol.EncodePushReq(0, session.User, 2, buffer.NewNocopyBuffer()), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err = cli.Send(ctx, protocol.EncodePushReq(0, session.User, 3, buffer.NewNocopyBuffer()), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case timeout := <-timeouts:
+		if timeout <= 0 || timeout > time.Second {
+			t.Fatalf("unexpected propagated timeout: %v", timeout)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("request with deadline not received")
+	}
+
+	select {
+	case timeout := <-timeouts:
+		t.Fatalf("canceled request should be skipped, got %v", timeout)
+	default:
+	}
+}
+
+func TestClient_Trace(t *testing.T) {
+	traces := make(chan trace.SpanContext, 2)
+
+	srv, err := server.NewServer(&server.Options{Addr: "127.0.0.1:49894"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.RegisterContextHandler(route.Push, func(ctx context.Context, conn *server.Conn, data []byte) error {
+		if _, _, _, _, err := protocol.DecodePushReq(data); err != nil {
+			return err
+		}
+
+		sc, _ := trace.FromContext(ctx)
+		traces <- sc
+
+		return nil
+	})
+
+	go srv.Start()
+	defer srv.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	cli := NewClient(&Options{Addr: "127.0.0.1:49894", InsID: "test"})
+
+	// 未携带链路的请求，处理器上下文无链路
+	if err = cli.Send(context.Background(), protocol.EncodePushReq(0, session.User, 1, buffer.NewNocopyBuffer()), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if sc := <-traces; sc.IsValid() {
+		t.Fatalf("expected no trace, got %+v", sc)
+	}
+
+	ctx, span := trace.Start(context.Background(), "test")
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	if err = cli.Send(ctx, protocol.EncodePushReq(0, session.User, 2, buffer.NewNocopyBuffer()), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case sc := <-traces:
+		if sc != span.Context() {
+			t.Fatalf("unexpected propagated trace: %+v", sc)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("request with trace not received")
+	}
+}
+
+func TestClient_DialFailed(t *testing.T) {
+	closed := make(chan struct{})
+
+	cli := NewClient(&Options{
+		Addr:         "127.0.0.1:49896",
+		CloseHandler: func() { close(closed) },
+	})
+
+	_ = cli.Send(context.Background(), protocol.EncodePushReq(0, session.User, 1, buffer.NewNocopyBuffer()), 1)
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client not closed after dial failed")
+	}
+
+	if err := cli.Send(context.Background(), protocol.EncodePushReq(0, session.User, 1, buffer.NewNocopyBuffer()), 1); err == nil {
+		t.Fatal("expected send on closed client to fail")
+	}
+}
+
+func TestClient_DialFailedWithFullQueue(t *testing.T) {
+	closed := make(chan struct{})
+
+	cli := NewClient(&Options{
+		Addr:         "127.0.0.1:49897",
+		CloseHandler: func() { close(closed) },
+	})
+
+	// 拨号期间写入队列无人消费，写满后发送方阻塞等待
+	for i := 0; i < 10240; i++ {
+		_ = cli.Send(context.Background(), protocol.EncodePushReq(0, session.User, 1, buffer.NewNocopyBuffer()), 1)
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- cli.Send(context.Background(), protocol.EncodePushReq(0, session.User, 1, buffer.NewNocopyBuffer()), 1)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client not closed after dial failed with a full queue")
+	}
+
+	select {
+	case err := <-sent:
+		if err == nil {
+			t.Fatal("expected blocked send to fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked send not released after dial failed")
+	}
+}
+
+func TestClient_IncompatibleProtocol(t *testing.T) {
+	defer func(min uint16) { protocol.MinVersion = min }(protocol.MinVersion)
+	protocol.MinVersion = protocol.Version + 1
+
+	srv, err := server.NewServer(&server.Options{Addr: "127.0.0.1:49893"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go srv.Start()
+	defer srv.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	closed := make(chan struct{})
+
+	cli := NewClient(&Options{
+		Addr:         "127.0.0.1:49893",
+		InsID:        "test",
+		CloseHandler: func() { close(closed) },
+	})
+
+	// 对端版本低于最低兼容版本时握手被拒绝，连接关闭
+	_ = cli.Send(context.Background(), protocol.EncodePushReq(0, session.User, 1, buffer.NewNocopyBuffer()), 1)
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client not closed after handshake rejected")
+	}
+
+	if version := cli.Version(); version != 0 {
+		t.Fatalf("unexpected negotiated version: %d", version)
+	}
+}
+
+// 统计连接池中的连接数
+func (c *Client) count() int {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+
+	n := len(c.unordered)
+	for _, conn := range c.ordered {
+		if conn != nil {
+			n++
+		}
+	}
+
+	return n
+}
@@ -1,11 +1,14 @@
 package client
 
 import (
+	"crypto/tls"
 	"gatesvr/core/buffer"
 	"gatesvr/errors"
+	"gatesvr/internal/transporter/internal/codes"
 	"gatesvr/internal/transporter/internal/def"
 	"gatesvr/internal/transporter/internal/protocol"
 	"gatesvr/log"
+	"gatesvr/trace"
 	"gatesvr/utils/xtime"
 	"net"
 	"sync/atomic"
@@ -23,15 +26,18 @@ type Conn struct {
 	chWrite           chan *chWrite // 写入队列
 	pending           *pending      // 等待队列
 	done              chan struct{} // 关闭请求
+	closed            chan struct{} // 连接已关闭，不再重连
 	builtin           bool          // 是否内建
 	lastHeartbeatTime int64         // 上次心跳时间
 }
 
+// 新建连接，拨号在后台进行，拨号期间发送的消息暂存于写入队列
 func newConn(cli *Client, ch ...chan *chWrite) *Conn {
 	c := &Conn{}
 	c.cli = cli
-	c.state = def.ConnClosed
+	c.state = def.ConnRetrying
 	c.pending = newPending()
+	c.closed = make(chan struct{})
 
 	if len(ch) > 0 {
 		c.chWrite = ch[0]
@@ -40,20 +46,40 @@ func newConn(cli *Client, ch ...chan *chWrite) *Conn {
 		c.builtin = true
 	}
 
-	c.dial()
+	go c.dial()
 
 	return c
 }
 
-// 发送
-func (c *Conn) send(ch *chWrite) error {
+// 尝试发送，写入队列已满时返回false
+func (c *Conn) trySend(ch *chWrite) (bool, error) {
 	if atomic.LoadInt32(&c.state) == def.ConnClosed {
-		return errors.ErrConnectionClosed
+		return false, errors.ErrConnectionClosed
 	}
 
-	c.chWrite <- ch
+	select {
+	case c.chWrite <- ch:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
 
-	return nil
+// 发送，等待写入队列期间连接关闭或ctx结束时返回错误
+func (c *Conn) send(ch *chWrite) error {
+	var done <-chan struct{}
+	if ch.ctx != nil {
+		done = ch.ctx.Done()
+	}
+
+	select {
+	case c.chWrite <- ch:
+		return nil
+	case <-c.closed:
+		return errors.ErrConnectionClosed
+	case <-done:
+		return ch.ctx.Err()
+	}
 }
 
 // 拨号
@@ -64,7 +90,7 @@ func (c *Conn) dial() {
 	)
 
 	for {
-		conn, err := net.DialTimeout("tcp", c.cli.opts.Addr, dialTimeout)
+		conn, err := c.doDial()
 		if err != nil {
 			retry++
 
@@ -94,6 +120,17 @@ func (c *Conn) dial() {
 	}
 }
 
+// 执行拨号
+func (c *Conn) doDial() (net.Conn, error) {
+	if c.cli.opts.TLSConfig == nil {
+		return net.DialTimeout("tcp", c.cli.opts.Addr, dialTimeout)
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	return tls.DialWithDialer(dialer, "tcp", c.cli.opts.Addr, c.cli.opts.TLSConfig)
+}
+
 // 处理连接
 func (c *Conn) process(conn net.Conn) {
 	atomic.StoreInt32(&c.state, def.ConnOpened)
@@ -118,11 +155,42 @@ func (c *Conn) process(conn net.Conn) {
 		return
 	}
 
-	<-call
+	version, capabilities, err := c.handshake(<-call)
+	if err != nil {
+		log.Warnf("handshake failed, addr: %s err: %v", c.cli.opts.Addr, err)
+
+		// 对端不兼容时重连无意义，直接关闭客户端
+		if c.shutdown(conn) {
+			c.cli.fail(c)
+		}
+
+		return
+	}
+
+	c.cli.negotiate(version, capabilities)
 
 	go c.write(conn)
 }
 
+// 解析握手响应，对端拒绝握手或协商出的版本不兼容时返回错误
+func (c *Conn) handshake(data []byte) (uint16, protocol.Capability, error) {
+	code, version, capabilities, err := protocol.DecodeHandshakeRes(data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err = codes.CodeToError(code); err != nil {
+		return 0, 0, err
+	}
+
+	version, capabilities, ok := protocol.Negotiate(version, capabilities)
+	if !ok {
+		return 0, 0, errors.ErrIncompatibleProtocol
+	}
+
+	return version, capabilities, nil
+}
+
 // 读取数据
 func (c *Conn) read(conn net.Conn) {
 	for {
@@ -157,11 +225,30 @@ func (c *Conn) write(conn net.Conn) {
 	ticker := time.NewTicker(def.HeartbeatInterval)
 	defer ticker.Stop()
 
+	var idle <-chan time.Time
+	if timeout := c.cli.opts.Pool.IdleTimeout; timeout > 0 {
+		idleTicker := time.NewTicker(timeout)
+		defer idleTicker.Stop()
+		idle = idleTicker.C
+	}
+
+	lastWriteTime := xtime.Now()
+
 	for {
 		select {
 		case <-c.done:
 			return
+		case <-idle:
+			if c.idle(lastWriteTime) {
+				c.shutdown(conn)
+				return
+			}
 		case <-ticker.C:
+			if c.cli.closed.Load() {
+				c.shutdown(conn)
+				return
+			}
+
 			deadline := xtime.Now().Add(-2 * def.HeartbeatInterval).Unix()
 			if atomic.LoadInt64(&c.lastHeartbeatTime) < deadline {
 				c.retry(conn)
@@ -178,6 +265,13 @@ func (c *Conn) write(conn net.Conn) {
 				return
 			}
 
+			lastWriteTime = xtime.Now()
+
+			if !c.extend(ch) {
+				ch.buf.Release()
+				continue
+			}
+
 			if ch.seq != 0 {
 				c.pending.store(ch.seq, ch.call)
 			}
@@ -208,12 +302,73 @@ func (c *Conn) retry(conn net.Conn) {
 	c.dial()
 }
 
-// 关闭连接
-func (c *Conn) close() {
-	c.cli.done()
+// 处理请求扩展信息，请求已超时或已取消时返回false
+// 对端支持时为请求附加链路上下文与剩余时间，使对端延续链路并在截止时间内处理请求
+func (c *Conn) extend(ch *chWrite) bool {
+	if ch.ctx == nil {
+		return true
+	}
+
+	if ch.ctx.Err() != nil {
+		return false
+	}
+
+	caps := c.cli.Capabilities()
+
+	if sc, ok := trace.FromContext(ch.ctx); ok && caps.Has(protocol.CapTrace) {
+		protocol.AttachTrace(ch.buf, sc)
+	}
+
+	deadline, ok := ch.ctx.Deadline()
+	if !ok {
+		return true
+	}
+
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return false
+	}
+
+	if caps.Has(protocol.CapDeadline) {
+		protocol.AttachDeadline(ch.buf, timeout)
+	}
 
+	return true
+}
+
+// 检测连接是否空闲，空闲超时的连接从连接池中移除后返回true
+// 由写入协程在两次写入之间调用，保证移除时已无进行中的写入，避免破坏同一目标的消息顺序
+func (c *Conn) idle(lastWriteTime time.Time) bool {
+	if xtime.Now().Sub(lastWriteTime) < c.cli.opts.Pool.IdleTimeout {
+		return false
+	}
+
+	return c.cli.release(c)
+}
+
+// 主动关闭连接，连接已在重连或关闭时返回false
+func (c *Conn) shutdown(conn net.Conn) bool {
+	if !atomic.CompareAndSwapInt32(&c.state, def.ConnOpened, def.ConnClosed) {
+		return false
+	}
+
+	close(c.done)
+
+	close(c.closed)
+
+	_ = conn.Close()
+
+	return true
+}
+
+// 拨号失败关闭连接
+func (c *Conn) close() {
 	atomic.StoreInt32(&c.state, def.ConnClosed)
 
+	close(c.closed)
+
+	c.cli.fail(c)
+
 	if c.builtin {
 		time.AfterFunc(time.Second, func() {
 			close(c.chWrite)
@@ -1,7 +1,14 @@
 package client
 
 import (
+	"crypto/tls"
 	"gatesvr/cluster"
+	"time"
+)
+
+const (
+	defaultOrdered   = 20 // 默认有序消息连接数
+	defaultUnordered = 10 // 默认无序消息连接数
 )
 
 type Options struct {
@@ -9,4 +16,36 @@ type Options struct {
 	InsID        string       // 实例ID
 	InsKind      cluster.Kind // 实例类型
 	CloseHandler func()       // 关闭处理器
+	TLSConfig    *tls.Config  // TLS配置，配置后使用TLS拨号
+	Pool         PoolOptions  // 连接池配置
+}
+
+type PoolOptions struct {
+	Ordered      int           // 有序消息连接数，同一目标的消息始终经由同一连接发送，默认为20
+	Unordered    int           // 无序消息连接数，首次发送无序消息时建立，默认为10
+	MaxUnordered int           // 最大无序消息连接数，写入队列积压时按需扩容，不大于Unordered时不扩容
+	IdleTimeout  time.Duration // 空闲连接回收时间，连接空闲超过该时间后关闭，下次使用时重新建立，为0时不回收
+}
+
+// 有序消息连接数
+func (o *PoolOptions) ordered() int {
+	if o.Ordered > 0 {
+		return o.Ordered
+	}
+
+	return defaultOrdered
+}
+
+// 无序消息连接数
+func (o *PoolOptions) unordered() int {
+	if o.Unordered > 0 {
+		return o.Unordered
+	}
+
+	return defaultUnordered
+}
+
+// 最大无序消息连接数
+func (o *PoolOptions) maxUnordered() int {
+	return max(o.MaxUnordered, o.unordered())
 }
@@ -62,3 +62,22 @@ func (p *partition) delete(seq uint64) {
 	delete(p.calls, seq)
 	p.mu.Unlock()
 }
+
+// 是否为空
+func (p *pending) empty() bool {
+	for _, partition := range p.partitions {
+		if !partition.empty() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 是否为空
+func (p *partition) empty() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.calls) == 0
+}
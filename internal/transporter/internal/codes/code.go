@@ -1,13 +1,18 @@
 package codes
 
 import (
+	"context"
 	"gatesvr/errors"
 )
 
 const (
-	OK              uint16 = iota // 成功
-	NotFoundSession               // 未找到会话连接
-	InternalError                 // 内部错误
+	OK                   uint16 = iota // 成功
+	NotFoundSession                    // 未找到会话连接
+	InternalError                      // 内部错误
+	AlreadyLoggedIn                    // 用户已登录
+	DeadlineExceeded                   // 等待超时
+	OutboundQueueFull                  // 发送队列已满
+	IncompatibleProtocol               // 协议版本不兼容
 )
 
 // ErrorToCode 错误转错误码
@@ -17,6 +22,14 @@ func ErrorToCode(err error) uint16 {
 		return OK
 	case errors.Is(err, errors.ErrNotFoundSession):
 		return NotFoundSession
+	case errors.Is(err, errors.ErrAlreadyLoggedIn):
+		return AlreadyLoggedIn
+	case errors.Is(err, errors.ErrDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return DeadlineExceeded
+	case errors.Is(err, errors.ErrOutboundQueueFull):
+		return OutboundQueueFull
+	case errors.Is(err, errors.ErrIncompatibleProtocol):
+		return IncompatibleProtocol
 	default:
 		return InternalError
 	}
@@ -28,6 +41,14 @@ func CodeToError(code uint16) error {
 		return nil
 	case NotFoundSession:
 		return errors.ErrNotFoundSession
+	case AlreadyLoggedIn:
+		return errors.ErrAlreadyLoggedIn
+	case DeadlineExceeded:
+		return errors.ErrDeadlineExceeded
+	case OutboundQueueFull:
+		return errors.ErrOutboundQueueFull
+	case IncompatibleProtocol:
+		return errors.ErrIncompatibleProtocol
 	default:
 		return errors.ErrUnknownError
 	}
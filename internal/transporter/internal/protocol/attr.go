@@ -0,0 +1,346 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"gatesvr/cluster"
+	"gatesvr/core/buffer"
+	"gatesvr/errors"
+	"gatesvr/internal/transporter/internal/codes"
+	"gatesvr/internal/transporter/internal/route"
+	"gatesvr/session"
+	"io"
+	"math"
+)
+
+const (
+	getAttrReqBytes      = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + b8 + b64 + b8
+	getAttrResBytes      = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + defaultCodeBytes
+	setAttrReqBytes      = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + b8 + b64 + b8 + b16
+	setAttrResBytes      = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + defaultCodeBytes
+	triggerAttrsReqBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + b8 + b64 + b64 + b8
+	triggerAttrsResBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + defaultCodeBytes
+)
+
+// EncodeGetAttrReq 编码获取会话属性请求（属性名最长255字节）
+// 协议：size + header + route + seq + session kind + target + key len + key
+func EncodeGetAttrReq(seq uint64, kind session.Kind, target int64, key string) (buffer.Buffer, error) {
+	if key == "" || len(key) > math.MaxUint8 {
+		return nil, errors.ErrInvalidArgument
+	}
+
+	size := getAttrReqBytes + len(key)
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(size)
+	writer.WriteUint32s(binary.BigEndian, uint32(size-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.GetAttr)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint8s(uint8(kind))
+	writer.WriteInt64s(binary.BigEndian, target)
+	writer.WriteUint8s(uint8(len(key)))
+	writer.WriteString(key)
+
+	return buf, nil
+}
+
+// DecodeGetAttrReq 解码获取会话属性请求
+// 协议：size + header + route + seq + session kind + target + key len + key
+func DecodeGetAttrReq(data []byte) (seq uint64, kind session.Kind, target int64, key string, err error) {
+	reader := buffer.NewReader(data)
+
+	if seq, kind, target, err = readSessionTarget(reader); err != nil {
+		return
+	}
+
+	n, err := reader.ReadUint8()
+	if err != nil {
+		return
+	}
+
+	if len(data) != getAttrReqBytes+int(n) {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	key, err = reader.ReadString(int(n))
+
+	return
+}
+
+// EncodeGetAttrRes 编码获取会话属性响应（属性值最长65535字节）
+// 协议：size + header + route + seq + code + [value]
+func EncodeGetAttrRes(seq uint64, code uint16, value ...string) buffer.Buffer {
+	size := getAttrResBytes
+	if code == codes.OK && len(value) > 0 {
+		size += len(value[0])
+	}
+
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(size)
+	writer.WriteUint32s(binary.BigEndian, uint32(size-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.GetAttr)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint16s(binary.BigEndian, code)
+
+	if code == codes.OK && len(value) > 0 {
+		writer.WriteString(value[0])
+	}
+
+	return buf
+}
+
+// DecodeGetAttrRes 解码获取会话属性响应
+// 协议：size + header + route + seq + code + [value]
+func DecodeGetAttrRes(data []byte) (code uint16, value string, err error) {
+	if len(data) < getAttrResBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes+defaultSeqBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if code, err = reader.ReadUint16(binary.BigEndian); err != nil {
+		return
+	}
+
+	if code == codes.OK && len(data) > getAttrResBytes {
+		value, err = reader.ReadString(len(data) - getAttrResBytes)
+	}
+
+	return
+}
+
+// EncodeSetAttrReq 编码设置会话属性请求（属性名最长255字节，属性值最长65535字节）
+// 协议：size + header + route + seq + session kind + target + key len + value len + key + value
+func EncodeSetAttrReq(seq uint64, kind session.Kind, target int64, key, value string) (buffer.Buffer, error) {
+	if key == "" || len(key) > math.MaxUint8 || len(value) > math.MaxUint16 {
+		return nil, errors.ErrInvalidArgument
+	}
+
+	size := setAttrReqBytes + len(key) + len(value)
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(size)
+	writer.WriteUint32s(binary.BigEndian, uint32(size-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.SetAttr)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint8s(uint8(kind))
+	writer.WriteInt64s(binary.BigEndian, target)
+	writer.WriteUint8s(uint8(len(key)))
+	writer.WriteUint16s(binary.BigEndian, uint16(len(value)))
+	writer.WriteString(key)
+	writer.WriteString(value)
+
+	return buf, nil
+}
+
+// DecodeSetAttrReq 解码设置会话属性请求
+// 协议：size + header + route + seq + session kind + target + key len + value len + key + value
+func DecodeSetAttrReq(data []byte) (seq uint64, kind session.Kind, target int64, key, value string, err error) {
+	reader := buffer.NewReader(data)
+
+	if seq, kind, target, err = readSessionTarget(reader); err != nil {
+		return
+	}
+
+	kn, err := reader.ReadUint8()
+	if err != nil {
+		return
+	}
+
+	vn, err := reader.ReadUint16(binary.BigEndian)
+	if err != nil {
+		return
+	}
+
+	if len(data) != setAttrReqBytes+int(kn)+int(vn) {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	if key, err = reader.ReadString(int(kn)); err != nil {
+		return
+	}
+
+	value, err = reader.ReadString(int(vn))
+
+	return
+}
+
+// EncodeSetAttrRes 编码设置会话属性响应
+// 协议：size + header + route + seq + code
+func EncodeSetAttrRes(seq uint64, code uint16) buffer.Buffer {
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(setAttrResBytes)
+	writer.WriteUint32s(binary.BigEndian, uint32(setAttrResBytes-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.SetAttr)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint16s(binary.BigEndian, code)
+
+	return buf
+}
+
+// DecodeSetAttrRes 解码设置会话属性响应
+// 协议：size + header + route + seq + code
+func DecodeSetAttrRes(data []byte) (code uint16, err error) {
+	if len(data) != setAttrResBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes+defaultSeqBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	code, err = reader.ReadUint16(binary.BigEndian)
+
+	return
+}
+
+// EncodeTriggerAttrsReq 编码携带会话属性的触发事件请求（最多255个属性）
+// 协议：size + header + route + seq + event + cid + uid + count + [key len + value len + key + value]...
+func EncodeTriggerAttrsReq(seq uint64, event cluster.Event, cid, uid int64, attrs map[string]string) (buffer.Buffer, error) {
+	if len(attrs) > math.MaxUint8 {
+		return nil, errors.ErrInvalidArgument
+	}
+
+	size := triggerAttrsReqBytes
+	for key, value := range attrs {
+		if len(key) > math.MaxUint8 || len(value) > math.MaxUint16 {
+			return nil, errors.ErrInvalidArgument
+		}
+		size += b8 + b16 + len(key) + len(value)
+	}
+
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(size)
+	writer.WriteUint32s(binary.BigEndian, uint32(size-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.TriggerAttrs)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint8s(uint8(event))
+	writer.WriteInt64s(binary.BigEndian, cid, uid)
+	writer.WriteUint8s(uint8(len(attrs)))
+
+	for key, value := range attrs {
+		writer.WriteUint8s(uint8(len(key)))
+		writer.WriteUint16s(binary.BigEndian, uint16(len(value)))
+		writer.WriteString(key)
+		writer.WriteString(value)
+	}
+
+	return buf, nil
+}
+
+// DecodeTriggerAttrsReq 解码携带会话属性的触发事件请求
+// 协议：size + header + route + seq + event + cid + uid + count + [key len + value len + key + value]...
+func DecodeTriggerAttrsReq(data []byte) (seq uint64, event cluster.Event, cid, uid int64, attrs map[string]string, err error) {
+	if len(data) < triggerAttrsReqBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if seq, err = reader.ReadUint64(binary.BigEndian); err != nil {
+		return
+	}
+
+	var evt uint8
+	if evt, err = reader.ReadUint8(); err != nil {
+		return
+	} else {
+		event = cluster.Event(evt)
+	}
+
+	if cid, err = reader.ReadInt64(binary.BigEndian); err != nil {
+		return
+	}
+
+	if uid, err = reader.ReadInt64(binary.BigEndian); err != nil {
+		return
+	}
+
+	count, err := reader.ReadUint8()
+	if err != nil {
+		return
+	}
+
+	attrs = make(map[string]string, count)
+
+	for i := 0; i < int(count); i++ {
+		var (
+			kn         uint8
+			vn         uint16
+			key, value string
+		)
+
+		if kn, err = reader.ReadUint8(); err != nil {
+			return
+		}
+
+		if vn, err = reader.ReadUint16(binary.BigEndian); err != nil {
+			return
+		}
+
+		if key, err = reader.ReadString(int(kn)); err != nil {
+			return
+		}
+
+		if value, err = reader.ReadString(int(vn)); err != nil {
+			return
+		}
+
+		attrs[key] = value
+	}
+
+	return
+}
+
+// EncodeTriggerAttrsRes 编码携带会话属性的触发事件响应
+// 协议：size + header + route + seq + code
+func EncodeTriggerAttrsRes(seq uint64, code uint16) buffer.Buffer {
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(triggerAttrsResBytes)
+	writer.WriteUint32s(binary.BigEndian, uint32(triggerAttrsResBytes-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.TriggerAttrs)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint16s(binary.BigEndian, code)
+
+	return buf
+}
+
+// 读取序列号及会话目标
+func readSessionTarget(reader *buffer.Reader) (seq uint64, kind session.Kind, target int64, err error) {
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if seq, err = reader.ReadUint64(binary.BigEndian); err != nil {
+		return
+	}
+
+	var k uint8
+	if k, err = reader.ReadUint8(); err != nil {
+		return
+	} else {
+		kind = session.Kind(k)
+	}
+
+	target, err = reader.ReadInt64(binary.BigEndian)
+
+	return
+}
@@ -0,0 +1,28 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"gatesvr/core/buffer"
+	"time"
+)
+
+// AttachDeadline 为请求帧附加请求剩余时间，服务端据此设置处理请求的截止时间
+// 仅可在对端支持CapDeadline时调用，需在其他扩展信息之后附加
+// 协议：<request> + timeout
+func AttachDeadline(buf buffer.Buffer, timeout time.Duration) {
+	if writer := attach(buf, deadlineBit, b64); writer != nil {
+		writer.WriteInt64s(binary.BigEndian, int64(timeout))
+	}
+}
+
+// ExtractDeadline 提取请求帧附加的请求剩余时间，返回移除剩余时间后的请求帧
+// 请求帧未携带剩余时间时原样返回
+// 协议：<request> + timeout
+func ExtractDeadline(data []byte) ([]byte, time.Duration, bool) {
+	data, extension, ok := extract(data, deadlineBit, b64)
+	if !ok {
+		return data, 0, false
+	}
+
+	return data, time.Duration(binary.BigEndian.Uint64(extension)), true
+}
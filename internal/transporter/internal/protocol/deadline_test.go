@@ -0,0 +1,39 @@
+package protocol_test
+
+import (
+	"gatesvr/core/buffer"
+	"gatesvr/internal/transporter/internal/protocol"
+	"gatesvr/session"
+	"testing"
+	"time"
+)
+
+func TestExtractDeadline(t *testing.T) {
+	buf := protocol.EncodePushReq(1, session.User, 3, buffer.NewNocopyBuffer([]byte("hello")))
+	origin := buf.Bytes()
+
+	buf = protocol.EncodePushReq(1, session.User, 3, buffer.NewNocopyBuffer([]byte("hello")))
+	protocol.AttachDeadline(buf, time.Second)
+
+	data, timeout, ok := protocol.ExtractDeadline(buf.Bytes())
+	if !ok || timeout != time.Second {
+		t.Fatalf("unexpected deadline: %v %v", ok, timeout)
+	}
+
+	if string(data) != string(origin) {
+		t.Fatalf("unexpected request frame: %v", data)
+	}
+
+	seq, kind, target, message, err := protocol.DecodePushReq(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seq != 1 || kind != session.User || target != 3 || string(message) != "hello" {
+		t.Fatalf("unexpected push request: %v %v %v %s", seq, kind, target, message)
+	}
+
+	if _, _, ok = protocol.ExtractDeadline(origin); ok {
+		t.Fatal("request frame without deadline should not carry one")
+	}
+}
@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"gatesvr/core/buffer"
+	"gatesvr/errors"
+	"gatesvr/internal/transporter/internal/codes"
+	"gatesvr/internal/transporter/internal/route"
+	"io"
+	"math"
+)
+
+const (
+	pushDeviceReqBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + b64 + b8
+	pushDeviceResBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + defaultCodeBytes + b64
+)
+
+// EncodePushDeviceReq 编码推送用户指定设备消息请求（设备标签最长255字节）
+// 协议：size + header + route + seq + uid + device len + device + <message packet>
+func EncodePushDeviceReq(seq uint64, uid int64, device string, message buffer.Buffer) (buffer.Buffer, error) {
+	if len(device) > math.MaxUint8 {
+		return nil, errors.ErrInvalidArgument
+	}
+
+	size := pushDeviceReqBytes + len(device)
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(size)
+	writer.WriteUint32s(binary.BigEndian, uint32(size-defaultSizeBytes+message.Len()))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.PushDevice)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteInt64s(binary.BigEndian, uid)
+	writer.WriteUint8s(uint8(len(device)))
+	writer.WriteString(device)
+	buf.Mount(message)
+
+	return buf, nil
+}
+
+// DecodePushDeviceReq 解码推送用户指定设备消息请求
+// 协议：size + header + route + seq + uid + device len + device + <message packet>
+func DecodePushDeviceReq(data []byte) (seq uint64, uid int64, device string, message []byte, err error) {
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if seq, err = reader.ReadUint64(binary.BigEndian); err != nil {
+		return
+	}
+
+	if uid, err = reader.ReadInt64(binary.BigEndian); err != nil {
+		return
+	}
+
+	n, err := reader.ReadUint8()
+	if err != nil {
+		return
+	}
+
+	if device, err = reader.ReadString(int(n)); err != nil {
+		return
+	}
+
+	message = data[pushDeviceReqBytes+int(n):]
+
+	return
+}
+
+// EncodePushDeviceRes 编码推送用户指定设备消息响应
+// 协议：size + header + route + seq + code + [total]
+func EncodePushDeviceRes(seq uint64, code uint16, total ...uint64) buffer.Buffer {
+	size := pushDeviceResBytes - defaultSizeBytes
+	if code != codes.OK || len(total) == 0 || total[0] == 0 {
+		size -= b64
+	}
+
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(pushDeviceResBytes)
+	writer.WriteUint32s(binary.BigEndian, uint32(size))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.PushDevice)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint16s(binary.BigEndian, code)
+
+	if code == codes.OK && len(total) > 0 && total[0] != 0 {
+		writer.WriteUint64s(binary.BigEndian, total[0])
+	}
+
+	return buf
+}
+
+// DecodePushDeviceRes 解码推送用户指定设备消息响应
+// 协议：size + header + route + seq + code + [total]
+func DecodePushDeviceRes(data []byte) (code uint16, total uint64, err error) {
+	if len(data) != pushDeviceResBytes && len(data) != pushDeviceResBytes-b64 {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes+defaultSeqBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if code, err = reader.ReadUint16(binary.BigEndian); err != nil {
+		return
+	}
+
+	if code == codes.OK && len(data) == pushDeviceResBytes {
+		total, err = reader.ReadUint64(binary.BigEndian)
+	}
+
+	return
+}
@@ -0,0 +1,46 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"gatesvr/core/buffer"
+)
+
+const (
+	deadlineBit uint8 = 1 << 6 // 截止时间标识位，标识数据帧尾部携带请求剩余时间
+	traceBit    uint8 = 1 << 5 // 链路追踪标识位，标识数据帧尾部携带链路上下文
+)
+
+// 为请求帧附加扩展信息，设置帧头中的扩展标识位并增加帧长度，返回扩展信息的写入器
+// 扩展信息依次追加在帧尾，解析时需按附加的相反顺序提取
+func attach(buf buffer.Buffer, bit uint8, size int) *buffer.Writer {
+	var head []byte
+	buf.Range(func(node *buffer.NocopyNode) bool {
+		head = node.Bytes()
+		return false
+	})
+
+	if len(head) < defaultSizeBytes+defaultHeaderBytes {
+		return nil
+	}
+
+	binary.BigEndian.PutUint32(head, binary.BigEndian.Uint32(head)+uint32(size))
+	head[defaultSizeBytes] |= bit
+
+	return buf.Malloc(size)
+}
+
+// 提取请求帧尾部的扩展信息，返回移除扩展信息后的请求帧
+func extract(data []byte, bit uint8, size int) ([]byte, []byte, bool) {
+	if len(data) < defaultSizeBytes+defaultHeaderBytes+size || data[defaultSizeBytes]&bit == 0 {
+		return data, nil, false
+	}
+
+	n := len(data) - size
+	extension := data[n:]
+
+	data = data[:n]
+	binary.BigEndian.PutUint32(data, uint32(n-defaultSizeBytes))
+	data[defaultSizeBytes] &^= bit
+
+	return data, extension, true
+}
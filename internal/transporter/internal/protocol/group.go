@@ -0,0 +1,258 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"gatesvr/core/buffer"
+	"gatesvr/errors"
+	"gatesvr/internal/transporter/internal/codes"
+	"gatesvr/internal/transporter/internal/route"
+	"gatesvr/session"
+	"io"
+	"math"
+)
+
+const (
+	groupReqBytes     = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + b8 + b64 + b8
+	groupResBytes     = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + defaultCodeBytes
+	pushGroupReqBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + b8
+	pushGroupResBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + defaultCodeBytes + b64
+)
+
+// EncodeJoinGroupReq 编码加入分组请求
+// 协议：size + header + route + seq + session kind + target + group len + group
+func EncodeJoinGroupReq(seq uint64, kind session.Kind, target int64, group string) (buffer.Buffer, error) {
+	return encodeGroupReq(route.JoinGroup, seq, kind, target, group)
+}
+
+// DecodeJoinGroupReq 解码加入分组请求
+// 协议：size + header + route + seq + session kind + target + group len + group
+func DecodeJoinGroupReq(data []byte) (seq uint64, kind session.Kind, target int64, group string, err error) {
+	return decodeGroupReq(data)
+}
+
+// EncodeJoinGroupRes 编码加入分组响应
+// 协议：size + header + route + seq + code
+func EncodeJoinGroupRes(seq uint64, code uint16) buffer.Buffer {
+	return encodeGroupRes(route.JoinGroup, seq, code)
+}
+
+// DecodeJoinGroupRes 解码加入分组响应
+// 协议：size + header + route + seq + code
+func DecodeJoinGroupRes(data []byte) (code uint16, err error) {
+	return decodeGroupRes(data)
+}
+
+// EncodeLeaveGroupReq 编码退出分组请求
+// 协议：size + header + route + seq + session kind + target + group len + group
+func EncodeLeaveGroupReq(seq uint64, kind session.Kind, target int64, group string) (buffer.Buffer, error) {
+	return encodeGroupReq(route.LeaveGroup, seq, kind, target, group)
+}
+
+// DecodeLeaveGroupReq 解码退出分组请求
+// 协议：size + header + route + seq + session kind + target + group len + group
+func DecodeLeaveGroupReq(data []byte) (seq uint64, kind session.Kind, target int64, group string, err error) {
+	return decodeGroupReq(data)
+}
+
+// EncodeLeaveGroupRes 编码退出分组响应
+// 协议：size + header + route + seq + code
+func EncodeLeaveGroupRes(seq uint64, code uint16) buffer.Buffer {
+	return encodeGroupRes(route.LeaveGroup, seq, code)
+}
+
+// DecodeLeaveGroupRes 解码退出分组响应
+// 协议：size + header + route + seq + code
+func DecodeLeaveGroupRes(data []byte) (code uint16, err error) {
+	return decodeGroupRes(data)
+}
+
+// EncodePushGroupReq 编码推送分组消息请求（分组名称最长255字节）
+// 协议：size + header + route + seq + group len + group + <message packet>
+func EncodePushGroupReq(seq uint64, group string, message buffer.Buffer) (buffer.Buffer, error) {
+	if len(group) > math.MaxUint8 {
+		return nil, errors.ErrInvalidArgument
+	}
+
+	size := pushGroupReqBytes + len(group)
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(size)
+	writer.WriteUint32s(binary.BigEndian, uint32(size-defaultSizeBytes+message.Len()))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.PushGroup)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint8s(uint8(len(group)))
+	writer.WriteString(group)
+	buf.Mount(message)
+
+	return buf, nil
+}
+
+// DecodePushGroupReq 解码推送分组消息请求
+// 协议：size + header + route + seq + group len + group + <message packet>
+func DecodePushGroupReq(data []byte) (seq uint64, group string, message []byte, err error) {
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if seq, err = reader.ReadUint64(binary.BigEndian); err != nil {
+		return
+	}
+
+	n, err := reader.ReadUint8()
+	if err != nil {
+		return
+	}
+
+	if group, err = reader.ReadString(int(n)); err != nil {
+		return
+	}
+
+	message = data[pushGroupReqBytes+int(n):]
+
+	return
+}
+
+// EncodePushGroupRes 编码推送分组消息响应
+// 协议：size + header + route + seq + code + [total]
+func EncodePushGroupRes(seq uint64, code uint16, total ...uint64) buffer.Buffer {
+	size := pushGroupResBytes - defaultSizeBytes
+	if code != codes.OK || len(total) == 0 || total[0] == 0 {
+		size -= b64
+	}
+
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(pushGroupResBytes)
+	writer.WriteUint32s(binary.BigEndian, uint32(size))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.PushGroup)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint16s(binary.BigEndian, code)
+
+	if code == codes.OK && len(total) > 0 && total[0] != 0 {
+		writer.WriteUint64s(binary.BigEndian, total[0])
+	}
+
+	return buf
+}
+
+// DecodePushGroupRes 解码推送分组消息响应
+// 协议：size + header + route + seq + code + [total]
+func DecodePushGroupRes(data []byte) (code uint16, total uint64, err error) {
+	if len(data) != pushGroupResBytes && len(data) != pushGroupResBytes-b64 {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes+defaultSeqBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if code, err = reader.ReadUint16(binary.BigEndian); err != nil {
+		return
+	}
+
+	if code == codes.OK && len(data) == pushGroupResBytes {
+		total, err = reader.ReadUint64(binary.BigEndian)
+	}
+
+	return
+}
+
+// 编码分组请求
+func encodeGroupReq(r uint8, seq uint64, kind session.Kind, target int64, group string) (buffer.Buffer, error) {
+	if group == "" || len(group) > math.MaxUint8 {
+		return nil, errors.ErrInvalidArgument
+	}
+
+	size := groupReqBytes + len(group)
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(size)
+	writer.WriteUint32s(binary.BigEndian, uint32(size-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(r)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint8s(uint8(kind))
+	writer.WriteInt64s(binary.BigEndian, target)
+	writer.WriteUint8s(uint8(len(group)))
+	writer.WriteString(group)
+
+	return buf, nil
+}
+
+// 解码分组请求
+func decodeGroupReq(data []byte) (seq uint64, kind session.Kind, target int64, group string, err error) {
+	if len(data) < groupReqBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if seq, err = reader.ReadUint64(binary.BigEndian); err != nil {
+		return
+	}
+
+	var k uint8
+	if k, err = reader.ReadUint8(); err != nil {
+		return
+	} else {
+		kind = session.Kind(k)
+	}
+
+	if target, err = reader.ReadInt64(binary.BigEndian); err != nil {
+		return
+	}
+
+	n, err := reader.ReadUint8()
+	if err != nil {
+		return
+	}
+
+	if len(data) != groupReqBytes+int(n) {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	group, err = reader.ReadString(int(n))
+
+	return
+}
+
+// 编码分组响应
+func encodeGroupRes(r uint8, seq uint64, code uint16) buffer.Buffer {
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(groupResBytes)
+	writer.WriteUint32s(binary.BigEndian, uint32(groupResBytes-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(r)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint16s(binary.BigEndian, code)
+
+	return buf
+}
+
+// 解码分组响应
+func decodeGroupRes(data []byte) (code uint16, err error) {
+	if len(data) != groupResBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes+defaultSeqBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	code, err = reader.ReadUint16(binary.BigEndian)
+
+	return
+}
@@ -0,0 +1,195 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"gatesvr/cluster"
+	"gatesvr/core/buffer"
+	"gatesvr/errors"
+	"gatesvr/internal/transporter/internal/route"
+	"io"
+)
+
+const (
+	Version       uint16 = 1 // 当前协议版本
+	LegacyVersion uint16 = 0 // 握手不携带版本信息的旧版本，始终兼容
+)
+
+// MinVersion 携带版本信息的对端需满足的最低协议版本，协商出的版本低于该版本时拒绝握手
+var MinVersion uint16 = 1
+
+// Capability 协议能力位图，握手时双方取交集
+type Capability uint32
+
+const (
+	CapCompress Capability = 1 << iota // 消息压缩
+	CapTrace                           // 链路追踪头
+	CapBatch                           // 批量帧
+	CapDeadline                        // 请求截止时间
+)
+
+// Capabilities 当前实现支持的协议能力
+const Capabilities = CapTrace | CapDeadline
+
+// 版本化握手请求标识，旧版本握手请求此位置为实例类型，不会取到该值
+const handshakeMagic uint8 = 0xff
+
+const (
+	handshakeReqBytes       = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + b8 + b16 + b32 + b8
+	handshakeResBytes       = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + defaultCodeBytes + b16 + b32
+	legacyHandshakeReqBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + b8
+	legacyHandshakeResBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + defaultCodeBytes
+)
+
+// Has 检测是否包含指定能力
+func (c Capability) Has(capability Capability) bool {
+	return c&capability == capability
+}
+
+// Negotiate 协商协议版本与能力
+// 取双方版本中的较低者及能力交集，旧版本对端始终兼容，其他对端协商出的版本低于MinVersion时不兼容
+func Negotiate(version uint16, capabilities Capability) (uint16, Capability, bool) {
+	version = min(version, Version)
+
+	return version, capabilities & Capabilities, version == LegacyVersion || version >= MinVersion
+}
+
+// EncodeHandshakeReq 编码握手请求
+// 协议：size + header + route + seq + magic + version + capabilities + ins kind + ins id
+func EncodeHandshakeReq(seq uint64, insKind cluster.Kind, insID string) buffer.Buffer {
+	size := handshakeReqBytes + len(insID)
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(size)
+	writer.WriteUint32s(binary.BigEndian, uint32(size-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.Handshake)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint8s(handshakeMagic)
+	writer.WriteUint16s(binary.BigEndian, Version)
+	writer.WriteUint32s(binary.BigEndian, uint32(Capabilities))
+	writer.WriteUint8s(uint8(insKind))
+	writer.WriteString(insID)
+
+	return buf
+}
+
+// DecodeHandshakeReq 解码握手请求
+// 协议：size + header + route + seq + magic + version + capabilities + ins kind + ins id
+// 旧版本的握手请求不携带版本信息，协议：size + header + route + seq + ins kind + ins id，解码时返回版本0及空能力
+func DecodeHandshakeReq(data []byte) (seq uint64, version uint16, capabilities Capability, insKind cluster.Kind, insID string, err error) {
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if seq, err = reader.ReadUint64(binary.BigEndian); err != nil {
+		return
+	}
+
+	var magic uint8
+	if magic, err = reader.ReadUint8(); err != nil {
+		return
+	}
+
+	// 旧版本握手请求此位置为实例类型
+	if magic != handshakeMagic {
+		insKind = cluster.Kind(magic)
+		insID, err = reader.ReadString(len(data) - legacyHandshakeReqBytes)
+		return
+	}
+
+	if len(data) < handshakeReqBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	if version, err = reader.ReadUint16(binary.BigEndian); err != nil {
+		return
+	}
+
+	var c uint32
+	if c, err = reader.ReadUint32(binary.BigEndian); err != nil {
+		return
+	} else {
+		capabilities = Capability(c)
+	}
+
+	var k uint8
+	if k, err = reader.ReadUint8(); err != nil {
+		return
+	} else {
+		insKind = cluster.Kind(k)
+	}
+
+	if insID, err = reader.ReadString(len(data) - handshakeReqBytes); err != nil {
+		return
+	}
+
+	return
+}
+
+// EncodeHandshakeRes 编码握手响应
+// 协议：size + header + route + seq + code + version + capabilities
+// 版本为0时按旧版本协议编码，协议：size + header + route + seq + code
+func EncodeHandshakeRes(seq uint64, code uint16, version uint16, capabilities Capability) buffer.Buffer {
+	if version == LegacyVersion {
+		buf := buffer.NewNocopyBuffer()
+		writer := buf.Malloc(legacyHandshakeResBytes)
+		writer.WriteUint32s(binary.BigEndian, uint32(legacyHandshakeResBytes-defaultSizeBytes))
+		writer.WriteUint8s(dataBit)
+		writer.WriteUint8s(route.Handshake)
+		writer.WriteUint64s(binary.BigEndian, seq)
+		writer.WriteUint16s(binary.BigEndian, code)
+
+		return buf
+	}
+
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(handshakeResBytes)
+	writer.WriteUint32s(binary.BigEndian, uint32(handshakeResBytes-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.Handshake)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint16s(binary.BigEndian, code)
+	writer.WriteUint16s(binary.BigEndian, version)
+	writer.WriteUint32s(binary.BigEndian, uint32(capabilities))
+
+	return buf
+}
+
+// DecodeHandshakeRes 解码握手响应
+// 协议：size + header + route + seq + code + version + capabilities
+// 旧版本的握手响应不携带版本信息，协议：size + header + route + seq + code，解码时返回版本0及空能力
+func DecodeHandshakeRes(data []byte) (code uint16, version uint16, capabilities Capability, err error) {
+	if len(data) != handshakeResBytes && len(data) != legacyHandshakeResBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes+defaultSeqBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if code, err = reader.ReadUint16(binary.BigEndian); err != nil {
+		return
+	}
+
+	if len(data) == legacyHandshakeResBytes {
+		return
+	}
+
+	if version, err = reader.ReadUint16(binary.BigEndian); err != nil {
+		return
+	}
+
+	var c uint32
+	if c, err = reader.ReadUint32(binary.BigEndian); err != nil {
+		return
+	} else {
+		capabilities = Capability(c)
+	}
+
+	return
+}
@@ -0,0 +1,118 @@
+package protocol_test
+
+import (
+	"gatesvr/cluster"
+	"gatesvr/internal/transporter/internal/codes"
+	"gatesvr/internal/transporter/internal/protocol"
+	"gatesvr/utils/xuuid"
+	"testing"
+)
+
+func TestEncodeHandshakeReq(t *testing.T) {
+	buffer := protocol.EncodeHandshakeReq(1, cluster.Gate, xuuid.UUID())
+
+	t.Log(buffer.Bytes())
+}
+
+func TestDecodeHandshakeReq(t *testing.T) {
+	insID := xuuid.UUID()
+	buffer := protocol.EncodeHandshakeReq(1, cluster.Gate, insID)
+
+	seq, version, capabilities, insKind, id, err := protocol.DecodeHandshakeReq(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seq != 1 || version != protocol.Version || capabilities != protocol.Capabilities || insKind != cluster.Gate || id != insID {
+		t.Fatalf("unexpected handshake request: %v %v %v %v %v", seq, version, capabilities, insKind, id)
+	}
+}
+
+func TestDecodeHandshakeReq_Legacy(t *testing.T) {
+	// 旧版本握手请求：size + header + route + seq + ins kind + ins id
+	data := []byte{0, 0, 0, 15, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, byte(cluster.Node), 'n', 'o', 'd', 'e'}
+
+	seq, version, capabilities, insKind, insID, err := protocol.DecodeHandshakeReq(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seq != 1 || version != 0 || capabilities != 0 || insKind != cluster.Node || insID != "node" {
+		t.Fatalf("unexpected legacy handshake request: %v %v %v %v %v", seq, version, capabilities, insKind, insID)
+	}
+
+	if _, capabilities, ok := protocol.Negotiate(version, capabilities); !ok || capabilities != 0 {
+		t.Fatal("legacy handshake should be compatible without capabilities")
+	}
+}
+
+func TestDecodeHandshakeReq_Truncated(t *testing.T) {
+	// 携带版本标识但长度不足的握手请求
+	data := []byte{0, 0, 0, 12, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0xff, 0, 1}
+
+	if _, _, _, _, _, err := protocol.DecodeHandshakeReq(data); err == nil {
+		t.Fatal("truncated handshake request should be rejected")
+	}
+}
+
+func TestEncodeHandshakeRes(t *testing.T) {
+	buffer := protocol.EncodeHandshakeRes(1, codes.OK, protocol.Version, protocol.Capabilities)
+
+	t.Log(buffer.Bytes())
+}
+
+func TestDecodeHandshakeRes(t *testing.T) {
+	buffer := protocol.EncodeHandshakeRes(1, codes.OK, protocol.Version, protocol.CapTrace|protocol.CapBatch)
+
+	code, version, capabilities, err := protocol.DecodeHandshakeRes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code != codes.OK || version != protocol.Version || !capabilities.Has(protocol.CapTrace|protocol.CapBatch) || capabilities.Has(protocol.CapCompress) {
+		t.Fatalf("unexpected handshake response: %v %v %v", code, version, capabilities)
+	}
+}
+
+func TestDecodeHandshakeRes_Legacy(t *testing.T) {
+	buffer := protocol.EncodeHandshakeRes(1, codes.OK, 0, protocol.Capabilities)
+
+	code, version, capabilities, err := protocol.DecodeHandshakeRes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code != codes.OK || version != 0 || capabilities != 0 {
+		t.Fatalf("unexpected legacy handshake response: %v %v %v", code, version, capabilities)
+	}
+
+	if _, _, _, err = protocol.DecodeHandshakeRes(buffer.Bytes()[:len(buffer.Bytes())-1]); err == nil {
+		t.Fatal("truncated handshake response should be rejected")
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	version, capabilities, ok := protocol.Negotiate(protocol.Version+1, protocol.CapCompress|protocol.CapTrace|protocol.CapBatch|protocol.CapDeadline)
+	if !ok {
+		t.Fatal("newer peer should be compatible")
+	}
+
+	if version != protocol.Version || capabilities != protocol.Capabilities {
+		t.Fatalf("unexpected negotiation: %v %v", version, capabilities)
+	}
+
+	if version, capabilities, ok = protocol.Negotiate(protocol.LegacyVersion, 0); !ok || version != protocol.LegacyVersion || capabilities != 0 {
+		t.Fatal("legacy peer should be compatible without capabilities")
+	}
+
+	defer func(min uint16) { protocol.MinVersion = min }(protocol.MinVersion)
+	protocol.MinVersion = protocol.Version + 1
+
+	if _, _, ok = protocol.Negotiate(protocol.Version, protocol.Capabilities); ok {
+		t.Fatal("peer below the min version should be rejected")
+	}
+
+	if _, _, ok = protocol.Negotiate(protocol.LegacyVersion, 0); !ok {
+		t.Fatal("legacy peer should stay compatible")
+	}
+}
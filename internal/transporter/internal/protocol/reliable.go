@@ -0,0 +1,89 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"gatesvr/core/buffer"
+	"gatesvr/errors"
+	"gatesvr/internal/transporter/internal/route"
+	"io"
+)
+
+const (
+	pushReliableReqBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + b64
+	pushReliableResBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + defaultCodeBytes
+)
+
+// EncodePushReliableReq 编码可靠推送请求
+// 协议：size + header + route + seq + uid + <message packet>
+func EncodePushReliableReq(seq uint64, uid int64, message buffer.Buffer) buffer.Buffer {
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(pushReliableReqBytes)
+	writer.WriteUint32s(binary.BigEndian, uint32(pushReliableReqBytes-defaultSizeBytes+message.Len()))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.PushReliable)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteInt64s(binary.BigEndian, uid)
+	buf.Mount(message)
+
+	return buf
+}
+
+// DecodePushReliableReq 解码可靠推送请求
+// 协议：size + header + route + seq + uid + <message packet>
+func DecodePushReliableReq(data []byte) (seq uint64, uid int64, message []byte, err error) {
+	if len(data) < pushReliableReqBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if seq, err = reader.ReadUint64(binary.BigEndian); err != nil {
+		return
+	}
+
+	if uid, err = reader.ReadInt64(binary.BigEndian); err != nil {
+		return
+	}
+
+	message = data[pushReliableReqBytes:]
+
+	return
+}
+
+// EncodePushReliableRes 编码可靠推送响应，客户端确认或投递失败后响应
+// 协议：size + header + route + seq + code
+func EncodePushReliableRes(seq uint64, code uint16) buffer.Buffer {
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(pushReliableResBytes)
+	writer.WriteUint32s(binary.BigEndian, uint32(pushReliableResBytes-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.PushReliable)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint16s(binary.BigEndian, code)
+
+	return buf
+}
+
+// DecodePushReliableRes 解码可靠推送响应
+// 协议：size + header + route + seq + code
+func DecodePushReliableRes(data []byte) (code uint16, err error) {
+	if len(data) != pushReliableResBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes+defaultSeqBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	code, err = reader.ReadUint16(binary.BigEndian)
+
+	return
+}
@@ -0,0 +1,133 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"gatesvr/core/buffer"
+	"gatesvr/errors"
+	"gatesvr/internal/transporter/internal/route"
+	"io"
+)
+
+const (
+	resumeReqBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + b64 + b64
+	resumeResBytes = defaultSizeBytes + defaultHeaderBytes + defaultRouteBytes + defaultSeqBytes + defaultCodeBytes + b32
+)
+
+// EncodeResumeReq 编码恢复请求
+// 协议：size + header + route + seq + uid + cid
+func EncodeResumeReq(seq uint64, uid, cid int64) buffer.Buffer {
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(resumeReqBytes)
+	writer.WriteUint32s(binary.BigEndian, uint32(resumeReqBytes-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.Resume)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteInt64s(binary.BigEndian, uid, cid)
+
+	return buf
+}
+
+// DecodeResumeReq 解码恢复请求
+// 协议：size + header + route + seq + uid + cid
+func DecodeResumeReq(data []byte) (seq uint64, uid, cid int64, err error) {
+	if len(data) != resumeReqBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if seq, err = reader.ReadUint64(binary.BigEndian); err != nil {
+		return
+	}
+
+	if uid, err = reader.ReadInt64(binary.BigEndian); err != nil {
+		return
+	}
+
+	if cid, err = reader.ReadInt64(binary.BigEndian); err != nil {
+		return
+	}
+
+	return
+}
+
+// EncodeResumeRes 编码恢复响应
+// 协议：size + header + route + seq + code + count + [message len + message]...
+func EncodeResumeRes(seq uint64, code uint16, messages [][]byte) buffer.Buffer {
+	size := resumeResBytes
+	for _, message := range messages {
+		size += b32 + len(message)
+	}
+
+	buf := buffer.NewNocopyBuffer()
+	writer := buf.Malloc(size)
+	writer.WriteUint32s(binary.BigEndian, uint32(size-defaultSizeBytes))
+	writer.WriteUint8s(dataBit)
+	writer.WriteUint8s(route.Resume)
+	writer.WriteUint64s(binary.BigEndian, seq)
+	writer.WriteUint16s(binary.BigEndian, code)
+	writer.WriteUint32s(binary.BigEndian, uint32(len(messages)))
+
+	for _, message := range messages {
+		writer.WriteUint32s(binary.BigEndian, uint32(len(message)))
+		writer.WriteBytes(message...)
+	}
+
+	return buf
+}
+
+// DecodeResumeRes 解码恢复响应
+// 协议：size + header + route + seq + code + count + [message len + message]...
+func DecodeResumeRes(data []byte) (code uint16, messages [][]byte, err error) {
+	if len(data) < resumeResBytes {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	reader := buffer.NewReader(data)
+
+	if _, err = reader.Seek(defaultSizeBytes+defaultHeaderBytes+defaultRouteBytes+defaultSeqBytes, io.SeekStart); err != nil {
+		return
+	}
+
+	if code, err = reader.ReadUint16(binary.BigEndian); err != nil {
+		return
+	}
+
+	count, err := reader.ReadUint32(binary.BigEndian)
+	if err != nil {
+		return
+	}
+
+	// 每条消息至少占用长度字段，据此校验数量，避免按伪造的数量分配内存
+	if int(count) > (len(data)-resumeResBytes)/b32 {
+		err = errors.ErrInvalidMessage
+		return
+	}
+
+	messages = make([][]byte, 0, count)
+
+	for i := 0; i < int(count); i++ {
+		var (
+			n       uint32
+			message []byte
+		)
+
+		if n, err = reader.ReadUint32(binary.BigEndian); err != nil {
+			return
+		}
+
+		if message, err = reader.ReadUint8s(int(n)); err != nil {
+			return
+		}
+
+		messages = append(messages, message)
+	}
+
+	return
+}
@@ -0,0 +1,25 @@
+package protocol_test
+
+import (
+	"gatesvr/internal/transporter/internal/protocol"
+	"testing"
+)
+
+func TestDecodeResumeRes(t *testing.T) {
+	buf := protocol.EncodeResumeRes(1, 2, [][]byte{[]byte("hello"), {}, []byte("world")})
+
+	code, messages, err := protocol.DecodeResumeRes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code != 2 || len(messages) != 3 || string(messages[0]) != "hello" || len(messages[1]) != 0 || string(messages[2]) != "world" {
+		t.Fatalf("unexpected response: %d %q", code, messages)
+	}
+
+	// 截断的消息
+	data := buf.Bytes()
+	if _, _, err = protocol.DecodeResumeRes(data[:len(data)-1]); err == nil {
+		t.Fatal("truncated response was accepted")
+	}
+}
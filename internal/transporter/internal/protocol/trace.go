@@ -0,0 +1,40 @@
+package protocol
+
+import (
+	"gatesvr/core/buffer"
+	"gatesvr/trace"
+)
+
+const traceBytes = 16 + 8 + b8 // 链路ID + 跨度ID + 采样标识
+
+// AttachTrace 为请求帧附加链路上下文，服务端据此延续链路
+// 仅可在对端支持CapTrace时调用
+// 协议：<request> + trace id + span id + sampled
+func AttachTrace(buf buffer.Buffer, sc trace.SpanContext) {
+	writer := attach(buf, traceBit, traceBytes)
+	if writer == nil {
+		return
+	}
+
+	writer.WriteBytes(sc.TraceID[:]...)
+	writer.WriteBytes(sc.SpanID[:]...)
+	writer.WriteBools(sc.Sampled)
+}
+
+// ExtractTrace 提取请求帧附加的链路上下文，返回移除链路上下文后的请求帧
+// 请求帧未携带链路上下文时原样返回
+// 协议：<request> + trace id + span id + sampled
+func ExtractTrace(data []byte) ([]byte, trace.SpanContext, bool) {
+	var sc trace.SpanContext
+
+	data, extension, ok := extract(data, traceBit, traceBytes)
+	if !ok {
+		return data, sc, false
+	}
+
+	copy(sc.TraceID[:], extension[:16])
+	copy(sc.SpanID[:], extension[16:24])
+	sc.Sampled = extension[24] != 0
+
+	return data, sc, sc.IsValid()
+}
@@ -0,0 +1,42 @@
+package protocol_test
+
+import (
+	"gatesvr/core/buffer"
+	"gatesvr/internal/transporter/internal/protocol"
+	"gatesvr/session"
+	"gatesvr/trace"
+	"testing"
+	"time"
+)
+
+func TestExtractTrace(t *testing.T) {
+	sc, err := trace.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := protocol.EncodePushReq(1, session.User, 3, buffer.NewNocopyBuffer([]byte("hello")))
+	origin := buf.Bytes()
+
+	buf = protocol.EncodePushReq(1, session.User, 3, buffer.NewNocopyBuffer([]byte("hello")))
+	protocol.AttachTrace(buf, sc)
+	protocol.AttachDeadline(buf, time.Second)
+
+	data, timeout, ok := protocol.ExtractDeadline(buf.Bytes())
+	if !ok || timeout != time.Second {
+		t.Fatalf("unexpected deadline: %v %v", ok, timeout)
+	}
+
+	data, extracted, ok := protocol.ExtractTrace(data)
+	if !ok || extracted != sc {
+		t.Fatalf("unexpected trace: %v %+v", ok, extracted)
+	}
+
+	if string(data) != string(origin) {
+		t.Fatalf("unexpected request frame: %v", data)
+	}
+
+	if _, _, ok = protocol.ExtractTrace(origin); ok {
+		t.Fatal("request frame without trace should not carry one")
+	}
+}
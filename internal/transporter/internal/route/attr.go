@@ -0,0 +1,7 @@
+package route
+
+const (
+	GetAttr      = PushGroup + iota + 1 // 获取会话属性
+	SetAttr                             // 设置会话属性
+	TriggerAttrs                        // 触发携带会话属性的事件
+)
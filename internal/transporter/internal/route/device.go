@@ -0,0 +1,5 @@
+package route
+
+const (
+	PushDevice = TriggerAttrs + iota + 1 // 推送用户指定设备消息
+)
@@ -0,0 +1,7 @@
+package route
+
+const (
+	JoinGroup  = SetState + iota + 1 // 加入分组
+	LeaveGroup                       // 退出分组
+	PushGroup                        // 推送分组消息
+)
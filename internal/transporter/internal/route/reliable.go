@@ -0,0 +1,5 @@
+package route
+
+const (
+	PushReliable = PushDevice + iota + 1 // 可靠推送消息
+)
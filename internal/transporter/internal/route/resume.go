@@ -0,0 +1,5 @@
+package route
+
+const (
+	Resume = PushReliable + iota + 1 // 恢复挂起的用户
+)
@@ -0,0 +1,8 @@
+package server
+
+import "crypto/tls"
+
+type Options struct {
+	Addr      string      // 监听地址
+	TLSConfig *tls.Config // TLS配置，配置后启用TLS并以安全端点对外暴露
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"gatesvr/core/endpoint"
+	xnet "gatesvr/core/net"
+	"gatesvr/errors"
+	"gatesvr/internal/transporter/internal/codes"
+	"gatesvr/internal/transporter/internal/protocol"
+	"gatesvr/internal/transporter/internal/route"
+	"gatesvr/log"
+	"gatesvr/trace"
+	"net"
+	"sync"
+	"time"
+)
+
+const scheme = "drpc"
+
+// ContextHandler 携带上下文的路由处理器
+type ContextHandler func(ctx context.Context, conn *Conn, data []byte) error
+
+type Server struct {
+	listener    net.Listener           // 监听器
+	listenAddr  string                 // 监听地址
+	exposeAddr  string                 // 暴露地址
+	tlsConfig   *tls.Config            // TLS配置
+	endpoint    *endpoint.Endpoint     // 暴露端点
+	handlers    map[uint8]RouteHandler // 路由处理器
+	rw          sync.RWMutex           // 锁
+	connections map[net.Conn]*Conn     // 连接
+}
+
+func NewServer(opts *Options) (*Server, error) {
+	listenAddr, exposeAddr, err := xnet.ParseAddr(opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{}
+	s.listenAddr = listenAddr
+	s.exposeAddr = exposeAddr
+	s.tlsConfig = opts.TLSConfig
+	s.endpoint = endpoint.NewEndpoint(scheme, exposeAddr, opts.TLSConfig != nil)
+	s.connections = make(map[net.Conn]*Conn)
+	s.handlers = make(map[uint8]RouteHandler)
+	s.handlers[route.Handshake] = s.handshake
+
+	return s, nil
+}
+
+// Scheme 协议
+func (s *Server) Scheme() string {
+	return scheme
+}
+
+// ListenAddr 监听地址
+func (s *Server) ListenAddr() string {
+	return s.listenAddr
+}
+
+// ExposeAddr 暴露地址
+func (s *Server) ExposeAddr() string {
+	return s.exposeAddr
+}
+
+// Endpoint 暴露端点
+func (s *Server) Endpoint() *endpoint.Endpoint {
+	return s.endpoint
+}
+
+// Start 启动服务器
+func (s *Server) Start() error {
+	addr, err := net.ResolveTCPAddr("tcp", s.listenAddr)
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.ListenTCP(addr.Network(), addr)
+	if err != nil {
+		return err
+	}
+
+	if s.tlsConfig != nil {
+		s.listener = tls.NewListener(ln, s.tlsConfig)
+	} else {
+		s.listener = ln
+	}
+
+	var tempDelay time.Duration
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+
+				if tempDelay > time.Second {
+					tempDelay = time.Second
+				}
+
+				log.Warnf("tcp accept connect error: %v; retrying in %v", err, tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+
+			log.Warnf("tcp accept connect error: %v", err)
+			return nil
+		}
+
+		tempDelay = 0
+
+		s.allocate(conn)
+	}
+}
+
+// Stop 停止服务器
+func (s *Server) Stop() error {
+	if err := s.listener.Close(); err != nil {
+		return err
+	}
+
+	s.rw.Lock()
+	for _, conn := range s.connections {
+		_ = conn.close()
+	}
+	s.connections = nil
+	s.rw.Unlock()
+
+	return nil
+}
+
+// RegisterHandler 注册处理器
+func (s *Server) RegisterHandler(route uint8, handler RouteHandler) {
+	s.RegisterContextHandler(route, func(_ context.Context, conn *Conn, data []byte) error {
+		return handler(conn, data)
+	})
+}
+
+// RegisterContextHandler 注册携带上下文的处理器
+// 请求帧携带剩余时间时，上下文以该剩余时间作为截止时间；请求到达时已超时的，处理器收到的上下文已结束
+// 请求帧携带链路上下文时，可通过trace.FromContext获取并延续链路
+func (s *Server) RegisterContextHandler(route uint8, handler ContextHandler) {
+	s.handlers[route] = func(conn *Conn, data []byte) error {
+		ctx := context.Background()
+
+		data, timeout, ok := protocol.ExtractDeadline(data)
+		if ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		data, sc, ok := protocol.ExtractTrace(data)
+		if ok {
+			ctx = trace.NewContext(ctx, sc)
+		}
+
+		return handler(ctx, conn, data)
+	}
+}
+
+// 分配连接
+func (s *Server) allocate(conn net.Conn) {
+	s.rw.Lock()
+	s.connections[conn] = newConn(s, conn)
+	s.rw.Unlock()
+}
+
+// 回收连接
+func (s *Server) recycle(conn net.Conn) {
+	s.rw.Lock()
+	delete(s.connections, conn)
+	s.rw.Unlock()
+}
+
+// 处理握手
+// 协商协议版本与能力，拒绝不兼容的对端并关闭连接
+func (s *Server) handshake(conn *Conn, data []byte) error {
+	seq, version, capabilities, insKind, insID, err := protocol.DecodeHandshakeReq(data)
+	if err != nil {
+		return err
+	}
+
+	version, capabilities, ok := protocol.Negotiate(version, capabilities)
+	if !ok {
+		_ = conn.Send(protocol.EncodeHandshakeRes(seq, codes.IncompatibleProtocol, version, protocol.Capabilities))
+		_ = conn.close(true)
+		return errors.ErrIncompatibleProtocol
+	}
+
+	conn.InsKind = insKind
+	conn.InsID = insID
+
+	return conn.Send(protocol.EncodeHandshakeRes(seq, codes.OK, version, capabilities))
+}
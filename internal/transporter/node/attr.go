@@ -0,0 +1,17 @@
+package node
+
+import (
+	"context"
+	"gatesvr/cluster"
+	"gatesvr/internal/transporter/internal/protocol"
+)
+
+// TriggerAttrs 触发携带会话属性的事件
+func (c *Client) TriggerAttrs(ctx context.Context, event cluster.Event, cid, uid int64, attrs map[string]string) error {
+	buf, err := protocol.EncodeTriggerAttrsReq(0, event, cid, uid, attrs)
+	if err != nil {
+		return err
+	}
+
+	return c.cli.Send(ctx, buf)
+}
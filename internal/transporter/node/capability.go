@@ -0,0 +1,23 @@
+package node
+
+import "gatesvr/internal/transporter/internal/protocol"
+
+type Capability = protocol.Capability
+
+const (
+	CapCompress = protocol.CapCompress // 消息压缩
+	CapTrace    = protocol.CapTrace    // 链路追踪头
+	CapBatch    = protocol.CapBatch    // 批量帧
+	CapDeadline = protocol.CapDeadline // 请求截止时间
+)
+
+// Version 获取与对端协商的协议版本，尚未完成握手时返回0
+func (c *Client) Version() uint16 {
+	return c.cli.Version()
+}
+
+// Supports 检测对端是否支持指定能力，尚未完成握手时返回false
+// 新增路由需依赖对端能力时，应在对端支持时使用，否则回退至原有路由
+func (c *Client) Supports(capability Capability) bool {
+	return c.cli.Capabilities().Has(capability)
+}
@@ -0,0 +1,19 @@
+package node
+
+import (
+	"context"
+	"gatesvr/cluster"
+)
+
+type Provider interface {
+	// Trigger 触发事件
+	Trigger(ctx context.Context, gid string, cid, uid int64, event cluster.Event) error
+	// TriggerAttrs 触发携带会话属性的事件
+	TriggerAttrs(ctx context.Context, gid string, cid, uid int64, event cluster.Event, attrs map[string]string) error
+	// Deliver 投递消息
+	Deliver(ctx context.Context, gid, nid string, cid, uid int64, message []byte) error
+	// GetState 获取状态
+	GetState() (cluster.State, error)
+	// SetState 设置状态
+	SetState(state cluster.State) error
+}
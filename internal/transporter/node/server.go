@@ -0,0 +1,159 @@
+package node
+
+import (
+	"context"
+	"gatesvr/cluster"
+	"gatesvr/errors"
+	"gatesvr/internal/transporter/internal/codes"
+	"gatesvr/internal/transporter/internal/protocol"
+	"gatesvr/internal/transporter/internal/route"
+	"gatesvr/internal/transporter/internal/server"
+)
+
+type Server struct {
+	*server.Server
+	provider Provider
+}
+
+func NewServer(addr string, provider Provider) (*Server, error) {
+	serv, err := server.NewServer(&server.Options{Addr: addr})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{Server: serv, provider: provider}
+	s.init()
+
+	return s, nil
+}
+
+func (s *Server) init() {
+	s.RegisterContextHandler(route.Trigger, s.trigger)
+	s.RegisterContextHandler(route.TriggerAttrs, s.triggerAttrs)
+	s.RegisterContextHandler(route.Deliver, s.deliver)
+	s.RegisterContextHandler(route.GetState, s.getState)
+	s.RegisterContextHandler(route.SetState, s.setState)
+}
+
+// 触发事件
+func (s *Server) trigger(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, event, cid, uid, err := protocol.DecodeTriggerReq(data)
+	if err != nil {
+		return err
+	}
+
+	if conn.InsKind != cluster.Gate {
+		return errors.ErrIllegalRequest
+	}
+
+	if err = expired(ctx); err == nil {
+		err = s.provider.Trigger(ctx, conn.InsID, cid, uid, event)
+	}
+
+	if seq == 0 {
+		if errors.Is(err, errors.ErrNotFoundSession) {
+			return nil
+		} else {
+			return err
+		}
+	} else {
+		return conn.Send(protocol.EncodeTriggerRes(seq, codes.ErrorToCode(err)))
+	}
+}
+
+// 触发携带会话属性的事件
+func (s *Server) triggerAttrs(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, event, cid, uid, attrs, err := protocol.DecodeTriggerAttrsReq(data)
+	if err != nil {
+		return err
+	}
+
+	if conn.InsKind != cluster.Gate {
+		return errors.ErrIllegalRequest
+	}
+
+	if err = expired(ctx); err == nil {
+		err = s.provider.TriggerAttrs(ctx, conn.InsID, cid, uid, event, attrs)
+	}
+
+	if seq == 0 {
+		if errors.Is(err, errors.ErrNotFoundSession) {
+			return nil
+		} else {
+			return err
+		}
+	} else {
+		return conn.Send(protocol.EncodeTriggerAttrsRes(seq, codes.ErrorToCode(err)))
+	}
+}
+
+// 投递消息
+// 请求帧携带的截止时间及链路上下文经由ctx传递至提供者
+func (s *Server) deliver(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, cid, uid, message, err := protocol.DecodeDeliverReq(data)
+	if err != nil {
+		return err
+	}
+
+	var (
+		gid string
+		nid string
+	)
+
+	switch conn.InsKind {
+	case cluster.Gate:
+		gid = conn.InsID
+	case cluster.Node:
+		nid = conn.InsID
+	default:
+		return errors.ErrIllegalRequest
+	}
+
+	if err = expired(ctx); err == nil {
+		err = s.provider.Deliver(ctx, gid, nid, cid, uid, message)
+	}
+
+	if seq == 0 {
+		return err
+	} else {
+		return conn.Send(protocol.EncodeDeliverRes(seq, codes.ErrorToCode(err)))
+	}
+}
+
+// 获取状态
+func (s *Server) getState(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, err := protocol.DecodeGetStateReq(data)
+	if err != nil {
+		return err
+	}
+
+	var state cluster.State
+	if err = expired(ctx); err == nil {
+		state, err = s.provider.GetState()
+	}
+
+	return conn.Send(protocol.EncodeGetStateRes(seq, codes.ErrorToCode(err), state))
+}
+
+// 设置状态
+func (s *Server) setState(ctx context.Context, conn *server.Conn, data []byte) error {
+	seq, state, err := protocol.DecodeSetStateReq(data)
+	if err != nil {
+		return err
+	}
+
+	if err = expired(ctx); err == nil {
+		err = s.provider.SetState(state)
+	}
+
+	return conn.Send(protocol.EncodeSetStateRes(seq, codes.ErrorToCode(err)))
+}
+
+// 检测请求是否已超时，已超时的请求跳过执行并以DeadlineExceeded响应
+func expired(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return errors.ErrDeadlineExceeded
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package node_test
+
+import (
+	"context"
+	"gatesvr/cluster"
+	"gatesvr/core/endpoint"
+	"gatesvr/internal/transporter/node"
+	"testing"
+	"time"
+)
+
+type trigger struct {
+	gid   string
+	event cluster.Event
+	cid   int64
+	uid   int64
+	attrs map[string]string
+}
+
+type provider struct {
+	triggers chan *trigger
+}
+
+// Trigger 触发事件
+func (p *provider) Trigger(ctx context.Context, gid string, cid, uid int64, event cluster.Event) error {
+	p.triggers <- &trigger{gid: gid, event: event, cid: cid, uid: uid}
+	return nil
+}
+
+// TriggerAttrs 触发携带会话属性的事件
+func (p *provider) TriggerAttrs(ctx context.Context, gid string, cid, uid int64, event cluster.Event, attrs map[string]string) error {
+	p.triggers <- &trigger{gid: gid, event: event, cid: cid, uid: uid, attrs: attrs}
+	return nil
+}
+
+// Deliver 投递消息
+func (p *provider) Deliver(ctx context.Context, gid, nid string, cid, uid int64, message []byte) error {
+	return nil
+}
+
+// GetState 获取状态
+func (p *provider) GetState() (cluster.State, error) {
+	return cluster.Work, nil
+}
+
+// SetState 设置状态
+func (p *provider) SetState(state cluster.State) error {
+	return nil
+}
+
+func TestServer_TriggerAttrs(t *testing.T) {
+	p := &provider{triggers: make(chan *trigger, 1)}
+
+	server, err := node.NewServer("127.0.0.1:49896", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go server.Start()
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	builder := node.NewBuilder(&node.Options{InsID: "gate-1", InsKind: cluster.Gate})
+
+	client, err := builder.Build(endpoint.NewEndpoint("grpc", "127.0.0.1:49896", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := map[string]string{"device": "ios", "version": "1.0.0"}
+	if err = client.TriggerAttrs(context.Background(), cluster.Disconnect, 1, 100, attrs); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case tr := <-p.triggers:
+		if tr.gid != "gate-1" || tr.event != cluster.Disconnect || tr.cid != 1 || tr.uid != 100 {
+			t.Fatalf("unexpected trigger: %+v", tr)
+		}
+
+		if len(tr.attrs) != len(attrs) || tr.attrs["device"] != "ios" || tr.attrs["version"] != "1.0.0" {
+			t.Fatalf("unexpected attrs: %v", tr.attrs)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("trigger with attrs was not received")
+	}
+}
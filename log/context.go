@@ -0,0 +1,135 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"gatesvr/trace"
+	"os"
+)
+
+type contextLogger struct {
+	logger  Logger
+	traceID string
+	spanID  string
+}
+
+// WithContext 获取携带链路信息的日志记录器
+// 上下文中存在链路时，日志自动附加链路ID与跨度ID；调用层级与全局日志函数保持一致，以保证调用者信息准确
+func WithContext(ctx context.Context) Logger {
+	l := &contextLogger{logger: globalLogger}
+
+	if ctx == nil {
+		return l
+	}
+
+	if sc, ok := trace.FromContext(ctx); ok {
+		l.traceID = sc.TraceID.String()
+		l.spanID = sc.SpanID.String()
+	}
+
+	return l
+}
+
+// 打印日志
+func (l *contextLogger) print(level Level, isNeedStack bool, a ...interface{}) {
+	l.log(level, isNeedStack, a...)
+}
+
+// 输出日志
+// 默认日志记录器将链路信息写入日志实体，其他日志记录器以消息前缀的形式附加链路信息
+func (l *contextLogger) log(level Level, isNeedStack bool, a ...interface{}) {
+	switch logger := l.logger.(type) {
+	case nil:
+	case *defaultLogger:
+		e := logger.BuildEntity(level, isNeedStack, a...)
+		e.TraceID = l.traceID
+		e.SpanID = l.spanID
+		e.Log()
+	default:
+		if l.traceID != "" {
+			a = append([]interface{}{fmt.Sprintf("[trace_id=%s span_id=%s]", l.traceID, l.spanID)}, a...)
+		}
+		logger.Print(level, a...)
+	}
+}
+
+// Print 打印日志
+func (l *contextLogger) Print(level Level, a ...interface{}) {
+	l.print(level, false, a...)
+}
+
+// Printf 打印模板日志
+func (l *contextLogger) Printf(level Level, format string, a ...interface{}) {
+	l.print(level, false, fmt.Sprintf(format, a...))
+}
+
+// Debug 打印调试日志
+func (l *contextLogger) Debug(a ...interface{}) {
+	l.print(DebugLevel, true, a...)
+}
+
+// Debugf 打印调试模板日志
+func (l *contextLogger) Debugf(format string, a ...interface{}) {
+	l.print(DebugLevel, true, fmt.Sprintf(format, a...))
+}
+
+// Info 打印信息日志
+func (l *contextLogger) Info(a ...interface{}) {
+	l.print(InfoLevel, true, a...)
+}
+
+// Infof 打印信息模板日志
+func (l *contextLogger) Infof(format string, a ...interface{}) {
+	l.print(InfoLevel, true, fmt.Sprintf(format, a...))
+}
+
+// Warn 打印警告日志
+func (l *contextLogger) Warn(a ...interface{}) {
+	l.print(WarnLevel, true, a...)
+}
+
+// Warnf 打印警告模板日志
+func (l *contextLogger) Warnf(format string, a ...interface{}) {
+	l.print(WarnLevel, true, fmt.Sprintf(format, a...))
+}
+
+// Error 打印错误日志
+func (l *contextLogger) Error(a ...interface{}) {
+	l.print(ErrorLevel, true, a...)
+}
+
+// Errorf 打印错误模板日志
+func (l *contextLogger) Errorf(format string, a ...interface{}) {
+	l.print(ErrorLevel, true, fmt.Sprintf(format, a...))
+}
+
+// Fatal 打印致命错误日志
+func (l *contextLogger) Fatal(a ...interface{}) {
+	l.print(FatalLevel, true, a...)
+	os.Exit(1)
+}
+
+// Fatalf 打印致命错误模板日志
+func (l *contextLogger) Fatalf(format string, a ...interface{}) {
+	l.print(FatalLevel, true, fmt.Sprintf(format, a...))
+	os.Exit(1)
+}
+
+// Panic 打印Panic日志
+func (l *contextLogger) Panic(a ...interface{}) {
+	l.print(PanicLevel, true, a...)
+}
+
+// Panicf 打印Panic模板日志
+func (l *contextLogger) Panicf(format string, a ...interface{}) {
+	l.print(PanicLevel, true, fmt.Sprintf(format, a...))
+}
+
+// Close 关闭日志
+func (l *contextLogger) Close() error {
+	if l.logger == nil {
+		return nil
+	}
+
+	return l.logger.Close()
+}
@@ -97,6 +97,8 @@ type Entity struct {
 	Time    string
 	Caller  string
 	Message string
+	TraceID string
+	SpanID  string
 	Frames  []runtime.Frame
 	pool    *EntityPool
 }
@@ -107,6 +109,8 @@ func (e *Entity) Free() {
 	e.Time = ""
 	e.Caller = ""
 	e.Message = ""
+	e.TraceID = ""
+	e.SpanID = ""
 	e.Frames = nil
 	e.pool.pool.Put(e)
 }
@@ -18,6 +18,8 @@ const (
 	fieldKeyTime      = "time"
 	fieldKeyFile      = "file"
 	fieldKeyMsg       = "msg"
+	fieldKeyTraceID   = "trace_id"
+	fieldKeySpanID    = "span_id"
 	fieldKeyStack     = "stack"
 	fieldKeyStackFunc = "func"
 	fieldKeyStackFile = "file"
@@ -48,6 +50,10 @@ func (f *jsonFormatter) format(e *Entity, isTerminal bool) []byte {
 		b.WriteString(`,"` + fieldKeyFile + `":"` + e.Caller + `"`)
 	}
 
+	if e.TraceID != "" {
+		b.WriteString(`,"` + fieldKeyTraceID + `":"` + e.TraceID + `","` + fieldKeySpanID + `":"` + e.SpanID + `"`)
+	}
+
 	if e.Message != "" {
 		b.WriteString(`,"` + fieldKeyMsg + `":"` + e.Message + `"`)
 	}
@@ -1,7 +1,9 @@
 package log_test
 
 import (
+	"context"
 	"gatesvr/log"
+	"gatesvr/trace"
 	"testing"
 )
 
@@ -13,3 +15,13 @@ func TestLog(t *testing.T) {
 	logger.Warn("welcome to due-framework")
 	logger.Error("welcome to due-framework")
 }
+
+func TestWithContext(t *testing.T) {
+	sc, err := trace.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.WithContext(trace.NewContext(context.Background(), sc)).Info("welcome to due-framework")
+	log.WithContext(context.Background()).Info("welcome to due-framework")
+}
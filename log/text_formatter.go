@@ -42,6 +42,10 @@ func (f *textFormatter) format(e *Entity, isTerminal bool) []byte {
 		b.WriteString(" " + e.Caller)
 	}
 
+	if e.TraceID != "" {
+		b.WriteString(" trace_id=" + e.TraceID + " span_id=" + e.SpanID)
+	}
+
 	if e.Message != "" {
 		b.WriteString(" " + e.Message)
 	}
@@ -0,0 +1,72 @@
+package kcp
+
+import (
+	"gatesvr/network"
+	"github.com/xtaci/kcp-go/v5"
+	"net"
+	"sync/atomic"
+)
+
+type client struct {
+	opts              *clientOptions            // 配置
+	id                int64                     // 连接ID
+	connectHandler    network.ConnectHandler    // 连接打开hook函数
+	disconnectHandler network.DisconnectHandler // 连接关闭hook函数
+	receiveHandler    network.ReceiveHandler    // 接收消息hook函数
+}
+
+var _ network.Client = &client{}
+
+func NewClient(opts ...ClientOption) network.Client {
+	o := defaultClientOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &client{opts: o}
+}
+
+// Dial 拨号连接
+// KCP基于UDP，拨号时不进行握手，连接是否可用以首个消息的收发为准
+func (c *client) Dial(addr ...string) (network.Conn, error) {
+	var address string
+	if len(addr) > 0 && addr[0] != "" {
+		address = addr[0]
+	} else {
+		address = c.opts.addr
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := kcp.DialWithOptions(udpAddr.String(), nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	c.opts.arq.setup(sess)
+
+	return newClientConn(c, atomic.AddInt64(&c.id, 1), sess), nil
+}
+
+// Protocol 协议
+func (c *client) Protocol() string {
+	return protocol
+}
+
+// OnConnect 监听连接打开
+func (c *client) OnConnect(handler network.ConnectHandler) {
+	c.connectHandler = handler
+}
+
+// OnDisconnect 监听连接关闭
+func (c *client) OnDisconnect(handler network.DisconnectHandler) {
+	c.disconnectHandler = handler
+}
+
+// OnReceive 监听接收到消息
+func (c *client) OnReceive(handler network.ReceiveHandler) {
+	c.receiveHandler = handler
+}
@@ -0,0 +1,82 @@
+package kcp
+
+import (
+	"gatesvr/etc"
+	"time"
+)
+
+const (
+	defaultClientDialAddr          = "127.0.0.1:3555"
+	defaultClientHeartbeatInterval = "10s"
+	defaultClientNoDelay           = true
+	defaultClientInterval          = "10ms"
+	defaultClientResend            = 2
+	defaultClientNoCongestion      = true
+	defaultClientSndWnd            = 256
+	defaultClientRcvWnd            = 256
+	defaultClientMTU               = 1400
+)
+
+const (
+	defaultClientDialAddrKey          = "etc.network.kcp.client.addr"
+	defaultClientHeartbeatIntervalKey = "etc.network.kcp.client.heartbeatInterval"
+	defaultClientNoDelayKey           = "etc.network.kcp.client.noDelay"
+	defaultClientIntervalKey          = "etc.network.kcp.client.interval"
+	defaultClientResendKey            = "etc.network.kcp.client.resend"
+	defaultClientNoCongestionKey      = "etc.network.kcp.client.noCongestion"
+	defaultClientSndWndKey            = "etc.network.kcp.client.sndWnd"
+	defaultClientRcvWndKey            = "etc.network.kcp.client.rcvWnd"
+	defaultClientMTUKey               = "etc.network.kcp.client.mtu"
+)
+
+type ClientOption func(o *clientOptions)
+
+type clientOptions struct {
+	addr              string        // 地址
+	heartbeatInterval time.Duration // 心跳间隔时间，默认10s
+	arq               arqOptions    // ARQ配置
+}
+
+func defaultClientOptions() *clientOptions {
+	return &clientOptions{
+		addr:              etc.Get(defaultClientDialAddrKey, defaultClientDialAddr).String(),
+		heartbeatInterval: etc.Get(defaultClientHeartbeatIntervalKey, defaultClientHeartbeatInterval).Duration(),
+		arq: arqOptions{
+			noDelay:      etc.Get(defaultClientNoDelayKey, defaultClientNoDelay).Bool(),
+			interval:     etc.Get(defaultClientIntervalKey, defaultClientInterval).Duration(),
+			resend:       etc.Get(defaultClientResendKey, defaultClientResend).Int(),
+			noCongestion: etc.Get(defaultClientNoCongestionKey, defaultClientNoCongestion).Bool(),
+			sndWnd:       etc.Get(defaultClientSndWndKey, defaultClientSndWnd).Int(),
+			rcvWnd:       etc.Get(defaultClientRcvWndKey, defaultClientRcvWnd).Int(),
+			mtu:          etc.Get(defaultClientMTUKey, defaultClientMTU).Int(),
+		},
+	}
+}
+
+// WithClientDialAddr 设置拨号地址
+func WithClientDialAddr(addr string) ClientOption {
+	return func(o *clientOptions) { o.addr = addr }
+}
+
+// WithClientHeartbeatInterval 设置心跳间隔时间
+func WithClientHeartbeatInterval(heartbeatInterval time.Duration) ClientOption {
+	return func(o *clientOptions) { o.heartbeatInterval = heartbeatInterval }
+}
+
+// WithClientNoDelay 设置ARQ重传参数
+// noDelay为是否启用nodelay模式，interval为内部刷新间隔时间，resend为快速重传阈值，noCongestion为是否关闭拥塞控制
+func WithClientNoDelay(noDelay bool, interval time.Duration, resend int, noCongestion bool) ClientOption {
+	return func(o *clientOptions) {
+		o.arq.noDelay, o.arq.interval, o.arq.resend, o.arq.noCongestion = noDelay, interval, resend, noCongestion
+	}
+}
+
+// WithClientWindowSize 设置发送及接收窗口大小
+func WithClientWindowSize(sndWnd, rcvWnd int) ClientOption {
+	return func(o *clientOptions) { o.arq.sndWnd, o.arq.rcvWnd = sndWnd, rcvWnd }
+}
+
+// WithClientMTU 设置最大传输单元
+func WithClientMTU(mtu int) ClientOption {
+	return func(o *clientOptions) { o.arq.mtu = mtu }
+}
@@ -0,0 +1,48 @@
+package kcp
+
+import (
+	"github.com/xtaci/kcp-go/v5"
+	"time"
+)
+
+const protocol = "kcp"
+
+const (
+	dataPacket      = iota // 数据包
+	heartbeatPacket        // 心跳包
+	closeSig               // 关闭信号
+)
+
+type chWrite struct {
+	typ int    // 写入类型
+	msg []byte // 写入消息
+}
+
+type arqOptions struct {
+	noDelay      bool          // 是否启用nodelay模式，启用后降低重传超时并立即回复ACK
+	interval     time.Duration // 内部刷新间隔时间
+	resend       int           // 快速重传阈值，收到该数量的跳过ACK后立即重传，为0时不启用快速重传
+	noCongestion bool          // 是否关闭拥塞控制
+	sndWnd       int           // 发送窗口大小（包）
+	rcvWnd       int           // 接收窗口大小（包）
+	mtu          int           // 最大传输单元
+}
+
+// 配置KCP会话
+// 使用流模式传输，复用与TCP一致的消息分包方式
+func (o *arqOptions) setup(sess *kcp.UDPSession) {
+	var noDelay, noCongestion int
+	if o.noDelay {
+		noDelay = 1
+	}
+	if o.noCongestion {
+		noCongestion = 1
+	}
+
+	sess.SetStreamMode(true)
+	sess.SetWriteDelay(false)
+	sess.SetACKNoDelay(o.noDelay)
+	sess.SetNoDelay(noDelay, int(o.interval/time.Millisecond), o.resend, noCongestion)
+	sess.SetWindowSize(o.sndWnd, o.rcvWnd)
+	sess.SetMtu(o.mtu)
+}
@@ -0,0 +1,162 @@
+package kcp
+
+import (
+	"gatesvr/log"
+	"gatesvr/network"
+	"github.com/xtaci/kcp-go/v5"
+	"net"
+	"time"
+)
+
+type server struct {
+	opts              *serverOptions            // 配置
+	listener          *kcp.Listener             // 监听器
+	connMgr           *serverConnMgr            // 连接管理器
+	outbound          *network.OutboundMetrics  // 发送队列统计
+	startHandler      network.StartHandler      // 服务器启动hook函数
+	stopHandler       network.CloseHandler      // 服务器关闭hook函数
+	connectHandler    network.ConnectHandler    // 连接打开hook函数
+	disconnectHandler network.DisconnectHandler // 连接关闭hook函数
+	receiveHandler    network.ReceiveHandler    // 接收消息hook函数
+}
+
+var _ network.Server = &server{}
+
+func NewServer(opts ...ServerOption) network.Server {
+	o := defaultServerOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s := &server{}
+	s.opts = o
+	s.outbound = &network.OutboundMetrics{}
+	s.connMgr = newServerConnMgr(s)
+
+	return s
+}
+
+// Addr 监听地址
+func (s *server) Addr() string {
+	return s.opts.addr
+}
+
+// Start 启动服务器
+func (s *server) Start() error {
+	if err := s.init(); err != nil {
+		return err
+	}
+
+	if s.startHandler != nil {
+		s.startHandler()
+	}
+
+	go s.serve()
+
+	return nil
+}
+
+// Stop 关闭服务器
+func (s *server) Stop() error {
+	if err := s.listener.Close(); err != nil {
+		return err
+	}
+
+	s.connMgr.close()
+
+	if s.stopHandler != nil {
+		s.stopHandler()
+	}
+
+	return nil
+}
+
+// Protocol 协议
+func (s *server) Protocol() string {
+	return protocol
+}
+
+// OutboundStats 获取发送队列统计
+func (s *server) OutboundStats() network.OutboundStats {
+	return s.outbound.Stats()
+}
+
+// OnStart 监听服务器启动
+func (s *server) OnStart(handler network.StartHandler) {
+	s.startHandler = handler
+}
+
+// OnStop 监听服务器关闭
+func (s *server) OnStop(handler network.CloseHandler) {
+	s.stopHandler = handler
+}
+
+// OnConnect 监听连接打开
+func (s *server) OnConnect(handler network.ConnectHandler) {
+	s.connectHandler = handler
+}
+
+// OnReceive 监听接收到消息
+func (s *server) OnReceive(handler network.ReceiveHandler) {
+	s.receiveHandler = handler
+}
+
+// OnDisconnect 监听连接断开
+func (s *server) OnDisconnect(handler network.DisconnectHandler) {
+	s.disconnectHandler = handler
+}
+
+// 初始化KCP服务器
+func (s *server) init() error {
+	addr, err := net.ResolveUDPAddr("udp", s.opts.addr)
+	if err != nil {
+		return err
+	}
+
+	ln, err := kcp.ListenWithOptions(addr.String(), nil, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	s.listener = ln
+
+	return nil
+}
+
+// 等待连接
+func (s *server) serve() {
+	var tempDelay time.Duration
+
+	for {
+		sess, err := s.listener.AcceptKCP()
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+
+				if tempDelay > time.Second {
+					tempDelay = time.Second
+				}
+
+				log.Warnf("kcp accept connect error: %v; retrying in %v", err, tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+
+			log.Warnf("kcp accept connect error: %v", err)
+			return
+		}
+
+		tempDelay = 0
+
+		s.opts.arq.setup(sess)
+
+		if err = s.connMgr.allocate(sess); err != nil {
+			log.Errorf("connection allocate error: %v", err)
+			_ = sess.Close()
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package kcp
+
+import (
+	"gatesvr/errors"
+	"gatesvr/log"
+	"gatesvr/network"
+	"gatesvr/packet"
+	"gatesvr/utils/xcall"
+	"gatesvr/utils/xnet"
+	"gatesvr/utils/xtime"
+	"github.com/xtaci/kcp-go/v5"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type serverConn struct {
+	rw                sync.RWMutex      // 读写锁
+	id                int64             // 连接ID
+	uid               int64             // 用户ID
+	conv              uint32            // 会话ID
+	conn              net.Conn          // KCP源连接
+	state             int32             // 连接状态
+	connMgr           *serverConnMgr    // 连接管理
+	outbound          *network.Outbound // 发送队列
+	done              chan struct{}     // 写入完成信号
+	close             chan struct{}     // 关闭信号
+	lastHeartbeatTime int64             // 上次心跳时间
+}
+
+var _ network.Conn = &serverConn{}
+
+func newServerConn(cm *serverConnMgr, id int64, sess *kcp.UDPSession) *serverConn {
+	return &serverConn{
+		id:                id,
+		conv:              sess.GetConv(),
+		conn:              sess,
+		state:             int32(network.ConnOpened),
+		connMgr:           cm,
+		outbound:          network.NewOutbound(cm.server.opts.outboundMaxBytes, cm.server.opts.outboundPolicy, cm.server.outbound),
+		done:              make(chan struct{}),
+		close:             make(chan struct{}),
+		lastHeartbeatTime: xtime.Now().UnixNano(),
+	}
+}
+
+// ID 获取连接ID
+func (c *serverConn) ID() int64 {
+	return c.id
+}
+
+// UID 获取用户ID
+func (c *serverConn) UID() int64 {
+	return atomic.LoadInt64(&c.uid)
+}
+
+// Bind 绑定用户ID
+func (c *serverConn) Bind(uid int64) {
+	atomic.StoreInt64(&c.uid, uid)
+}
+
+// Unbind 解绑用户ID
+func (c *serverConn) Unbind() {
+	atomic.StoreInt64(&c.uid, 0)
+}
+
+// Send 发送消息（同步）
+func (c *serverConn) Send(msg []byte) error {
+	if err := c.checkState(); err != nil {
+		return err
+	}
+
+	c.rw.RLock()
+	conn := c.conn
+	c.rw.RUnlock()
+
+	if conn == nil {
+		return errors.ErrConnectionClosed
+	}
+
+	_, err := conn.Write(msg)
+	return err
+}
+
+// Push 发送消息（异步）
+// 写入发送队列，不会阻塞；队列已满时按发送队列策略处理
+func (c *serverConn) Push(msg []byte) error {
+	if err := c.checkState(); err != nil {
+		return err
+	}
+
+	err := c.outbound.Push(msg)
+	if errors.Is(err, errors.ErrSlowConsumer) {
+		log.Warnf("connection is disconnected due to slow consumer, cid: %d uid: %d", c.id, c.UID())
+		// 调用方可能持有会话锁，异步关闭连接以避免死锁
+		xcall.Go(func() { _ = c.forceClose() })
+	}
+
+	return err
+}
+
+// State 获取连接状态
+func (c *serverConn) State() network.ConnState {
+	return network.ConnState(atomic.LoadInt32(&c.state))
+}
+
+// Close 关闭连接
+func (c *serverConn) Close(force ...bool) error {
+	if len(force) > 0 && force[0] {
+		return c.forceClose()
+	} else {
+		return c.graceClose()
+	}
+}
+
+// LocalIP 获取本地IP
+func (c *serverConn) LocalIP() (string, error) {
+	addr, err := c.LocalAddr()
+	if err != nil {
+		return "", err
+	}
+
+	return xnet.ExtractIP(addr)
+}
+
+// LocalAddr 获取本地地址
+func (c *serverConn) LocalAddr() (net.Addr, error) {
+	if err := c.checkState(); err != nil {
+		return nil, err
+	}
+
+	c.rw.RLock()
+	conn := c.conn
+	c.rw.RUnlock()
+
+	if conn == nil {
+		return nil, errors.ErrConnectionClosed
+	}
+
+	return conn.LocalAddr(), nil
+}
+
+// RemoteIP 获取远端IP
+func (c *serverConn) RemoteIP() (string, error) {
+	addr, err := c.RemoteAddr()
+	if err != nil {
+		return "", err
+	}
+
+	return xnet.ExtractIP(addr)
+}
+
+// RemoteAddr 获取远端地址
+func (c *serverConn) RemoteAddr() (net.Addr, error) {
+	if err := c.checkState(); err != nil {
+		return nil, err
+	}
+
+	c.rw.RLock()
+	conn := c.conn
+	c.rw.RUnlock()
+
+	if conn == nil {
+		return nil, errors.ErrConnectionClosed
+	}
+
+	return conn.RemoteAddr(), nil
+}
+
+// 启动连接
+func (c *serverConn) start() {
+	xcall.Go(c.read)
+
+	xcall.Go(c.write)
+
+	if c.connMgr.server.connectHandler != nil {
+		c.connMgr.server.connectHandler(c)
+	}
+}
+
+// 写入信号
+func (c *serverConn) signal(sig int) error {
+	if err := c.checkState(); err != nil {
+		return err
+	}
+
+	return c.outbound.Signal(sig)
+}
+
+// 检测连接状态
+func (c *serverConn) checkState() error {
+	switch network.ConnState(atomic.LoadInt32(&c.state)) {
+	case network.ConnHanged:
+		return errors.ErrConnectionHanged
+	case network.ConnClosed:
+		return errors.ErrConnectionClosed
+	default:
+		return nil
+	}
+}
+
+// 优雅关闭
+func (c *serverConn) graceClose() error {
+	c.rw.RLock()
+
+	if !atomic.CompareAndSwapInt32(&c.state, int32(network.ConnOpened), int32(network.ConnHanged)) {
+		c.rw.RUnlock()
+		return errors.ErrConnectionNotOpened
+	}
+
+	_ = c.outbound.Signal(closeSig)
+
+	c.rw.RUnlock()
+
+	// 写入协程可能因强制关闭而退出，此时不会再发出写入完成信号
+	select {
+	case <-c.done:
+	case <-c.close:
+	}
+
+	if !atomic.CompareAndSwapInt32(&c.state, int32(network.ConnHanged), int32(network.ConnClosed)) {
+		return errors.ErrConnectionNotHanged
+	}
+
+	return c.doClose()
+}
+
+// 强制关闭
+func (c *serverConn) forceClose() error {
+	if !atomic.CompareAndSwapInt32(&c.state, int32(network.ConnOpened), int32(network.ConnClosed)) {
+		if !atomic.CompareAndSwapInt32(&c.state, int32(network.ConnHanged), int32(network.ConnClosed)) {
+			return errors.ErrConnectionClosed
+		}
+	}
+
+	return c.doClose()
+}
+
+// 执行关闭
+func (c *serverConn) doClose() error {
+	c.rw.Lock()
+	c.outbound.Close()
+	close(c.close)
+	conn := c.conn
+	c.conn = nil
+	c.rw.Unlock()
+
+	err := conn.Close()
+
+	c.connMgr.recycle(c)
+
+	if c.connMgr.server.disconnectHandler != nil {
+		c.connMgr.server.disconnectHandler(c)
+	}
+
+	return err
+}
+
+// 读取消息
+func (c *serverConn) read() {
+	conn := c.conn
+
+	for {
+		select {
+		case <-c.close:
+			return
+		default:
+			msg, err := packet.ReadMessage(conn)
+			if err != nil {
+				_ = c.forceClose()
+				return
+			}
+
+			if c.connMgr.server.opts.heartbeatInterval > 0 {
+				atomic.StoreInt64(&c.lastHeartbeatTime, xtime.Now().UnixNano())
+			}
+
+			switch c.State() {
+			case network.ConnHanged:
+				continue
+			case network.ConnClosed:
+				return
+			default:
+				// ignore
+			}
+
+			isHeartbeat, err := packet.CheckHeartbeat(msg)
+			if err != nil {
+				log.Errorf("check heartbeat message error: %v", err)
+				continue
+			}
+
+			// responsive heartbeat
+			if isHeartbeat {
+				if c.connMgr.server.opts.heartbeatMechanism == RespHeartbeat {
+					_ = c.signal(heartbeatPacket)
+				}
+				continue
+			}
+
+			// ignore empty packet
+			if len(msg) == 0 {
+				continue
+			}
+
+			if c.connMgr.server.receiveHandler != nil {
+				c.connMgr.server.receiveHandler(c, msg)
+			}
+		}
+	}
+}
+
+// 写入消息
+func (c *serverConn) write() {
+	var (
+		conn   = c.conn
+		opts   = c.connMgr.server.opts
+		ticker *time.Ticker
+	)
+
+	if opts.heartbeatInterval > 0 {
+		ticker = time.NewTicker(opts.heartbeatInterval)
+		defer ticker.Stop()
+	} else {
+		ticker = &time.Ticker{C: make(chan time.Time, 1)}
+	}
+
+	for {
+		select {
+		case <-c.close:
+			return
+		case <-c.outbound.Notify():
+			if !c.flush(conn) {
+				return
+			}
+		case <-ticker.C:
+			deadline := xtime.Now().Add(-2 * opts.heartbeatInterval).UnixNano()
+			if atomic.LoadInt64(&c.lastHeartbeatTime) < deadline {
+				log.Debugf("connection heartbeat timeout, cid: %d", c.id)
+				_ = c.forceClose()
+				return
+			}
+
+			if opts.heartbeatMechanism == TickHeartbeat {
+				c.writeHeartbeat(conn)
+			}
+		}
+	}
+}
+
+// 写出发送队列中的消息，返回是否继续写入
+func (c *serverConn) flush(conn net.Conn) bool {
+	for {
+		msg, sig, ok := c.outbound.Pop()
+		if !ok {
+			return true
+		}
+
+		switch sig {
+		case closeSig:
+			select {
+			case c.done <- struct{}{}:
+			case <-c.close:
+			}
+			return false
+		case heartbeatPacket:
+			c.writeHeartbeat(conn)
+		default:
+			if c.isClosed() {
+				return false
+			}
+
+			if _, err := conn.Write(msg); err != nil {
+				log.Errorf("write data message error: %v", err)
+			}
+		}
+	}
+}
+
+// 写入心跳
+func (c *serverConn) writeHeartbeat(conn net.Conn) {
+	if c.isClosed() {
+		return
+	}
+
+	heartbeat, err := packet.PackHeartbeat()
+	if err != nil {
+		log.Errorf("pack heartbeat message error: %v", err)
+		return
+	}
+
+	if _, err = conn.Write(heartbeat); err != nil {
+		log.Errorf("write heartbeat message error: %v", err)
+	}
+}
+
+// 是否已关闭
+func (c *serverConn) isClosed() bool {
+	return network.ConnState(atomic.LoadInt32(&c.state)) == network.ConnClosed
+}
@@ -0,0 +1,133 @@
+package kcp
+
+import (
+	"gatesvr/errors"
+	"gatesvr/log"
+	"gatesvr/utils/xnet"
+	"github.com/xtaci/kcp-go/v5"
+	"sync"
+	"sync/atomic"
+)
+
+type serverConnMgr struct {
+	id         int64        // 连接ID
+	total      int64        // 总连接数
+	server     *server      // 服务器
+	partitions []*partition // 连接管理
+	convs      sync.Map     // 会话连接（会话ID -> *serverConn）
+}
+
+func newServerConnMgr(server *server) *serverConnMgr {
+	cm := &serverConnMgr{}
+	cm.server = server
+	cm.partitions = make([]*partition, 100)
+
+	for i := 0; i < len(cm.partitions); i++ {
+		cm.partitions[i] = &partition{connections: make(map[int64]*serverConn)}
+	}
+
+	return cm
+}
+
+// 关闭连接
+func (cm *serverConnMgr) close() {
+	for _, p := range cm.partitions {
+		for _, conn := range p.snapshot() {
+			_ = conn.Close(true)
+		}
+	}
+}
+
+// 分配连接
+// 同一远端IP以相同会话ID建立新连接时（如客户端端口变化），视为客户端已重建连接，关闭旧连接
+func (cm *serverConnMgr) allocate(sess *kcp.UDPSession) error {
+	conv := sess.GetConv()
+
+	if v, ok := cm.convs.Load(conv); ok {
+		if old := v.(*serverConn); sameIP(old, sess) {
+			log.Debugf("connection is replaced by the same conversation, cid: %d conv: %d", old.ID(), conv)
+			_ = old.Close(true)
+		}
+	}
+
+	if atomic.AddInt64(&cm.total, 1) > int64(cm.server.opts.maxConnNum) {
+		atomic.AddInt64(&cm.total, -1)
+		return errors.ErrTooManyConnection
+	}
+
+	id := atomic.AddInt64(&cm.id, 1)
+	conn := newServerConn(cm, id, sess)
+	cm.partition(id).store(id, conn)
+	cm.convs.Store(conv, conn)
+	conn.start()
+
+	return nil
+}
+
+// 回收连接
+func (cm *serverConnMgr) recycle(conn *serverConn) {
+	cm.convs.CompareAndDelete(conn.conv, conn)
+
+	if cm.partition(conn.id).delete(conn.id) {
+		atomic.AddInt64(&cm.total, -1)
+	}
+}
+
+// 是否来自同一远端IP
+func sameIP(conn *serverConn, sess *kcp.UDPSession) bool {
+	oldIP, err := conn.RemoteIP()
+	if err != nil {
+		return false
+	}
+
+	newIP, err := xnet.ExtractIP(sess.RemoteAddr())
+	if err != nil {
+		return false
+	}
+
+	return oldIP == newIP
+}
+
+// 获取分片
+func (cm *serverConnMgr) partition(id int64) *partition {
+	return cm.partitions[int(id%int64(len(cm.partitions)))]
+}
+
+type partition struct {
+	rw          sync.RWMutex          // 读写锁
+	connections map[int64]*serverConn // 连接
+}
+
+// 存储连接
+func (p *partition) store(id int64, conn *serverConn) {
+	p.rw.Lock()
+	p.connections[id] = conn
+	p.rw.Unlock()
+}
+
+// 删除连接
+func (p *partition) delete(id int64) bool {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	if _, ok := p.connections[id]; !ok {
+		return false
+	}
+
+	delete(p.connections, id)
+
+	return true
+}
+
+// 连接快照
+func (p *partition) snapshot() []*serverConn {
+	p.rw.RLock()
+	defer p.rw.RUnlock()
+
+	conns := make([]*serverConn, 0, len(p.connections))
+	for _, conn := range p.connections {
+		conns = append(conns, conn)
+	}
+
+	return conns
+}
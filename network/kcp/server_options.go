@@ -0,0 +1,126 @@
+package kcp
+
+import (
+	"gatesvr/etc"
+	"gatesvr/network"
+	"time"
+)
+
+const (
+	defaultServerAddr               = ":3555"
+	defaultServerMaxConnNum         = 5000
+	defaultServerHeartbeatInterval  = "10s"
+	defaultServerHeartbeatMechanism = "resp"
+	defaultServerOutboundMaxBytes   = 4 * 1024 * 1024
+	defaultServerOutboundPolicy     = "disconnect"
+	defaultServerNoDelay            = true
+	defaultServerInterval           = "10ms"
+	defaultServerResend             = 2
+	defaultServerNoCongestion       = true
+	defaultServerSndWnd             = 256
+	defaultServerRcvWnd             = 256
+	defaultServerMTU                = 1400
+)
+
+const (
+	defaultServerAddrKey               = "etc.network.kcp.server.addr"
+	defaultServerMaxConnNumKey         = "etc.network.kcp.server.maxConnNum"
+	defaultServerHeartbeatIntervalKey  = "etc.network.kcp.server.heartbeatInterval"
+	defaultServerHeartbeatMechanismKey = "etc.network.kcp.server.heartbeatMechanism"
+	defaultServerOutboundMaxBytesKey   = "etc.network.kcp.server.outboundMaxBytes"
+	defaultServerOutboundPolicyKey     = "etc.network.kcp.server.outboundPolicy"
+	defaultServerNoDelayKey            = "etc.network.kcp.server.noDelay"
+	defaultServerIntervalKey           = "etc.network.kcp.server.interval"
+	defaultServerResendKey             = "etc.network.kcp.server.resend"
+	defaultServerNoCongestionKey       = "etc.network.kcp.server.noCongestion"
+	defaultServerSndWndKey             = "etc.network.kcp.server.sndWnd"
+	defaultServerRcvWndKey             = "etc.network.kcp.server.rcvWnd"
+	defaultServerMTUKey                = "etc.network.kcp.server.mtu"
+)
+
+const (
+	RespHeartbeat HeartbeatMechanism = "resp" // 响应式心跳
+	TickHeartbeat HeartbeatMechanism = "tick" // 主动定时心跳
+)
+
+type HeartbeatMechanism string
+
+type ServerOption func(o *serverOptions)
+
+type serverOptions struct {
+	addr               string                 // 监听地址，默认0.0.0.0:3555
+	maxConnNum         int                    // 最大连接数，默认5000
+	heartbeatInterval  time.Duration          // 心跳检测间隔时间，默认10s
+	heartbeatMechanism HeartbeatMechanism     // 心跳机制，默认resp
+	outboundMaxBytes   int                    // 单个连接发送队列的最大字节数，默认4MB，为0时不限制
+	outboundPolicy     network.OutboundPolicy // 发送队列已满时的策略，默认disconnect
+	arq                arqOptions             // ARQ配置
+}
+
+func defaultServerOptions() *serverOptions {
+	return &serverOptions{
+		addr:               etc.Get(defaultServerAddrKey, defaultServerAddr).String(),
+		maxConnNum:         etc.Get(defaultServerMaxConnNumKey, defaultServerMaxConnNum).Int(),
+		heartbeatInterval:  etc.Get(defaultServerHeartbeatIntervalKey, defaultServerHeartbeatInterval).Duration(),
+		heartbeatMechanism: HeartbeatMechanism(etc.Get(defaultServerHeartbeatMechanismKey, defaultServerHeartbeatMechanism).String()),
+		outboundMaxBytes:   etc.Get(defaultServerOutboundMaxBytesKey, defaultServerOutboundMaxBytes).Int(),
+		outboundPolicy:     network.OutboundPolicy(etc.Get(defaultServerOutboundPolicyKey, defaultServerOutboundPolicy).String()),
+		arq: arqOptions{
+			noDelay:      etc.Get(defaultServerNoDelayKey, defaultServerNoDelay).Bool(),
+			interval:     etc.Get(defaultServerIntervalKey, defaultServerInterval).Duration(),
+			resend:       etc.Get(defaultServerResendKey, defaultServerResend).Int(),
+			noCongestion: etc.Get(defaultServerNoCongestionKey, defaultServerNoCongestion).Bool(),
+			sndWnd:       etc.Get(defaultServerSndWndKey, defaultServerSndWnd).Int(),
+			rcvWnd:       etc.Get(defaultServerRcvWndKey, defaultServerRcvWnd).Int(),
+			mtu:          etc.Get(defaultServerMTUKey, defaultServerMTU).Int(),
+		},
+	}
+}
+
+// WithServerListenAddr 设置监听地址
+func WithServerListenAddr(addr string) ServerOption {
+	return func(o *serverOptions) { o.addr = addr }
+}
+
+// WithServerMaxConnNum 设置连接的最大连接数
+func WithServerMaxConnNum(maxConnNum int) ServerOption {
+	return func(o *serverOptions) { o.maxConnNum = maxConnNum }
+}
+
+// WithServerHeartbeatInterval 设置心跳检测间隔时间
+func WithServerHeartbeatInterval(heartbeatInterval time.Duration) ServerOption {
+	return func(o *serverOptions) { o.heartbeatInterval = heartbeatInterval }
+}
+
+// WithServerHeartbeatMechanism 设置心跳机制
+func WithServerHeartbeatMechanism(heartbeatMechanism HeartbeatMechanism) ServerOption {
+	return func(o *serverOptions) { o.heartbeatMechanism = heartbeatMechanism }
+}
+
+// WithServerOutboundMaxBytes 设置单个连接发送队列的最大字节数
+func WithServerOutboundMaxBytes(maxBytes int) ServerOption {
+	return func(o *serverOptions) { o.outboundMaxBytes = maxBytes }
+}
+
+// WithServerOutboundPolicy 设置发送队列已满时的策略
+func WithServerOutboundPolicy(policy network.OutboundPolicy) ServerOption {
+	return func(o *serverOptions) { o.outboundPolicy = policy }
+}
+
+// WithServerNoDelay 设置ARQ重传参数
+// noDelay为是否启用nodelay模式，interval为内部刷新间隔时间，resend为快速重传阈值，noCongestion为是否关闭拥塞控制
+func WithServerNoDelay(noDelay bool, interval time.Duration, resend int, noCongestion bool) ServerOption {
+	return func(o *serverOptions) {
+		o.arq.noDelay, o.arq.interval, o.arq.resend, o.arq.noCongestion = noDelay, interval, resend, noCongestion
+	}
+}
+
+// WithServerWindowSize 设置发送及接收窗口大小
+func WithServerWindowSize(sndWnd, rcvWnd int) ServerOption {
+	return func(o *serverOptions) { o.arq.sndWnd, o.arq.rcvWnd = sndWnd, rcvWnd }
+}
+
+// WithServerMTU 设置最大传输单元
+func WithServerMTU(mtu int) ServerOption {
+	return func(o *serverOptions) { o.arq.mtu = mtu }
+}
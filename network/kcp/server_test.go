@@ -0,0 +1,83 @@
+package kcp_test
+
+import (
+	"gatesvr/log"
+	"gatesvr/network"
+	"gatesvr/network/kcp"
+	"gatesvr/packet"
+	"testing"
+	"time"
+)
+
+func TestServer_Echo(t *testing.T) {
+	server := kcp.NewServer(kcp.WithServerListenAddr("127.0.0.1:13555"))
+
+	server.OnStart(func() {
+		log.Info("server is started")
+	})
+
+	server.OnStop(func() {
+		log.Info("server is stopped")
+	})
+
+	server.OnConnect(func(conn network.Conn) {
+		log.Infof("connection is opened, connection id: %d", conn.ID())
+	})
+
+	server.OnDisconnect(func(conn network.Conn) {
+		log.Infof("connection is closed, connection id: %d", conn.ID())
+	})
+
+	server.OnReceive(func(conn network.Conn, msg []byte) {
+		if err := conn.Push(msg); err != nil {
+			log.Errorf("push message failed: %v", err)
+		}
+	})
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("start server failed: %v", err)
+	}
+	defer server.Stop()
+
+	chMsg := make(chan *packet.Message, 1)
+
+	client := kcp.NewClient(kcp.WithClientDialAddr("127.0.0.1:13555"))
+
+	client.OnReceive(func(conn network.Conn, msg []byte) {
+		message, err := packet.UnpackMessage(msg)
+		if err != nil {
+			t.Errorf("unpack message failed: %v", err)
+			return
+		}
+
+		chMsg <- message
+	})
+
+	conn, err := client.Dial()
+	if err != nil {
+		t.Fatalf("client dial failed: %v", err)
+	}
+	defer conn.Close(true)
+
+	msg, err := packet.PackMessage(&packet.Message{
+		Seq:    1,
+		Route:  1,
+		Buffer: []byte("hello server~~"),
+	})
+	if err != nil {
+		t.Fatalf("pack message failed: %v", err)
+	}
+
+	if err = conn.Push(msg); err != nil {
+		t.Fatalf("push message failed: %v", err)
+	}
+
+	select {
+	case message := <-chMsg:
+		if message.Seq != 1 || message.Route != 1 || string(message.Buffer) != "hello server~~" {
+			t.Fatalf("unexpected echo message: %+v", message)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("wait echo message timeout")
+	}
+}
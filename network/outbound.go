@@ -0,0 +1,217 @@
+package network
+
+import (
+	"gatesvr/errors"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	DropOldest     OutboundPolicy = "drop-oldest" // 队列已满时丢弃最早的消息
+	DropNewest     OutboundPolicy = "drop-newest" // 队列已满时丢弃新消息
+	DisconnectSlow OutboundPolicy = "disconnect"  // 队列已满时断开慢速连接
+)
+
+type OutboundPolicy string
+
+// OutboundStater 支持发送队列统计的服务器
+type OutboundStater interface {
+	// OutboundStats 获取发送队列统计
+	OutboundStats() OutboundStats
+}
+
+type OutboundStats struct {
+	DroppedOldest int64 // drop-oldest策略下丢弃的消息数
+	DroppedNewest int64 // drop-newest策略下丢弃的消息数
+	Disconnected  int64 // disconnect策略下断开的连接数
+	DroppedBytes  int64 // 丢弃的消息总字节数
+}
+
+// OutboundMetrics 发送队列统计，同一服务器的所有连接共享
+type OutboundMetrics struct {
+	droppedOldest atomic.Int64
+	droppedNewest atomic.Int64
+	disconnected  atomic.Int64
+	droppedBytes  atomic.Int64
+}
+
+// Stats 获取统计
+func (m *OutboundMetrics) Stats() OutboundStats {
+	return OutboundStats{
+		DroppedOldest: m.droppedOldest.Load(),
+		DroppedNewest: m.droppedNewest.Load(),
+		Disconnected:  m.disconnected.Load(),
+		DroppedBytes:  m.droppedBytes.Load(),
+	}
+}
+
+// Outbound 连接的发送队列
+// 数据消息按字节数限制队列长度，写入永不阻塞；信号（如心跳、关闭）不受长度限制，且在队列中的数据消息全部取出后才被取出
+type Outbound struct {
+	mu       sync.Mutex
+	maxBytes int              // 最大字节数
+	policy   OutboundPolicy   // 队列已满时的策略
+	metrics  *OutboundMetrics // 统计
+	queue    [][]byte         // 数据消息
+	head     int              // 队首位置
+	bytes    int              // 队列中数据消息的字节数
+	signals  []int            // 待处理的信号
+	notify   chan struct{}    // 可读通知
+	closed   bool             // 是否已关闭
+}
+
+func NewOutbound(maxBytes int, policy OutboundPolicy, metrics *OutboundMetrics) *Outbound {
+	if metrics == nil {
+		metrics = &OutboundMetrics{}
+	}
+
+	return &Outbound{
+		maxBytes: maxBytes,
+		policy:   policy,
+		metrics:  metrics,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// Push 写入数据消息
+// 队列已满时，drop-newest策略返回ErrOutboundQueueFull，disconnect策略返回ErrSlowConsumer，由调用方断开连接
+func (q *Outbound) Push(msg []byte) error {
+	q.mu.Lock()
+
+	if q.closed {
+		q.mu.Unlock()
+		return errors.ErrConnectionClosed
+	}
+
+	if q.maxBytes > 0 && q.bytes+len(msg) > q.maxBytes {
+		switch q.policy {
+		case DropOldest:
+			for q.head < len(q.queue) && q.bytes+len(msg) > q.maxBytes {
+				q.metrics.droppedOldest.Add(1)
+				q.metrics.droppedBytes.Add(int64(len(q.queue[q.head])))
+				q.shift()
+			}
+
+			// 单条消息超过最大字节数
+			if q.bytes+len(msg) > q.maxBytes {
+				q.mu.Unlock()
+				q.metrics.droppedOldest.Add(1)
+				q.metrics.droppedBytes.Add(int64(len(msg)))
+				return errors.ErrOutboundQueueFull
+			}
+		case DisconnectSlow:
+			q.mu.Unlock()
+			q.metrics.disconnected.Add(1)
+			q.metrics.droppedBytes.Add(int64(len(msg)))
+			return errors.ErrSlowConsumer
+		default:
+			q.mu.Unlock()
+			q.metrics.droppedNewest.Add(1)
+			q.metrics.droppedBytes.Add(int64(len(msg)))
+			return errors.ErrOutboundQueueFull
+		}
+	}
+
+	q.queue = append(q.queue, msg)
+	q.bytes += len(msg)
+	q.mu.Unlock()
+
+	q.wake()
+
+	return nil
+}
+
+// Signal 写入信号，信号值需大于0，重复的信号将被合并
+func (q *Outbound) Signal(sig int) error {
+	q.mu.Lock()
+
+	if q.closed {
+		q.mu.Unlock()
+		return errors.ErrConnectionClosed
+	}
+
+	for _, s := range q.signals {
+		if s == sig {
+			q.mu.Unlock()
+			return nil
+		}
+	}
+
+	q.signals = append(q.signals, sig)
+	q.mu.Unlock()
+
+	q.wake()
+
+	return nil
+}
+
+// Pop 取出消息，优先取出数据消息；取出数据消息时sig为0，取出信号时msg为nil
+func (q *Outbound) Pop() (msg []byte, sig int, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.head < len(q.queue) {
+		msg = q.queue[q.head]
+		q.shift()
+		return msg, 0, true
+	}
+
+	if len(q.signals) > 0 {
+		sig = q.signals[0]
+		q.signals = q.signals[1:]
+		return nil, sig, true
+	}
+
+	return nil, 0, false
+}
+
+// Notify 可读通知
+func (q *Outbound) Notify() <-chan struct{} {
+	return q.notify
+}
+
+// Bytes 队列中数据消息的字节数
+func (q *Outbound) Bytes() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.bytes
+}
+
+// Close 关闭队列，丢弃未取出的消息
+func (q *Outbound) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.queue = nil
+	q.head = 0
+	q.bytes = 0
+	q.signals = nil
+}
+
+// 移除队首消息，调用方需持有锁
+func (q *Outbound) shift() {
+	q.bytes -= len(q.queue[q.head])
+	q.queue[q.head] = nil
+	q.head++
+
+	// 回收已取出的空间
+	if q.head == len(q.queue) {
+		q.queue = q.queue[:0]
+		q.head = 0
+	} else if q.head >= 1024 && q.head*2 >= len(q.queue) {
+		n := copy(q.queue, q.queue[q.head:])
+		clear(q.queue[n:])
+		q.queue = q.queue[:n]
+		q.head = 0
+	}
+}
+
+// 唤醒读取方
+func (q *Outbound) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
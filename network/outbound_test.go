@@ -0,0 +1,87 @@
+package network_test
+
+import (
+	"gatesvr/errors"
+	"gatesvr/network"
+	"testing"
+)
+
+func TestOutbound_DropOldest(t *testing.T) {
+	metrics := &network.OutboundMetrics{}
+	queue := network.NewOutbound(6, network.DropOldest, metrics)
+
+	for _, msg := range []string{"aa", "bb", "cc", "dd"} {
+		if err := queue.Push([]byte(msg)); err != nil {
+			t.Fatalf("push message failed: %v", err)
+		}
+	}
+
+	for _, want := range []string{"bb", "cc", "dd"} {
+		msg, sig, ok := queue.Pop()
+		if !ok || sig != 0 || string(msg) != want {
+			t.Fatalf("pop message = %q, want %q", msg, want)
+		}
+	}
+
+	if stats := metrics.Stats(); stats.DroppedOldest != 1 || stats.DroppedBytes != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestOutbound_DropNewest(t *testing.T) {
+	metrics := &network.OutboundMetrics{}
+	queue := network.NewOutbound(4, network.DropNewest, metrics)
+
+	_ = queue.Push([]byte("aa"))
+	_ = queue.Push([]byte("bb"))
+
+	if err := queue.Push([]byte("cc")); !errors.Is(err, errors.ErrOutboundQueueFull) {
+		t.Fatalf("push message err = %v, want %v", err, errors.ErrOutboundQueueFull)
+	}
+
+	if stats := metrics.Stats(); stats.DroppedNewest != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestOutbound_Disconnect(t *testing.T) {
+	metrics := &network.OutboundMetrics{}
+	queue := network.NewOutbound(2, network.DisconnectSlow, metrics)
+
+	_ = queue.Push([]byte("aa"))
+
+	if err := queue.Push([]byte("bb")); !errors.Is(err, errors.ErrSlowConsumer) {
+		t.Fatalf("push message err = %v, want %v", err, errors.ErrSlowConsumer)
+	}
+
+	if stats := metrics.Stats(); stats.Disconnected != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestOutbound_Signal(t *testing.T) {
+	queue := network.NewOutbound(0, network.DropNewest, nil)
+
+	_ = queue.Signal(2)
+	_ = queue.Push([]byte("aa"))
+	_ = queue.Signal(2)
+
+	// 数据消息先于信号取出，重复的信号被合并
+	if msg, sig, _ := queue.Pop(); sig != 0 || string(msg) != "aa" {
+		t.Fatalf("pop message = %q sig = %d, want data message", msg, sig)
+	}
+
+	if _, sig, _ := queue.Pop(); sig != 2 {
+		t.Fatalf("pop sig = %d, want 2", sig)
+	}
+
+	if _, _, ok := queue.Pop(); ok {
+		t.Fatal("queue should be empty")
+	}
+
+	queue.Close()
+
+	if err := queue.Push([]byte("aa")); !errors.Is(err, errors.ErrConnectionClosed) {
+		t.Fatalf("push message err = %v, want %v", err, errors.ErrConnectionClosed)
+	}
+}
@@ -0,0 +1,186 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"gatesvr/errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	v1Prefix    = "PROXY "
+	v1MaxLength = 107 // v1头部最大长度（包含CRLF）
+	v2HeaderLen = 16  // v2固定头部长度
+)
+
+const (
+	v2CmdLocal = 0x0 // LOCAL命令，连接由代理自身发起（如健康检查）
+	v2CmdProxy = 0x1 // PROXY命令，连接由代理转发
+)
+
+const (
+	v2FamilyInet  = 0x1 // IPv4
+	v2FamilyInet6 = 0x2 // IPv6
+)
+
+const (
+	v2TransportStream = 0x1 // TCP
+	v2TransportDgram  = 0x2 // UDP
+)
+
+var v2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// ReadHeader 读取PROXY协议头部，返回客户端源地址及代理的目标地址
+// 不存在PROXY协议头部时不消费任何数据，返回的地址均为nil；LOCAL命令或未知协议族同样返回nil地址
+func ReadHeader(r *bufio.Reader) (src, dst net.Addr, err error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch b[0] {
+	case v1Prefix[0]:
+		if b, err = r.Peek(len(v1Prefix)); err != nil {
+			return nil, nil, err
+		}
+		if string(b) != v1Prefix {
+			return nil, nil, nil
+		}
+		return readV1(r)
+	case v2Signature[0]:
+		if b, err = r.Peek(len(v2Signature)); err != nil {
+			return nil, nil, err
+		}
+		if !bytes.Equal(b, v2Signature) {
+			return nil, nil, nil
+		}
+		return readV2(r)
+	default:
+		return nil, nil, nil
+	}
+}
+
+// 读取v1头部，格式为：PROXY TCP4 srcIP dstIP srcPort dstPort\r\n
+func readV1(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	line := make([]byte, 0, v1MaxLength)
+
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line = append(line, c)
+
+		if c == '\n' {
+			break
+		}
+
+		if len(line) >= v1MaxLength {
+			return nil, nil, errors.ErrInvalidProxyHeader
+		}
+	}
+
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, nil, errors.ErrInvalidProxyHeader
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil, nil
+	}
+
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, nil, errors.ErrInvalidProxyHeader
+	}
+
+	srcIP, dstIP := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	if srcIP == nil || dstIP == nil {
+		return nil, nil, errors.ErrInvalidProxyHeader
+	}
+
+	if (fields[1] == "TCP4") != (srcIP.To4() != nil) || (fields[1] == "TCP4") != (dstIP.To4() != nil) {
+		return nil, nil, errors.ErrInvalidProxyHeader
+	}
+
+	srcPort, err := parsePort(fields[4])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dstPort, err := parsePort(fields[5])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &net.TCPAddr{IP: srcIP, Port: srcPort}, &net.TCPAddr{IP: dstIP, Port: dstPort}, nil
+}
+
+// 读取v2头部
+func readV2(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	header := make([]byte, v2HeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, err
+	}
+
+	if header[12]>>4 != 0x2 {
+		return nil, nil, errors.ErrInvalidProxyHeader
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, nil, err
+	}
+
+	switch header[12] & 0x0F {
+	case v2CmdLocal:
+		return nil, nil, nil
+	case v2CmdProxy:
+	default:
+		return nil, nil, errors.ErrInvalidProxyHeader
+	}
+
+	var ipLen int
+	switch header[13] >> 4 {
+	case v2FamilyInet:
+		ipLen = net.IPv4len
+	case v2FamilyInet6:
+		ipLen = net.IPv6len
+	default:
+		// 未知协议族及Unix套接字保留真实地址
+		return nil, nil, nil
+	}
+
+	if len(payload) < 2*ipLen+4 {
+		return nil, nil, errors.ErrInvalidProxyHeader
+	}
+
+	srcIP := net.IP(payload[:ipLen])
+	dstIP := net.IP(payload[ipLen : 2*ipLen])
+	srcPort := int(binary.BigEndian.Uint16(payload[2*ipLen:]))
+	dstPort := int(binary.BigEndian.Uint16(payload[2*ipLen+2:]))
+
+	switch header[13] & 0x0F {
+	case v2TransportStream:
+		return &net.TCPAddr{IP: srcIP, Port: srcPort}, &net.TCPAddr{IP: dstIP, Port: dstPort}, nil
+	case v2TransportDgram:
+		return &net.UDPAddr{IP: srcIP, Port: srcPort}, &net.UDPAddr{IP: dstIP, Port: dstPort}, nil
+	default:
+		return nil, nil, nil
+	}
+}
+
+// 解析端口
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, errors.ErrInvalidProxyHeader
+	}
+
+	return port, nil
+}
@@ -0,0 +1,205 @@
+package proxyproto
+
+import (
+	"bufio"
+	"gatesvr/log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultHeaderTimeout = 5 * time.Second // 默认读取头部超时时间
+
+type listener struct {
+	net.Listener
+	trusted []*net.IPNet  // 受信任的代理地址段
+	timeout time.Duration // 读取头部超时时间
+	conns   chan net.Conn // 已解析头部的连接
+	errs    chan error    // 接收连接错误
+	done    chan struct{} // 关闭信号
+	once    sync.Once
+}
+
+// NewListener 包装监听器，解析受信任来源连接的PROXY协议头部
+// 仅来自trusted地址段的连接会解析头部，头部在独立的协程中读取，不会阻塞连接的接收；timeout为读取头部的超时时间
+func NewListener(ln net.Listener, trusted []string, timeout ...time.Duration) (net.Listener, error) {
+	nets, err := ParseCIDRs(trusted)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &listener{
+		Listener: ln,
+		trusted:  nets,
+		timeout:  defaultHeaderTimeout,
+		conns:    make(chan net.Conn),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+	}
+
+	if len(timeout) > 0 && timeout[0] > 0 {
+		l.timeout = timeout[0]
+	}
+
+	go l.serve()
+
+	return l, nil
+}
+
+// ParseCIDRs 解析地址段，不带掩码的IP视为单个地址
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+// Accept 接收连接
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close 关闭监听器
+func (l *listener) Close() error {
+	l.once.Do(func() { close(l.done) })
+
+	return l.Listener.Close()
+}
+
+// 接收原始连接
+func (l *listener) serve() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			select {
+			case l.errs <- err:
+			case <-l.done:
+				return
+			}
+
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				continue
+			}
+
+			return
+		}
+
+		if !l.isTrusted(conn.RemoteAddr()) {
+			l.deliver(conn)
+			continue
+		}
+
+		go l.handshake(conn)
+	}
+}
+
+// 读取PROXY协议头部
+func (l *listener) handshake(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+
+	_ = conn.SetReadDeadline(time.Now().Add(l.timeout))
+
+	src, dst, err := ReadHeader(reader)
+	if err != nil {
+		log.Warnf("read proxy protocol header failed, remote: %s err: %v", conn.RemoteAddr(), err)
+		_ = conn.Close()
+		return
+	}
+
+	_ = conn.SetReadDeadline(time.Time{})
+
+	l.deliver(&Conn{Conn: conn, reader: reader, src: src, dst: dst})
+}
+
+// 投递连接
+func (l *listener) deliver(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		_ = conn.Close()
+	}
+}
+
+// 是否为受信任的代理地址
+func (l *listener) isTrusted(addr net.Addr) bool {
+	var ip net.IP
+
+	switch v := addr.(type) {
+	case *net.TCPAddr:
+		ip = v.IP
+	case *net.UDPAddr:
+		ip = v.IP
+	default:
+		return false
+	}
+
+	for _, n := range l.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Conn 解析过PROXY协议头部的连接
+type Conn struct {
+	net.Conn
+	reader *bufio.Reader // 读取头部时缓存的数据
+	src    net.Addr      // 客户端源地址
+	dst    net.Addr      // 代理的目标地址
+}
+
+// Read 读取数据，优先读取解析头部时缓存的数据
+func (c *Conn) Read(b []byte) (int, error) {
+	if c.reader != nil {
+		if c.reader.Buffered() > 0 {
+			return c.reader.Read(b)
+		}
+		c.reader = nil
+	}
+
+	return c.Conn.Read(b)
+}
+
+// RemoteAddr 获取客户端源地址，头部未携带地址时返回真实地址
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.src != nil {
+		return c.src
+	}
+
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr 获取代理的目标地址，头部未携带地址时返回真实地址
+func (c *Conn) LocalAddr() net.Addr {
+	if c.dst != nil {
+		return c.dst
+	}
+
+	return c.Conn.LocalAddr()
+}
@@ -0,0 +1,113 @@
+package proxyproto_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"gatesvr/network/proxyproto"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestReadHeader_V1(t *testing.T) {
+	r := bufio.NewReader(bytes.NewBufferString("PROXY TCP4 203.0.113.7 10.0.0.1 51234 3553\r\nhello"))
+
+	src, dst, err := proxyproto.ReadHeader(r)
+	if err != nil {
+		t.Fatalf("read header failed: %v", err)
+	}
+
+	if src.String() != "203.0.113.7:51234" || dst.String() != "10.0.0.1:3553" {
+		t.Fatalf("unexpected addr, src: %v dst: %v", src, dst)
+	}
+
+	if rest, _ := io.ReadAll(r); string(rest) != "hello" {
+		t.Fatalf("unexpected payload: %q", rest)
+	}
+}
+
+func TestReadHeader_V2(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(append(v2Header(net.ParseIP("2001:db8::1"), 51234), "hello"...)))
+
+	src, _, err := proxyproto.ReadHeader(r)
+	if err != nil {
+		t.Fatalf("read header failed: %v", err)
+	}
+
+	if src.String() != "[2001:db8::1]:51234" {
+		t.Fatalf("unexpected src addr: %v", src)
+	}
+
+	if rest, _ := io.ReadAll(r); string(rest) != "hello" {
+		t.Fatalf("unexpected payload: %q", rest)
+	}
+}
+
+func TestReadHeader_Absent(t *testing.T) {
+	r := bufio.NewReader(bytes.NewBufferString("hello"))
+
+	src, dst, err := proxyproto.ReadHeader(r)
+	if err != nil || src != nil || dst != nil {
+		t.Fatalf("unexpected result, src: %v dst: %v err: %v", src, dst, err)
+	}
+
+	if rest, _ := io.ReadAll(r); string(rest) != "hello" {
+		t.Fatalf("unexpected payload: %q", rest)
+	}
+}
+
+func TestListener_Trusted(t *testing.T) {
+	if addr := accept(t, []string{"127.0.0.1"}, "PROXY TCP4 203.0.113.7 127.0.0.1 51234 3553\r\n"); addr != "203.0.113.7:51234" {
+		t.Fatalf("unexpected remote addr: %s", addr)
+	}
+}
+
+func TestListener_Untrusted(t *testing.T) {
+	if addr := accept(t, []string{"10.0.0.0/8"}, "PROXY TCP4 203.0.113.7 127.0.0.1 51234 3553\r\n"); addr == "203.0.113.7:51234" {
+		t.Fatal("the header from untrusted source should be ignored")
+	}
+}
+
+// 建立连接并发送数据，返回服务端连接的远端地址
+func accept(t *testing.T, trusted []string, header string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+
+	pln, err := proxyproto.NewListener(ln, trusted)
+	if err != nil {
+		t.Fatalf("create listener failed: %v", err)
+	}
+	defer pln.Close()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+
+	if _, err = client.Write([]byte(header + "hello")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	conn, err := pln.Accept()
+	if err != nil {
+		t.Fatalf("accept failed: %v", err)
+	}
+	defer conn.Close()
+
+	return conn.RemoteAddr().String()
+}
+
+// 构建v2头部
+func v2Header(ip net.IP, port uint16) []byte {
+	buf := []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A, 0x21, 0x21, 0, 36}
+	buf = append(buf, ip.To16()...)
+	buf = append(buf, net.IPv6loopback...)
+	buf = binary.BigEndian.AppendUint16(buf, port)
+	buf = binary.BigEndian.AppendUint16(buf, 3553)
+
+	return buf
+}
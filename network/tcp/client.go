@@ -1,6 +1,7 @@
 package tcp
 
 import (
+	"crypto/tls"
 	"gatesvr/network"
 	"net"
 	"sync/atomic"
@@ -39,7 +40,13 @@ func (c *client) Dial(addr ...string) (network.Conn, error) {
 		return nil, err
 	}
 
-	conn, err := net.DialTimeout(tcpAddr.Network(), tcpAddr.String(), c.opts.timeout)
+	var conn net.Conn
+	if c.opts.tlsConfig != nil {
+		dialer := &net.Dialer{Timeout: c.opts.timeout}
+		conn, err = tls.DialWithDialer(dialer, tcpAddr.Network(), tcpAddr.String(), c.opts.tlsConfig)
+	} else {
+		conn, err = net.DialTimeout(tcpAddr.Network(), tcpAddr.String(), c.opts.timeout)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package tcp
 
 import (
+	"crypto/tls"
 	"gatesvr/etc"
 	"time"
 )
@@ -23,6 +24,7 @@ type clientOptions struct {
 	addr              string        // 地址
 	timeout           time.Duration // 拨号超时时间，默认5s
 	heartbeatInterval time.Duration // 心跳间隔时间，默认10s
+	tlsConfig         *tls.Config   // TLS配置，配置后使用TLS拨号
 }
 
 func defaultClientOptions() *clientOptions {
@@ -47,3 +49,8 @@ func WithClientDialTimeout(timeout time.Duration) ClientOption {
 func WithClientHeartbeatInterval(heartbeatInterval time.Duration) ClientOption {
 	return func(o *clientOptions) { o.heartbeatInterval = heartbeatInterval }
 }
+
+// WithClientTLSConfig 设置TLS配置
+func WithClientTLSConfig(tlsConfig *tls.Config) ClientOption {
+	return func(o *clientOptions) { o.tlsConfig = tlsConfig }
+}
@@ -0,0 +1,14 @@
+package tcp
+
+const protocol = "tcp"
+
+const (
+	dataPacket      = iota // 数据包
+	heartbeatPacket        // 心跳包
+	closeSig               // 关闭信号
+)
+
+type chWrite struct {
+	typ int    // 写入类型
+	msg []byte // 写入消息
+}
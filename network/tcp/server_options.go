@@ -0,0 +1,120 @@
+package tcp
+
+import (
+	"gatesvr/etc"
+	"gatesvr/network"
+	"time"
+)
+
+const (
+	defaultServerAddr               = ":3553"
+	defaultServerMaxConnNum         = 5000
+	defaultServerHeartbeatInterval  = "10s"
+	defaultServerHeartbeatMechanism = "resp"
+	defaultServerOutboundMaxBytes   = 4 * 1024 * 1024
+	defaultServerOutboundPolicy     = "disconnect"
+)
+
+const (
+	defaultServerAddrKey               = "etc.network.tcp.server.addr"
+	defaultServerMaxConnNumKey         = "etc.network.tcp.server.maxConnNum"
+	defaultServerHeartbeatIntervalKey  = "etc.network.tcp.server.heartbeatInterval"
+	defaultServerHeartbeatMechanismKey = "etc.network.tcp.server.heartbeatMechanism"
+	defaultServerCertFileKey           = "etc.network.tcp.server.certFile"
+	defaultServerKeyFileKey            = "etc.network.tcp.server.keyFile"
+	defaultServerCAFileKey             = "etc.network.tcp.server.caFile"
+	defaultServerOutboundMaxBytesKey   = "etc.network.tcp.server.outboundMaxBytes"
+	defaultServerOutboundPolicyKey     = "etc.network.tcp.server.outboundPolicy"
+	defaultServerProxyTrustedKey       = "etc.network.tcp.server.proxyTrusted"
+	defaultServerProxyHeaderTimeoutKey = "etc.network.tcp.server.proxyHeaderTimeout"
+)
+
+const (
+	RespHeartbeat HeartbeatMechanism = "resp" // 响应式心跳
+	TickHeartbeat HeartbeatMechanism = "tick" // 主动定时心跳
+)
+
+type HeartbeatMechanism string
+
+type ServerOption func(o *serverOptions)
+
+type serverOptions struct {
+	addr               string                 // 监听地址，默认0.0.0.0:3553
+	maxConnNum         int                    // 最大连接数，默认5000
+	heartbeatInterval  time.Duration          // 心跳检测间隔时间，默认10s
+	heartbeatMechanism HeartbeatMechanism     // 心跳机制，默认resp
+	certFile           string                 // 证书文件，配置后启用TLS
+	keyFile            string                 // 秘钥文件
+	caFile             string                 // CA证书文件，配置后要求客户端提供证书（双向认证）
+	outboundMaxBytes   int                    // 单个连接发送队列的最大字节数，默认4MB，为0时不限制
+	outboundPolicy     network.OutboundPolicy // 发送队列已满时的策略，默认disconnect
+	proxyTrusted       []string               // 受信任的代理地址段，配置后解析来自这些地址的连接的PROXY协议头部
+	proxyHeaderTimeout time.Duration          // 读取PROXY协议头部的超时时间，默认5s
+}
+
+func defaultServerOptions() *serverOptions {
+	return &serverOptions{
+		addr:               etc.Get(defaultServerAddrKey, defaultServerAddr).String(),
+		maxConnNum:         etc.Get(defaultServerMaxConnNumKey, defaultServerMaxConnNum).Int(),
+		heartbeatInterval:  etc.Get(defaultServerHeartbeatIntervalKey, defaultServerHeartbeatInterval).Duration(),
+		heartbeatMechanism: HeartbeatMechanism(etc.Get(defaultServerHeartbeatMechanismKey, defaultServerHeartbeatMechanism).String()),
+		certFile:           etc.Get(defaultServerCertFileKey).String(),
+		keyFile:            etc.Get(defaultServerKeyFileKey).String(),
+		caFile:             etc.Get(defaultServerCAFileKey).String(),
+		outboundMaxBytes:   etc.Get(defaultServerOutboundMaxBytesKey, defaultServerOutboundMaxBytes).Int(),
+		outboundPolicy:     network.OutboundPolicy(etc.Get(defaultServerOutboundPolicyKey, defaultServerOutboundPolicy).String()),
+		proxyTrusted:       etc.Get(defaultServerProxyTrustedKey).Strings(),
+		proxyHeaderTimeout: etc.Get(defaultServerProxyHeaderTimeoutKey).Duration(),
+	}
+}
+
+// WithServerListenAddr 设置监听地址
+func WithServerListenAddr(addr string) ServerOption {
+	return func(o *serverOptions) { o.addr = addr }
+}
+
+// WithServerMaxConnNum 设置连接的最大连接数
+func WithServerMaxConnNum(maxConnNum int) ServerOption {
+	return func(o *serverOptions) { o.maxConnNum = maxConnNum }
+}
+
+// WithServerHeartbeatInterval 设置心跳检测间隔时间
+func WithServerHeartbeatInterval(heartbeatInterval time.Duration) ServerOption {
+	return func(o *serverOptions) { o.heartbeatInterval = heartbeatInterval }
+}
+
+// WithServerHeartbeatMechanism 设置心跳机制
+func WithServerHeartbeatMechanism(heartbeatMechanism HeartbeatMechanism) ServerOption {
+	return func(o *serverOptions) { o.heartbeatMechanism = heartbeatMechanism }
+}
+
+// WithServerCredentials 设置证书和秘钥，启用TLS
+func WithServerCredentials(certFile, keyFile string) ServerOption {
+	return func(o *serverOptions) { o.certFile, o.keyFile = certFile, keyFile }
+}
+
+// WithServerClientCAFile 设置客户端CA证书，启用双向认证
+func WithServerClientCAFile(caFile string) ServerOption {
+	return func(o *serverOptions) { o.caFile = caFile }
+}
+
+// WithServerOutboundMaxBytes 设置单个连接发送队列的最大字节数
+func WithServerOutboundMaxBytes(maxBytes int) ServerOption {
+	return func(o *serverOptions) { o.outboundMaxBytes = maxBytes }
+}
+
+// WithServerOutboundPolicy 设置发送队列已满时的策略
+func WithServerOutboundPolicy(policy network.OutboundPolicy) ServerOption {
+	return func(o *serverOptions) { o.outboundPolicy = policy }
+}
+
+// WithServerProxyProtocol 设置受信任的代理地址段，启用PROXY协议（v1/v2）
+// 来自受信任地址的连接的远端地址将被替换为PROXY协议头部中的客户端地址，如：10.0.0.0/8、192.168.1.10
+func WithServerProxyProtocol(trusted ...string) ServerOption {
+	return func(o *serverOptions) { o.proxyTrusted = trusted }
+}
+
+// WithServerProxyHeaderTimeout 设置读取PROXY协议头部的超时时间
+func WithServerProxyHeaderTimeout(timeout time.Duration) ServerOption {
+	return func(o *serverOptions) { o.proxyHeaderTimeout = timeout }
+}
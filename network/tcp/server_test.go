@@ -0,0 +1,129 @@
+package tcp_test
+
+import (
+	"gatesvr/log"
+	"gatesvr/network"
+	"gatesvr/network/tcp"
+	"gatesvr/packet"
+	"testing"
+	"time"
+)
+
+func TestServer_Echo(t *testing.T) {
+	server := tcp.NewServer(tcp.WithServerListenAddr("127.0.0.1:13553"))
+
+	server.OnStart(func() {
+		log.Info("server is started")
+	})
+
+	server.OnStop(func() {
+		log.Info("server is stopped")
+	})
+
+	server.OnConnect(func(conn network.Conn) {
+		log.Infof("connection is opened, connection id: %d", conn.ID())
+	})
+
+	server.OnDisconnect(func(conn network.Conn) {
+		log.Infof("connection is closed, connection id: %d", conn.ID())
+	})
+
+	server.OnReceive(func(conn network.Conn, msg []byte) {
+		if err := conn.Push(msg); err != nil {
+			log.Errorf("push message failed: %v", err)
+		}
+	})
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("start server failed: %v", err)
+	}
+	defer server.Stop()
+
+	chMsg := make(chan *packet.Message, 1)
+
+	client := tcp.NewClient(tcp.WithClientDialAddr("127.0.0.1:13553"))
+
+	client.OnReceive(func(conn network.Conn, msg []byte) {
+		message, err := packet.UnpackMessage(msg)
+		if err != nil {
+			t.Errorf("unpack message failed: %v", err)
+			return
+		}
+
+		chMsg <- message
+	})
+
+	conn, err := client.Dial()
+	if err != nil {
+		t.Fatalf("client dial failed: %v", err)
+	}
+	defer conn.Close(true)
+
+	msg, err := packet.PackMessage(&packet.Message{
+		Seq:    1,
+		Route:  1,
+		Buffer: []byte("hello server~~"),
+	})
+	if err != nil {
+		t.Fatalf("pack message failed: %v", err)
+	}
+
+	if err = conn.Push(msg); err != nil {
+		t.Fatalf("push message failed: %v", err)
+	}
+
+	select {
+	case message := <-chMsg:
+		if message.Seq != 1 || message.Route != 1 || string(message.Buffer) != "hello server~~" {
+			t.Fatalf("unexpected echo message: %+v", message)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("wait echo message timeout")
+	}
+}
+
+func TestServer_MaxConnNum(t *testing.T) {
+	server := tcp.NewServer(
+		tcp.WithServerListenAddr("127.0.0.1:13554"),
+		tcp.WithServerMaxConnNum(1),
+	)
+
+	chClosed := make(chan struct{}, 1)
+
+	server.OnDisconnect(func(conn network.Conn) {
+		chClosed <- struct{}{}
+	})
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("start server failed: %v", err)
+	}
+	defer server.Stop()
+
+	client := tcp.NewClient(tcp.WithClientDialAddr("127.0.0.1:13554"), tcp.WithClientHeartbeatInterval(0))
+
+	chRefused := make(chan struct{}, 1)
+
+	client.OnDisconnect(func(conn network.Conn) {
+		chRefused <- struct{}{}
+	})
+
+	conn1, err := client.Dial()
+	if err != nil {
+		t.Fatalf("client dial failed: %v", err)
+	}
+	defer conn1.Close(true)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err = client.Dial(); err != nil {
+		t.Fatalf("client dial failed: %v", err)
+	}
+
+	select {
+	case <-chRefused:
+	case <-chClosed:
+		t.Fatal("the accepted connection should not be closed")
+	case <-time.After(3 * time.Second):
+		t.Fatal("the connection exceeding max connection num should be refused")
+	}
+}
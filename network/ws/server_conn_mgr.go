@@ -0,0 +1,107 @@
+package ws
+
+import (
+	"gatesvr/errors"
+	"github.com/gorilla/websocket"
+	"sync"
+	"sync/atomic"
+)
+
+type serverConnMgr struct {
+	id         int64        // 连接ID
+	total      int64        // 总连接数
+	server     *server      // 服务器
+	partitions []*partition // 连接管理
+}
+
+func newServerConnMgr(server *server) *serverConnMgr {
+	cm := &serverConnMgr{}
+	cm.server = server
+	cm.partitions = make([]*partition, 100)
+
+	for i := 0; i < len(cm.partitions); i++ {
+		cm.partitions[i] = &partition{connections: make(map[int64]*serverConn)}
+	}
+
+	return cm
+}
+
+// 关闭连接
+func (cm *serverConnMgr) close() {
+	for _, p := range cm.partitions {
+		for _, conn := range p.snapshot() {
+			_ = conn.Close(true)
+		}
+	}
+}
+
+// 是否已达到最大连接数
+func (cm *serverConnMgr) isFull() bool {
+	return atomic.LoadInt64(&cm.total) >= int64(cm.server.opts.maxConnNum)
+}
+
+// 分配连接
+func (cm *serverConnMgr) allocate(c *websocket.Conn) error {
+	if atomic.AddInt64(&cm.total, 1) > int64(cm.server.opts.maxConnNum) {
+		atomic.AddInt64(&cm.total, -1)
+		return errors.ErrTooManyConnection
+	}
+
+	id := atomic.AddInt64(&cm.id, 1)
+	conn := newServerConn(cm, id, c)
+	cm.partition(id).store(id, conn)
+	conn.start()
+
+	return nil
+}
+
+// 回收连接
+func (cm *serverConnMgr) recycle(id int64) {
+	if cm.partition(id).delete(id) {
+		atomic.AddInt64(&cm.total, -1)
+	}
+}
+
+// 获取分片
+func (cm *serverConnMgr) partition(id int64) *partition {
+	return cm.partitions[int(id%int64(len(cm.partitions)))]
+}
+
+type partition struct {
+	rw          sync.RWMutex          // 读写锁
+	connections map[int64]*serverConn // 连接
+}
+
+// 存储连接
+func (p *partition) store(id int64, conn *serverConn) {
+	p.rw.Lock()
+	p.connections[id] = conn
+	p.rw.Unlock()
+}
+
+// 删除连接
+func (p *partition) delete(id int64) bool {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	if _, ok := p.connections[id]; !ok {
+		return false
+	}
+
+	delete(p.connections, id)
+
+	return true
+}
+
+// 连接快照
+func (p *partition) snapshot() []*serverConn {
+	p.rw.RLock()
+	defer p.rw.RUnlock()
+
+	conns := make([]*serverConn, 0, len(p.connections))
+	for _, conn := range p.connections {
+		conns = append(conns, conn)
+	}
+
+	return conns
+}
@@ -0,0 +1,196 @@
+package ws
+
+import (
+	"gatesvr/etc"
+	"gatesvr/network"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultServerAddr               = ":3554"
+	defaultServerPath               = "/"
+	defaultServerMaxConnNum         = 5000
+	defaultServerOrigin             = "*"
+	defaultServerHandshakeTimeout   = "10s"
+	defaultServerHeartbeatInterval  = "10s"
+	defaultServerHeartbeatMechanism = "resp"
+	defaultServerOutboundMaxBytes   = 4 * 1024 * 1024
+	defaultServerOutboundPolicy     = "disconnect"
+)
+
+const (
+	defaultServerAddrKey               = "etc.network.ws.server.addr"
+	defaultServerPathKey               = "etc.network.ws.server.path"
+	defaultServerMaxConnNumKey         = "etc.network.ws.server.maxConnNum"
+	defaultServerOriginsKey            = "etc.network.ws.server.origins"
+	defaultServerHandshakeTimeoutKey   = "etc.network.ws.server.handshakeTimeout"
+	defaultServerHeartbeatIntervalKey  = "etc.network.ws.server.heartbeatInterval"
+	defaultServerHeartbeatMechanismKey = "etc.network.ws.server.heartbeatMechanism"
+	defaultServerCertFileKey           = "etc.network.ws.server.certFile"
+	defaultServerKeyFileKey            = "etc.network.ws.server.keyFile"
+	defaultServerCAFileKey             = "etc.network.ws.server.caFile"
+	defaultServerOutboundMaxBytesKey   = "etc.network.ws.server.outboundMaxBytes"
+	defaultServerOutboundPolicyKey     = "etc.network.ws.server.outboundPolicy"
+	defaultServerProxyTrustedKey       = "etc.network.ws.server.proxyTrusted"
+	defaultServerProxyHeaderTimeoutKey = "etc.network.ws.server.proxyHeaderTimeout"
+)
+
+const (
+	RespHeartbeat HeartbeatMechanism = "resp" // 响应式心跳
+	TickHeartbeat HeartbeatMechanism = "tick" // 主动定时心跳
+)
+
+type HeartbeatMechanism string
+
+type CheckOriginFunc func(r *http.Request) bool
+
+type ServerOption func(o *serverOptions)
+
+type serverOptions struct {
+	addr               string                 // 监听地址，默认0.0.0.0:3554
+	path               string                 // 路由路径，默认为"/"
+	maxConnNum         int                    // 最大连接数，默认5000
+	checkOrigin        CheckOriginFunc        // 跨域检测
+	handshakeTimeout   time.Duration          // 握手超时时间，默认10s
+	heartbeatInterval  time.Duration          // 心跳检测间隔时间，默认10s
+	heartbeatMechanism HeartbeatMechanism     // 心跳机制，默认resp
+	certFile           string                 // 证书文件，配置后启用TLS（wss）
+	keyFile            string                 // 秘钥文件
+	caFile             string                 // CA证书文件，配置后要求客户端提供证书（双向认证）
+	outboundMaxBytes   int                    // 单个连接发送队列的最大字节数，默认4MB，为0时不限制
+	outboundPolicy     network.OutboundPolicy // 发送队列已满时的策略，默认disconnect
+	proxyTrusted       []string               // 受信任的代理地址段，配置后解析来自这些地址的连接的PROXY协议头部
+	proxyHeaderTimeout time.Duration          // 读取PROXY协议头部的超时时间，默认5s
+}
+
+func defaultServerOptions() *serverOptions {
+	origins := etc.Get(defaultServerOriginsKey, []string{defaultServerOrigin}).Strings()
+
+	return &serverOptions{
+		addr:               etc.Get(defaultServerAddrKey, defaultServerAddr).String(),
+		path:               etc.Get(defaultServerPathKey, defaultServerPath).String(),
+		maxConnNum:         etc.Get(defaultServerMaxConnNumKey, defaultServerMaxConnNum).Int(),
+		checkOrigin:        newCheckOrigin(origins...),
+		handshakeTimeout:   etc.Get(defaultServerHandshakeTimeoutKey, defaultServerHandshakeTimeout).Duration(),
+		heartbeatInterval:  etc.Get(defaultServerHeartbeatIntervalKey, defaultServerHeartbeatInterval).Duration(),
+		heartbeatMechanism: HeartbeatMechanism(etc.Get(defaultServerHeartbeatMechanismKey, defaultServerHeartbeatMechanism).String()),
+		certFile:           etc.Get(defaultServerCertFileKey).String(),
+		keyFile:            etc.Get(defaultServerKeyFileKey).String(),
+		caFile:             etc.Get(defaultServerCAFileKey).String(),
+		outboundMaxBytes:   etc.Get(defaultServerOutboundMaxBytesKey, defaultServerOutboundMaxBytes).Int(),
+		outboundPolicy:     network.OutboundPolicy(etc.Get(defaultServerOutboundPolicyKey, defaultServerOutboundPolicy).String()),
+		proxyTrusted:       etc.Get(defaultServerProxyTrustedKey).Strings(),
+		proxyHeaderTimeout: etc.Get(defaultServerProxyHeaderTimeoutKey).Duration(),
+	}
+}
+
+// WithServerListenAddr 设置监听地址
+func WithServerListenAddr(addr string) ServerOption {
+	return func(o *serverOptions) { o.addr = addr }
+}
+
+// WithServerPath 设置Websocket的连接路径
+func WithServerPath(path string) ServerOption {
+	return func(o *serverOptions) { o.path = path }
+}
+
+// WithServerMaxConnNum 设置连接的最大连接数
+func WithServerMaxConnNum(maxConnNum int) ServerOption {
+	return func(o *serverOptions) { o.maxConnNum = maxConnNum }
+}
+
+// WithServerAllowOrigins 设置允许跨域的来源，"*"表示允许所有来源
+func WithServerAllowOrigins(origins ...string) ServerOption {
+	return func(o *serverOptions) { o.checkOrigin = newCheckOrigin(origins...) }
+}
+
+// WithServerCheckOrigin 设置Websocket跨域检测函数
+func WithServerCheckOrigin(checkOrigin CheckOriginFunc) ServerOption {
+	return func(o *serverOptions) { o.checkOrigin = checkOrigin }
+}
+
+// WithServerHandshakeTimeout 设置握手超时时间
+func WithServerHandshakeTimeout(handshakeTimeout time.Duration) ServerOption {
+	return func(o *serverOptions) { o.handshakeTimeout = handshakeTimeout }
+}
+
+// WithServerHeartbeatInterval 设置心跳检测间隔时间
+func WithServerHeartbeatInterval(heartbeatInterval time.Duration) ServerOption {
+	return func(o *serverOptions) { o.heartbeatInterval = heartbeatInterval }
+}
+
+// WithServerHeartbeatMechanism 设置心跳机制
+func WithServerHeartbeatMechanism(heartbeatMechanism HeartbeatMechanism) ServerOption {
+	return func(o *serverOptions) { o.heartbeatMechanism = heartbeatMechanism }
+}
+
+// WithServerCredentials 设置证书和秘钥，启用TLS（wss）
+func WithServerCredentials(certFile, keyFile string) ServerOption {
+	return func(o *serverOptions) { o.certFile, o.keyFile = certFile, keyFile }
+}
+
+// WithServerClientCAFile 设置客户端CA证书，启用双向认证
+func WithServerClientCAFile(caFile string) ServerOption {
+	return func(o *serverOptions) { o.caFile = caFile }
+}
+
+// WithServerOutboundMaxBytes 设置单个连接发送队列的最大字节数
+func WithServerOutboundMaxBytes(maxBytes int) ServerOption {
+	return func(o *serverOptions) { o.outboundMaxBytes = maxBytes }
+}
+
+// WithServerOutboundPolicy 设置发送队列已满时的策略
+func WithServerOutboundPolicy(policy network.OutboundPolicy) ServerOption {
+	return func(o *serverOptions) { o.outboundPolicy = policy }
+}
+
+// 根据允许的来源列表构建跨域检测函数
+// 来源可填写完整来源（如：https://example.com）或主机名（如：example.com）
+func newCheckOrigin(origins ...string) CheckOriginFunc {
+	allows := make(map[string]struct{}, len(origins))
+	for _, origin := range origins {
+		if origin = strings.ToLower(strings.TrimSpace(origin)); origin == "" {
+			continue
+		}
+
+		if origin == "*" {
+			return func(r *http.Request) bool { return true }
+		}
+
+		allows[strings.TrimSuffix(origin, "/")] = struct{}{}
+	}
+
+	return func(r *http.Request) bool {
+		origin := strings.ToLower(r.Header.Get("Origin"))
+		if origin == "" {
+			return true
+		}
+
+		if _, ok := allows[origin]; ok {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+
+		_, ok := allows[u.Host]
+
+		return ok
+	}
+}
+
+// WithServerProxyProtocol 设置受信任的代理地址段，启用PROXY协议（v1/v2）
+// 来自受信任地址的连接的远端地址将被替换为PROXY协议头部中的客户端地址，如：10.0.0.0/8、192.168.1.10
+func WithServerProxyProtocol(trusted ...string) ServerOption {
+	return func(o *serverOptions) { o.proxyTrusted = trusted }
+}
+
+// WithServerProxyHeaderTimeout 设置读取PROXY协议头部的超时时间
+func WithServerProxyHeaderTimeout(timeout time.Duration) ServerOption {
+	return func(o *serverOptions) { o.proxyHeaderTimeout = timeout }
+}
@@ -0,0 +1,139 @@
+package ws_test
+
+import (
+	"gatesvr/log"
+	"gatesvr/network"
+	"gatesvr/network/ws"
+	"gatesvr/packet"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServer_Echo(t *testing.T) {
+	server := ws.NewServer(ws.WithServerListenAddr("127.0.0.1:13555"))
+
+	server.OnStart(func() {
+		log.Info("server is started")
+	})
+
+	server.OnStop(func() {
+		log.Info("server is stopped")
+	})
+
+	server.OnConnect(func(conn network.Conn) {
+		log.Infof("connection is opened, connection id: %d", conn.ID())
+	})
+
+	server.OnDisconnect(func(conn network.Conn) {
+		log.Infof("connection is closed, connection id: %d", conn.ID())
+	})
+
+	server.OnReceive(func(conn network.Conn, msg []byte) {
+		if err := conn.Push(msg); err != nil {
+			log.Errorf("push message failed: %v", err)
+		}
+	})
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("start server failed: %v", err)
+	}
+	defer server.Stop()
+
+	chMsg := make(chan *packet.Message, 1)
+
+	client := ws.NewClient(ws.WithClientDialUrl("ws://127.0.0.1:13555"))
+
+	client.OnReceive(func(conn network.Conn, msg []byte) {
+		message, err := packet.UnpackMessage(msg)
+		if err != nil {
+			t.Errorf("unpack message failed: %v", err)
+			return
+		}
+
+		chMsg <- message
+	})
+
+	conn, err := client.Dial()
+	if err != nil {
+		t.Fatalf("client dial failed: %v", err)
+	}
+	defer conn.Close(true)
+
+	msg, err := packet.PackMessage(&packet.Message{
+		Seq:    1,
+		Route:  1,
+		Buffer: []byte("hello server~~"),
+	})
+	if err != nil {
+		t.Fatalf("pack message failed: %v", err)
+	}
+
+	if err = conn.Push(msg); err != nil {
+		t.Fatalf("push message failed: %v", err)
+	}
+
+	select {
+	case message := <-chMsg:
+		if message.Seq != 1 || message.Route != 1 || string(message.Buffer) != "hello server~~" {
+			t.Fatalf("unexpected echo message: %+v", message)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("wait echo message timeout")
+	}
+}
+
+func TestServer_MaxConnNum(t *testing.T) {
+	server := ws.NewServer(
+		ws.WithServerListenAddr("127.0.0.1:13556"),
+		ws.WithServerMaxConnNum(1),
+	)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("start server failed: %v", err)
+	}
+	defer server.Stop()
+
+	client := ws.NewClient(ws.WithClientDialUrl("ws://127.0.0.1:13556"), ws.WithClientHeartbeatInterval(0))
+
+	conn, err := client.Dial()
+	if err != nil {
+		t.Fatalf("client dial failed: %v", err)
+	}
+	defer conn.Close(true)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err = client.Dial(); err == nil {
+		t.Fatal("the connection exceeding max connection num should be refused")
+	}
+}
+
+func TestServer_CheckOrigin(t *testing.T) {
+	server := ws.NewServer(
+		ws.WithServerListenAddr("127.0.0.1:13557"),
+		ws.WithServerPath("/game"),
+		ws.WithServerAllowOrigins("https://h5.example.com"),
+	)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("start server failed: %v", err)
+	}
+	defer server.Stop()
+
+	header := http.Header{}
+	header.Set("Origin", "https://h5.example.com")
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://127.0.0.1:13557/game", header)
+	if err != nil {
+		t.Fatalf("dial with allowed origin failed: %v", err)
+	}
+	_ = conn.Close()
+
+	header.Set("Origin", "https://evil.example.com")
+
+	if _, _, err = websocket.DefaultDialer.Dial("ws://127.0.0.1:13557/game", header); err == nil {
+		t.Fatal("dial with disallowed origin should be refused")
+	}
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"gatesvr/core/value"
+	"gatesvr/errors"
+)
+
+// SetAttr 设置会话属性，value为空时删除该属性
+// 属性以连接维度维护，连接断开后自动清除
+func (s *Session) SetAttr(kind Kind, target int64, key, value string) error {
+	if key == "" {
+		return errors.ErrInvalidArgument
+	}
+
+	conn, err := s.conn(kind, target)
+	if err != nil {
+		return err
+	}
+
+	cid := conn.ID()
+	cs := s.connShard(cid)
+	cs.rw.Lock()
+	defer cs.rw.Unlock()
+
+	// 连接已被移除时不再设置属性
+	if _, ok := cs.conns[cid]; !ok {
+		return errors.ErrNotFoundSession
+	}
+
+	if value == "" {
+		if attrs, ok := cs.attrs[cid]; ok {
+			delete(attrs, key)
+
+			if len(attrs) == 0 {
+				delete(cs.attrs, cid)
+			}
+		}
+		return nil
+	}
+
+	attrs, ok := cs.attrs[cid]
+	if !ok {
+		attrs = make(map[string]string)
+		cs.attrs[cid] = attrs
+	}
+	attrs[key] = value
+
+	return nil
+}
+
+// GetAttr 获取会话属性，属性不存在时返回空值
+func (s *Session) GetAttr(kind Kind, target int64, key string) (value.Value, error) {
+	conn, err := s.conn(kind, target)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := s.connShard(conn.ID())
+	cs.rw.RLock()
+	v, ok := cs.attrs[conn.ID()][key]
+	cs.rw.RUnlock()
+
+	if ok {
+		return value.NewValue(v), nil
+	}
+
+	return value.NewValue(), nil
+}
+
+// Attrs 获取会话的所有属性快照
+func (s *Session) Attrs(kind Kind, target int64) (map[string]string, error) {
+	conn, err := s.conn(kind, target)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := s.connShard(conn.ID())
+	cs.rw.RLock()
+	defer cs.rw.RUnlock()
+
+	attrs := make(map[string]string, len(cs.attrs[conn.ID()]))
+	for k, v := range cs.attrs[conn.ID()] {
+		attrs[k] = v
+	}
+
+	return attrs, nil
+}
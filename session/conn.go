@@ -0,0 +1,35 @@
+package session
+
+import (
+	"gatesvr/network"
+)
+
+const (
+	listenerShift = 56  // 监听器索引在连接ID中的偏移位
+	MaxListeners  = 128 // 最大监听器数量
+)
+
+type conn struct {
+	network.Conn
+	id int64 // 全局连接ID
+}
+
+// NewConn 包装来自指定监听器的网络连接，使其连接ID在多个监听器间全局唯一
+func NewConn(c network.Conn, listener int) network.Conn {
+	if listener == 0 {
+		return c
+	}
+
+	return &conn{Conn: c, id: MakeCID(listener, c.ID())}
+}
+
+// MakeCID 根据监听器索引和监听器内的连接ID生成全局连接ID
+// 第0个监听器的连接ID保持不变，以兼容单监听器场景
+func MakeCID(listener int, id int64) int64 {
+	return int64(listener)<<listenerShift | id
+}
+
+// ID 获取全局连接ID
+func (c *conn) ID() int64 {
+	return c.id
+}
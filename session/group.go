@@ -0,0 +1,153 @@
+package session
+
+import (
+	"gatesvr/errors"
+	"gatesvr/network"
+)
+
+// JoinGroup 加入分组，分组不存在时自动创建
+// 分组成员以连接维度维护，用户在多个设备上登录时所有设备均加入分组，连接断开后自动退出所有分组
+func (s *Session) JoinGroup(group string, kind Kind, target int64) error {
+	if group == "" {
+		return errors.ErrInvalidArgument
+	}
+
+	conns, err := s.lookup(kind, target)
+	if err != nil {
+		return err
+	}
+
+	s.grw.Lock()
+	defer s.grw.Unlock()
+
+	for _, conn := range conns {
+		// 连接已被移除时不再加入分组
+		if _, ok := s.loadConn(conn.ID()); ok {
+			s.join(group, conn.ID())
+		}
+	}
+
+	return nil
+}
+
+// LeaveGroup 退出分组，分组无成员时自动移除
+func (s *Session) LeaveGroup(group string, kind Kind, target int64) error {
+	conns, err := s.lookup(kind, target)
+	if err != nil {
+		return err
+	}
+
+	s.grw.Lock()
+	defer s.grw.Unlock()
+
+	for _, conn := range conns {
+		s.leave(group, conn.ID())
+	}
+
+	return nil
+}
+
+// Members 获取分组成员，kind为User时仅返回已绑定用户的成员
+func (s *Session) Members(group string, kind Kind) ([]int64, error) {
+	s.grw.RLock()
+	defer s.grw.RUnlock()
+
+	members := s.groups[group]
+
+	switch kind {
+	case Conn:
+		targets := make([]int64, 0, len(members))
+		for cid := range members {
+			targets = append(targets, cid)
+		}
+		return targets, nil
+	case User:
+		uids := make(map[int64]struct{}, len(members))
+		targets := make([]int64, 0, len(members))
+		for cid := range members {
+			if conn, ok := s.loadConn(cid); ok {
+				// 用户在多个设备上加入分组时仅返回一次
+				if uid := conn.UID(); uid != 0 {
+					if _, ok = uids[uid]; !ok {
+						uids[uid] = struct{}{}
+						targets = append(targets, uid)
+					}
+				}
+			}
+		}
+		return targets, nil
+	default:
+		return nil, errors.ErrInvalidSessionKind
+	}
+}
+
+// GroupCount 统计分组成员数
+func (s *Session) GroupCount(group string) int64 {
+	s.grw.RLock()
+	defer s.grw.RUnlock()
+
+	return int64(len(s.groups[group]))
+}
+
+// PushGroup 推送分组消息（异步）
+func (s *Session) PushGroup(group string, msg []byte) (n int64, err error) {
+	s.grw.RLock()
+	conns := make([]network.Conn, 0, len(s.groups[group]))
+	for cid := range s.groups[group] {
+		if conn, ok := s.loadConn(cid); ok {
+			conns = append(conns, conn)
+		}
+	}
+	s.grw.RUnlock()
+
+	for _, conn := range conns {
+		if conn.Push(msg) == nil {
+			n++
+		}
+	}
+
+	return
+}
+
+// 加入分组，调用方需持有分组写锁
+func (s *Session) join(group string, cid int64) {
+	members, ok := s.groups[group]
+	if !ok {
+		members = make(map[int64]struct{})
+		s.groups[group] = members
+	}
+	members[cid] = struct{}{}
+
+	joined, ok := s.joined[cid]
+	if !ok {
+		joined = make(map[string]struct{})
+		s.joined[cid] = joined
+	}
+	joined[group] = struct{}{}
+}
+
+// 退出分组，调用方需持有分组写锁
+func (s *Session) leave(group string, cid int64) {
+	if members, ok := s.groups[group]; ok {
+		delete(members, cid)
+
+		if len(members) == 0 {
+			delete(s.groups, group)
+		}
+	}
+
+	if joined, ok := s.joined[cid]; ok {
+		delete(joined, group)
+
+		if len(joined) == 0 {
+			delete(s.joined, cid)
+		}
+	}
+}
+
+// 退出连接加入的所有分组，调用方需持有分组写锁
+func (s *Session) leaveAll(cid int64) {
+	for group := range s.joined[cid] {
+		s.leave(group, cid)
+	}
+}
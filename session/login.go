@@ -0,0 +1,138 @@
+package session
+
+import (
+	"gatesvr/errors"
+	"gatesvr/network"
+)
+
+const (
+	LoginReplace LoginPolicy = "replace" // 解绑旧设备，旧设备连接保持打开
+	LoginKick    LoginPolicy = "kick"    // 踢出旧设备
+	LoginReject  LoginPolicy = "reject"  // 拒绝新设备登录
+	LoginMulti   LoginPolicy = "multi"   // 允许多设备同时在线
+)
+
+// DeviceAttr 设备标签属性名，绑定用户前通过连接属性设置
+// LoginMulti策略下相同设备标签的旧设备将被踢出，推送时亦可指定设备标签
+const DeviceAttr = "device"
+
+type LoginPolicy string
+
+type loginRule struct {
+	policy  LoginPolicy // 多设备登录策略
+	devices int         // 最大同时在线设备数
+}
+
+// SetLoginPolicy 设置多设备登录策略
+// devices为LoginMulti策略下最大同时在线设备数，超出时踢出最早登录的设备，为0时不限制
+func (s *Session) SetLoginPolicy(policy LoginPolicy, devices int) {
+	s.rule.Store(&loginRule{policy: policy, devices: devices})
+}
+
+// PushDevice 推送消息至用户指定设备标签的设备（异步），返回推送成功的设备数
+func (s *Session) PushDevice(uid int64, device string, msg []byte) (n int64, err error) {
+	us := s.userShard(uid)
+	us.rw.RLock()
+	conns, ok := us.users[uid]
+	if ok {
+		conns = append([]network.Conn(nil), conns...)
+	}
+	us.rw.RUnlock()
+
+	if !ok {
+		return 0, errors.ErrNotFoundSession
+	}
+
+	for _, conn := range conns {
+		if s.loadAttr(conn.ID(), DeviceAttr) != device {
+			continue
+		}
+
+		if err = conn.Push(msg); err == nil {
+			n++
+		}
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+
+	if err == nil {
+		err = errors.ErrNotFoundSession
+	}
+
+	return
+}
+
+// Restore 恢复绑定时被移除的设备，用于绑定后续流程失败时回滚
+// 仅恢复仍然存在且未绑定其他用户的连接，恢复的设备排在当前设备之前
+func (s *Session) Restore(uid int64, conns []network.Conn) {
+	if len(conns) == 0 {
+		return
+	}
+
+	us := s.userShard(uid)
+	us.rw.Lock()
+	defer us.rw.Unlock()
+
+	restored := make([]network.Conn, 0, len(conns))
+
+	for _, conn := range conns {
+		cs := s.connShard(conn.ID())
+		cs.rw.Lock()
+		_, exists := cs.conns[conn.ID()]
+		ok := exists && conn.UID() == 0
+		if ok {
+			conn.Bind(uid)
+		}
+		cs.rw.Unlock()
+
+		if ok {
+			restored = append(restored, conn)
+		}
+	}
+
+	if len(restored) > 0 {
+		us.users[uid] = append(restored, us.users[uid]...)
+	}
+}
+
+// 按多设备登录策略选出需移除的已登录设备，返回需移除的设备及是否需踢出
+// 调用方需持有用户所在分片的写锁
+func (s *Session) evict(cid, uid int64) ([]network.Conn, bool, error) {
+	conns, ok := s.userShard(uid).users[uid]
+	if !ok {
+		return nil, false, nil
+	}
+
+	rule := s.rule.Load()
+
+	switch rule.policy {
+	case LoginKick:
+		return append([]network.Conn(nil), conns...), true, nil
+	case LoginReject:
+		return nil, false, errors.ErrAlreadyLoggedIn
+	case LoginMulti:
+		var (
+			evicted []network.Conn
+			remain  = make([]network.Conn, 0, len(conns))
+			device  = s.loadAttr(cid, DeviceAttr)
+		)
+
+		for _, conn := range conns {
+			if device != "" && s.loadAttr(conn.ID(), DeviceAttr) == device {
+				evicted = append(evicted, conn)
+			} else {
+				remain = append(remain, conn)
+			}
+		}
+
+		if rule.devices > 0 && len(remain) >= rule.devices {
+			evicted = append(evicted, remain[:len(remain)-rule.devices+1]...)
+		}
+
+		return evicted, true, nil
+	default:
+		return append([]network.Conn(nil), conns...), false, nil
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"gatesvr/network"
 	"net"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -25,57 +26,92 @@ func (k Kind) String() string {
 	return ""
 }
 
+// Session 会话管理器
+// 连接会话按连接ID分片，用户会话按用户ID分片，分组单独加锁；推送消息时仅在锁内获取连接快照，不在锁内执行IO
+// 加锁顺序：用户分片 -> 分组 -> 连接分片，同时锁定多个用户分片时按分片顺序加锁
 type Session struct {
-	rw    sync.RWMutex           // 读写锁
-	conns map[int64]network.Conn // 连接会话（连接ID -> network.Conn）
-	users map[int64]network.Conn // 用户会话（用户ID -> network.Conn）
+	conns  [shardCount]*connShard        // 连接会话分片
+	users  [shardCount]*userShard        // 用户会话分片
+	grw    sync.RWMutex                  // 分组读写锁
+	groups map[string]map[int64]struct{} // 分组成员（分组名称 -> 连接ID集合）
+	joined map[int64]map[string]struct{} // 连接加入的分组（连接ID -> 分组名称集合）
+	rule   atomic.Pointer[loginRule]     // 多设备登录策略
 }
 
 func NewSession() *Session {
-	return &Session{
-		conns: make(map[int64]network.Conn),
-		users: make(map[int64]network.Conn),
+	s := &Session{
+		groups: make(map[string]map[int64]struct{}),
+		joined: make(map[int64]map[string]struct{}),
 	}
+
+	for i := 0; i < shardCount; i++ {
+		s.conns[i] = &connShard{
+			conns: make(map[int64]network.Conn),
+			attrs: make(map[int64]map[string]string),
+		}
+		s.users[i] = &userShard{
+			users:    make(map[int64][]network.Conn),
+			suspends: make(map[int64]*suspension),
+		}
+	}
+
+	s.rule.Store(&loginRule{policy: LoginReplace})
+
+	return s
 }
 
 // AddConn 添加连接
 func (s *Session) AddConn(conn network.Conn) {
-	s.rw.Lock()
-	defer s.rw.Unlock()
-
 	cid, uid := conn.ID(), conn.UID()
 
-	s.conns[cid] = conn
+	cs := s.connShard(cid)
+	cs.rw.Lock()
+	cs.conns[cid] = conn
+	cs.rw.Unlock()
 
 	if uid != 0 {
-		s.users[uid] = conn
+		us := s.userShard(uid)
+		us.rw.Lock()
+		us.users[uid] = append(us.users[uid], conn)
+		us.rw.Unlock()
 	}
 }
 
-// RemConn 移除连接
-func (s *Session) RemConn(conn network.Conn) {
-	s.rw.Lock()
-	defer s.rw.Unlock()
+// RemConn 移除连接，返回连接是否存在
+func (s *Session) RemConn(conn network.Conn) bool {
+	cid := conn.ID()
 
-	cid, uid := conn.ID(), conn.UID()
+	if _, ok := s.remConn(cid); !ok {
+		return false
+	}
 
-	delete(s.conns, cid)
+	s.grw.Lock()
+	s.leaveAll(cid)
+	s.grw.Unlock()
 
-	if uid != 0 {
-		delete(s.users, uid)
+	// 连接移除后不会再被绑定，此时读取的用户ID不再变化
+	if uid := conn.UID(); uid != 0 {
+		us := s.userShard(uid)
+		us.rw.Lock()
+		us.remove(uid, cid)
+		us.rw.Unlock()
 	}
+
+	return true
 }
 
 // Has 是否存在会话
 func (s *Session) Has(kind Kind, target int64) (ok bool, err error) {
-	s.rw.RLock()
-	defer s.rw.RUnlock()
-
 	switch kind {
 	case Conn:
-		_, ok = s.conns[target]
+		_, ok = s.loadConn(target)
 	case User:
-		_, ok = s.users[target]
+		us := s.userShard(target)
+		us.rw.RLock()
+		if _, ok = us.users[target]; !ok {
+			_, ok = us.suspends[target]
+		}
+		us.rw.RUnlock()
 	default:
 		err = errors.ErrInvalidSessionKind
 	}
@@ -84,53 +120,125 @@ func (s *Session) Has(kind Kind, target int64) (ok bool, err error) {
 }
 
 // Bind 绑定用户ID
-func (s *Session) Bind(cid, uid int64) error {
-	s.rw.Lock()
-	defer s.rw.Unlock()
+// 用户已在其他设备上登录时按多设备登录策略移除旧设备，返回被移除的设备及是否需踢出
+// 调用方后续流程失败时需通过Restore恢复被移除的设备，成功且需踢出时需通知并关闭被移除的设备
+func (s *Session) Bind(cid, uid int64) ([]network.Conn, bool, error) {
+	conn, ok := s.loadConn(cid)
+	if !ok {
+		return nil, false, errors.ErrNotFoundSession
+	}
 
-	conn, err := s.conn(Conn, cid)
+	for {
+		oldUID := conn.UID()
+		if oldUID != 0 && uid == oldUID {
+			return nil, false, nil
+		}
+
+		unlock := s.lockUsers(oldUID, uid)
+		evicted, kick, ok, err := s.bind(conn, oldUID, uid)
+		unlock()
+
+		// 加锁期间连接的绑定关系已被修改时重试
+		if !ok {
+			continue
+		}
+
+		return evicted, kick, err
+	}
+}
+
+// 绑定用户ID，调用方需持有新旧用户所在分片的写锁
+// 连接的绑定关系已不为oldUID时不做任何修改，返回false
+func (s *Session) bind(conn network.Conn, oldUID, uid int64) ([]network.Conn, bool, bool, error) {
+	cid := conn.ID()
+
+	evicted, kick, err := s.evict(cid, uid)
 	if err != nil {
-		return err
+		return nil, false, true, err
 	}
 
-	if oldUID := conn.UID(); oldUID != 0 {
-		if uid == oldUID {
-			return nil
-		}
-		delete(s.users, oldUID)
+	// 在连接分片锁内检查并绑定，保证连接移除后不会再被绑定，且同一连接不会被并发绑定
+	cs := s.connShard(cid)
+	cs.rw.Lock()
+	_, exists := cs.conns[cid]
+	changed := conn.UID() != oldUID
+	if exists && !changed {
+		conn.Bind(uid)
 	}
+	cs.rw.Unlock()
 
-	if oldConn, ok := s.users[uid]; ok {
-		oldConn.Unbind()
+	if changed {
+		return nil, false, false, nil
 	}
 
-	conn.Bind(uid)
-	s.users[uid] = conn
+	if !exists {
+		return nil, false, true, errors.ErrNotFoundSession
+	}
 
-	return nil
+	if oldUID != 0 {
+		s.userShard(oldUID).remove(oldUID, cid)
+	}
+
+	us := s.userShard(uid)
+
+	for _, c := range evicted {
+		c.Unbind()
+		us.remove(uid, c.ID())
+	}
+
+	us.users[uid] = append(us.users[uid], conn)
+	delete(us.suspends, uid)
+
+	return evicted, kick, true, nil
 }
 
-// Unbind 解绑用户ID
+// Unbind 解绑用户ID，用户在多个设备上登录时解绑所有设备，返回最近登录设备的连接ID
 func (s *Session) Unbind(uid int64) (int64, error) {
-	s.rw.Lock()
-	defer s.rw.Unlock()
+	us := s.userShard(uid)
+	us.rw.Lock()
+	defer us.rw.Unlock()
 
-	conn, err := s.conn(User, uid)
-	if err != nil {
-		return 0, err
+	conns, ok := us.users[uid]
+	if !ok {
+		return 0, errors.ErrNotFoundSession
+	}
+
+	for _, conn := range conns {
+		conn.Unbind()
+	}
+	delete(us.users, uid)
+
+	return conns[len(conns)-1].ID(), nil
+}
+
+// UnbindConn 解绑连接上的用户ID，仅解绑该连接，用户在其他设备上的登录不受影响
+func (s *Session) UnbindConn(cid int64) error {
+	conn, ok := s.loadConn(cid)
+	if !ok {
+		return errors.ErrNotFoundSession
+	}
+
+	uid := conn.UID()
+	if uid == 0 {
+		return errors.ErrNotFoundSession
+	}
+
+	us := s.userShard(uid)
+	us.rw.Lock()
+	defer us.rw.Unlock()
+
+	// 加锁期间连接已被解绑或重新绑定时，连接不在该用户的设备列表中
+	if !us.remove(uid, cid) {
+		return errors.ErrNotFoundSession
 	}
 
 	conn.Unbind()
-	delete(s.users, uid)
 
-	return conn.ID(), nil
+	return nil
 }
 
 // LocalIP 获取本地IP
 func (s *Session) LocalIP(kind Kind, target int64) (string, error) {
-	s.rw.RLock()
-	defer s.rw.RUnlock()
-
 	conn, err := s.conn(kind, target)
 	if err != nil {
 		return "", err
@@ -141,9 +249,6 @@ func (s *Session) LocalIP(kind Kind, target int64) (string, error) {
 
 // LocalAddr 获取本地地址
 func (s *Session) LocalAddr(kind Kind, target int64) (net.Addr, error) {
-	s.rw.RLock()
-	defer s.rw.RUnlock()
-
 	conn, err := s.conn(kind, target)
 	if err != nil {
 		return nil, err
@@ -154,9 +259,6 @@ func (s *Session) LocalAddr(kind Kind, target int64) (net.Addr, error) {
 
 // RemoteIP 获取远端IP
 func (s *Session) RemoteIP(kind Kind, target int64) (string, error) {
-	s.rw.RLock()
-	defer s.rw.RUnlock()
-
 	conn, err := s.conn(kind, target)
 	if err != nil {
 		return "", err
@@ -167,9 +269,6 @@ func (s *Session) RemoteIP(kind Kind, target int64) (string, error) {
 
 // RemoteAddr 获取远端地址
 func (s *Session) RemoteAddr(kind Kind, target int64) (net.Addr, error) {
-	s.rw.RLock()
-	defer s.rw.RUnlock()
-
 	conn, err := s.conn(kind, target)
 	if err != nil {
 		return nil, err
@@ -178,43 +277,42 @@ func (s *Session) RemoteAddr(kind Kind, target int64) (net.Addr, error) {
 	return conn.RemoteAddr()
 }
 
-// Close 关闭会话
-func (s *Session) Close(kind Kind, target int64, force ...bool) error {
-	s.rw.RLock()
-	conn, err := s.conn(kind, target)
-	s.rw.RUnlock()
-
+// Close 关闭会话，用户在多个设备上登录时关闭所有设备
+func (s *Session) Close(kind Kind, target int64, force ...bool) (err error) {
+	conns, err := s.lookup(kind, target)
 	if err != nil {
 		return err
 	}
 
-	return conn.Close(force...)
+	for _, conn := range conns {
+		if e := conn.Close(force...); e != nil {
+			err = e
+		}
+	}
+
+	return
 }
 
 // Send 发送消息（同步）
+// 用户在多个设备上登录时发送至所有设备；用户处于挂起状态时消息将被缓存
 func (s *Session) Send(kind Kind, target int64, msg []byte) error {
-	s.rw.RLock()
-	defer s.rw.RUnlock()
-
-	conn, err := s.conn(kind, target)
-	if err != nil {
+	conns, err := s.snapshot(kind, target, msg)
+	if err != nil || conns == nil {
 		return err
 	}
 
-	return conn.Send(msg)
+	return deliver(conns, msg, network.Conn.Send)
 }
 
 // Push 推送消息（异步）
+// 用户在多个设备上登录时推送至所有设备；用户处于挂起状态时消息将被缓存
 func (s *Session) Push(kind Kind, target int64, msg []byte) error {
-	s.rw.RLock()
-	defer s.rw.RUnlock()
-
-	conn, err := s.conn(kind, target)
-	if err != nil {
+	conns, err := s.snapshot(kind, target, msg)
+	if err != nil || conns == nil {
 		return err
 	}
 
-	return conn.Push(msg)
+	return deliver(conns, msg, network.Conn.Push)
 }
 
 // Multicast 推送组播消息（异步）
@@ -223,26 +321,19 @@ func (s *Session) Multicast(kind Kind, targets []int64, msg []byte) (n int64, er
 		return
 	}
 
-	s.rw.RLock()
-	defer s.rw.RUnlock()
-
-	var conns map[int64]network.Conn
-	switch kind {
-	case Conn:
-		conns = s.conns
-	case User:
-		conns = s.users
-	default:
+	if kind != Conn && kind != User {
 		err = errors.ErrInvalidSessionKind
 		return
 	}
 
 	for _, target := range targets {
-		conn, ok := conns[target]
-		if !ok {
+		conns, e := s.snapshot(kind, target, msg)
+		if e != nil {
 			continue
 		}
-		if conn.Push(msg) == nil {
+
+		// 用户处于挂起状态时消息已被缓存
+		if conns == nil || deliver(conns, msg, network.Conn.Push) == nil {
 			n++
 		}
 	}
@@ -251,25 +342,50 @@ func (s *Session) Multicast(kind Kind, targets []int64, msg []byte) (n int64, er
 }
 
 // Broadcast 推送广播消息（异步）
+// 逐个分片获取连接快照后再推送，推送过程中不持有锁
 func (s *Session) Broadcast(kind Kind, msg []byte) (n int64, err error) {
-	s.rw.RLock()
-	defer s.rw.RUnlock()
-
-	var conns map[int64]network.Conn
 	switch kind {
 	case Conn:
-		conns = s.conns
+		var conns []network.Conn
+		for _, cs := range s.conns {
+			conns = conns[:0]
+
+			cs.rw.RLock()
+			for _, conn := range cs.conns {
+				conns = append(conns, conn)
+			}
+			cs.rw.RUnlock()
+
+			for _, conn := range conns {
+				if conn.Push(msg) == nil {
+					n++
+				}
+			}
+		}
 	case User:
-		conns = s.users
+		var users [][]network.Conn
+		for _, us := range s.users {
+			users = users[:0]
+
+			us.rw.RLock()
+			for _, conns := range us.users {
+				users = append(users, append([]network.Conn(nil), conns...))
+			}
+
+			for _, sus := range us.suspends {
+				sus.push(msg)
+				n++
+			}
+			us.rw.RUnlock()
+
+			for _, conns := range users {
+				if deliver(conns, msg, network.Conn.Push) == nil {
+					n++
+				}
+			}
+		}
 	default:
 		err = errors.ErrInvalidSessionKind
-		return
-	}
-
-	for _, conn := range conns {
-		if conn.Push(msg) == nil {
-			n++
-		}
 	}
 
 	return
@@ -277,35 +393,96 @@ func (s *Session) Broadcast(kind Kind, msg []byte) (n int64, err error) {
 
 // Stat 统计会话总数
 func (s *Session) Stat(kind Kind) (int64, error) {
-	s.rw.RLock()
-	defer s.rw.RUnlock()
+	var n int
 
 	switch kind {
 	case Conn:
-		return int64(len(s.conns)), nil
+		for _, cs := range s.conns {
+			cs.rw.RLock()
+			n += len(cs.conns)
+			cs.rw.RUnlock()
+		}
 	case User:
-		return int64(len(s.users)), nil
+		for _, us := range s.users {
+			us.rw.RLock()
+			n += len(us.users)
+			us.rw.RUnlock()
+		}
 	default:
 		return 0, errors.ErrInvalidSessionKind
 	}
+
+	return int64(n), nil
 }
 
-// 获取会话
-func (s *Session) conn(kind Kind, target int64) (network.Conn, error) {
-	switch kind {
-	case Conn:
-		conn, ok := s.conns[target]
-		if !ok {
-			return nil, errors.ErrNotFoundSession
+// Conns 获取所有连接快照
+func (s *Session) Conns() []network.Conn {
+	conns := make([]network.Conn, 0)
+
+	for _, cs := range s.conns {
+		cs.rw.RLock()
+		for _, conn := range cs.conns {
+			conns = append(conns, conn)
 		}
-		return conn, nil
-	case User:
-		conn, ok := s.users[target]
-		if !ok {
-			return nil, errors.ErrNotFoundSession
+		cs.rw.RUnlock()
+	}
+
+	return conns
+}
+
+// 获取会话的连接快照，用户处于挂起状态时缓存消息并返回空快照
+func (s *Session) snapshot(kind Kind, target int64, msg []byte) ([]network.Conn, error) {
+	if kind != User {
+		return s.lookup(kind, target)
+	}
+
+	us := s.userShard(target)
+	us.rw.RLock()
+	defer us.rw.RUnlock()
+
+	if conns, ok := us.users[target]; ok {
+		return append([]network.Conn(nil), conns...), nil
+	}
+
+	if sus, ok := us.suspends[target]; ok {
+		sus.push(msg)
+		return nil, nil
+	}
+
+	return nil, errors.ErrNotFoundSession
+}
+
+// 移除连接及连接属性，返回被移除的连接属性及连接是否存在
+func (s *Session) remConn(cid int64) (map[string]string, bool) {
+	cs := s.connShard(cid)
+	cs.rw.Lock()
+	defer cs.rw.Unlock()
+
+	if _, ok := cs.conns[cid]; !ok {
+		return nil, false
+	}
+
+	attrs := cs.attrs[cid]
+	delete(cs.conns, cid)
+	delete(cs.attrs, cid)
+
+	return attrs, true
+}
+
+// 向所有连接投递消息，至少一个连接投递成功时视为成功
+func deliver(conns []network.Conn, msg []byte, fn func(network.Conn, []byte) error) (err error) {
+	ok := false
+	for _, conn := range conns {
+		if e := fn(conn, msg); e != nil {
+			err = e
+		} else {
+			ok = true
 		}
-		return conn, nil
-	default:
-		return nil, errors.ErrInvalidSessionKind
 	}
+
+	if ok {
+		return nil
+	}
+
+	return
 }
@@ -0,0 +1,242 @@
+package session_test
+
+import (
+	"gatesvr/network"
+	"gatesvr/session"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const population = 10000 // 基准测试中预先建立的连接数
+
+type conn struct {
+	id     int64
+	uid    atomic.Int64
+	pushed atomic.Int64
+}
+
+func newConn(id int64) *conn {
+	return &conn{id: id}
+}
+
+func (c *conn) ID() int64                     { return c.id }
+func (c *conn) UID() int64                    { return c.uid.Load() }
+func (c *conn) Bind(uid int64)                { c.uid.Store(uid) }
+func (c *conn) Unbind()                       { c.uid.Store(0) }
+func (c *conn) Send(msg []byte) error         { return c.Push(msg) }
+func (c *conn) State() network.ConnState      { return network.ConnOpened }
+func (c *conn) Close(force ...bool) error     { return nil }
+func (c *conn) LocalIP() (string, error)      { return "127.0.0.1", nil }
+func (c *conn) LocalAddr() (net.Addr, error)  { return nil, nil }
+func (c *conn) RemoteIP() (string, error)     { return "127.0.0.1", nil }
+func (c *conn) RemoteAddr() (net.Addr, error) { return nil, nil }
+
+// Push 模拟写入连接发送队列的开销
+func (c *conn) Push(msg []byte) error {
+	for i := 0; i < 100; i++ {
+		c.pushed.Add(1)
+	}
+
+	return nil
+}
+
+func TestSession_Bind(t *testing.T) {
+	s := session.NewSession()
+	s.SetLoginPolicy(session.LoginMulti, 2)
+
+	conns := []*conn{newConn(1), newConn(2), newConn(3)}
+	for i, c := range conns {
+		s.AddConn(c)
+		_ = s.SetAttr(session.Conn, c.ID(), session.DeviceAttr, []string{"pc", "phone", "phone"}[i])
+	}
+
+	for _, c := range conns[:2] {
+		if evicted, _, err := s.Bind(c.ID(), 100); err != nil || len(evicted) != 0 {
+			t.Fatalf("bind failed, evicted: %d err: %v", len(evicted), err)
+		}
+	}
+
+	if err := s.Push(session.User, 100, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	if conns[0].pushed.Load() == 0 || conns[1].pushed.Load() == 0 {
+		t.Fatal("message was not pushed to all devices")
+	}
+
+	// 相同设备标签的旧设备被踢出
+	evicted, kick, err := s.Bind(conns[2].ID(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !kick || len(evicted) != 1 || evicted[0].ID() != conns[1].ID() || conns[1].UID() != 0 {
+		t.Fatalf("unexpected kicked devices: %v", evicted)
+	}
+
+	if n, err := s.PushDevice(100, "pc", []byte("hello")); err != nil || n != 1 {
+		t.Fatalf("push device failed, n: %d err: %v", n, err)
+	}
+
+	s.SetLoginPolicy(session.LoginReject, 0)
+
+	if _, _, err = s.Bind(conns[1].ID(), 100); err == nil {
+		t.Fatal("login was not rejected")
+	}
+}
+
+func TestSession_Restore(t *testing.T) {
+	s := session.NewSession()
+	s.SetLoginPolicy(session.LoginKick, 0)
+
+	c1, c2 := newConn(1), newConn(2)
+	s.AddConn(c1)
+	s.AddConn(c2)
+
+	_, _, _ = s.Bind(c1.ID(), 100)
+
+	evicted, kick, err := s.Bind(c2.ID(), 100)
+	if err != nil || !kick || len(evicted) != 1 || c1.UID() != 0 {
+		t.Fatalf("unexpected eviction, evicted: %v kick: %v err: %v", evicted, kick, err)
+	}
+
+	// 后续流程失败时回滚当前设备并恢复被移除的设备
+	_ = s.UnbindConn(c2.ID())
+	s.Restore(100, evicted)
+
+	if c1.UID() != 100 || c2.UID() != 0 {
+		t.Fatalf("unexpected binding, c1: %d c2: %d", c1.UID(), c2.UID())
+	}
+
+	if cid, err := s.Unbind(100); err != nil || cid != c1.ID() {
+		t.Fatalf("evicted device was not restored, cid: %d err: %v", cid, err)
+	}
+}
+
+func TestSession_Suspend(t *testing.T) {
+	s := session.NewSession()
+
+	c1, c2 := newConn(1), newConn(2)
+	s.AddConn(c1)
+	s.AddConn(c2)
+
+	_, _, _ = s.Bind(c1.ID(), 100)
+	_ = s.JoinGroup("room", session.User, 100)
+
+	if ok, suspended := s.Suspend(c1, 10); !ok || !suspended {
+		t.Fatalf("suspend failed, ok: %v suspended: %v", ok, suspended)
+	}
+
+	_ = s.Push(session.User, 100, []byte("hello"))
+
+	if _, err := s.Resume(c2.ID(), 100, c2.ID()); err == nil {
+		t.Fatal("resume with a mismatched connection should fail")
+	}
+
+	if n, err := s.Resume(c2.ID(), 100, c1.ID()); err != nil || n != 1 {
+		t.Fatalf("resume failed, n: %d err: %v", n, err)
+	}
+
+	if members, _ := s.Members("room", session.User); len(members) != 1 || members[0] != 100 {
+		t.Fatalf("unexpected group members: %v", members)
+	}
+}
+
+func BenchmarkSession_ConnectBroadcast(b *testing.B) {
+	benchmarkConnectBroadcast(b, session.NewSession())
+}
+
+func BenchmarkLockedSession_ConnectBroadcast(b *testing.B) {
+	benchmarkConnectBroadcast(b, newLockedSession())
+}
+
+type registry interface {
+	AddConn(conn network.Conn)
+	RemConn(conn network.Conn) bool
+	Broadcast(kind session.Kind, msg []byte) (int64, error)
+}
+
+// 持续广播的同时并发建立、断开连接
+func benchmarkConnectBroadcast(b *testing.B, r registry) {
+	for i := int64(1); i <= population; i++ {
+		r.AddConn(newConn(i))
+	}
+
+	var (
+		id   atomic.Int64
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+
+	id.Store(population)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_, _ = r.Broadcast(session.Conn, []byte("hello"))
+			}
+		}
+	}()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c := newConn(id.Add(1))
+			r.AddConn(c)
+			r.RemConn(c)
+		}
+	})
+
+	b.StopTimer()
+
+	close(done)
+	wg.Wait()
+}
+
+// 单锁会话，广播期间持有读锁，用于对比分片会话的锁竞争
+type lockedSession struct {
+	rw    sync.RWMutex
+	conns map[int64]network.Conn
+}
+
+func newLockedSession() *lockedSession {
+	return &lockedSession{conns: make(map[int64]network.Conn)}
+}
+
+func (s *lockedSession) AddConn(conn network.Conn) {
+	s.rw.Lock()
+	s.conns[conn.ID()] = conn
+	s.rw.Unlock()
+}
+
+func (s *lockedSession) RemConn(conn network.Conn) bool {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	_, ok := s.conns[conn.ID()]
+	delete(s.conns, conn.ID())
+
+	return ok
+}
+
+func (s *lockedSession) Broadcast(kind session.Kind, msg []byte) (n int64, err error) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	for _, conn := range s.conns {
+		if conn.Push(msg) == nil {
+			n++
+		}
+	}
+
+	return
+}
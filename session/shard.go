@@ -0,0 +1,161 @@
+package session
+
+import (
+	"gatesvr/errors"
+	"gatesvr/network"
+	"sort"
+	"sync"
+)
+
+const shardCount = 32 // 会话分片数，需为2的幂
+
+type connShard struct {
+	rw    sync.RWMutex                // 读写锁
+	conns map[int64]network.Conn      // 连接会话（连接ID -> network.Conn）
+	attrs map[int64]map[string]string // 连接属性（连接ID -> 属性）
+}
+
+type userShard struct {
+	rw       sync.RWMutex             // 读写锁
+	users    map[int64][]network.Conn // 用户会话（用户ID -> 按登录顺序排列的设备连接）
+	suspends map[int64]*suspension    // 挂起的用户会话（用户ID -> *suspension）
+}
+
+// 获取连接所在分片
+func (s *Session) connShard(cid int64) *connShard {
+	return s.conns[uint64(cid)&(shardCount-1)]
+}
+
+// 获取用户所在分片
+func (s *Session) userShard(uid int64) *userShard {
+	return s.users[uint64(uid)&(shardCount-1)]
+}
+
+// 按分片顺序锁定用户所在分片，返回解锁函数；用户ID为0时忽略
+func (s *Session) lockUsers(uids ...int64) func() {
+	indexes := make([]int, 0, len(uids))
+	for _, uid := range uids {
+		if uid == 0 {
+			continue
+		}
+
+		index := int(uint64(uid) & (shardCount - 1))
+		if !contains(indexes, index) {
+			indexes = append(indexes, index)
+		}
+	}
+
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		s.users[index].rw.Lock()
+	}
+
+	return func() {
+		for i := len(indexes) - 1; i >= 0; i-- {
+			s.users[indexes[i]].rw.Unlock()
+		}
+	}
+}
+
+// 获取连接
+func (s *Session) loadConn(cid int64) (network.Conn, bool) {
+	cs := s.connShard(cid)
+	cs.rw.RLock()
+	conn, ok := cs.conns[cid]
+	cs.rw.RUnlock()
+
+	return conn, ok
+}
+
+// 获取连接属性，调用方不得持有该连接所在分片的锁
+func (s *Session) loadAttr(cid int64, key string) string {
+	cs := s.connShard(cid)
+	cs.rw.RLock()
+	v := cs.attrs[cid][key]
+	cs.rw.RUnlock()
+
+	return v
+}
+
+// 获取会话，用户在多个设备上登录时返回最近登录的设备
+func (s *Session) conn(kind Kind, target int64) (network.Conn, error) {
+	switch kind {
+	case Conn:
+		conn, ok := s.loadConn(target)
+		if !ok {
+			return nil, errors.ErrNotFoundSession
+		}
+		return conn, nil
+	case User:
+		us := s.userShard(target)
+		us.rw.RLock()
+		defer us.rw.RUnlock()
+
+		conns, ok := us.users[target]
+		if !ok {
+			return nil, errors.ErrNotFoundSession
+		}
+		return conns[len(conns)-1], nil
+	default:
+		return nil, errors.ErrInvalidSessionKind
+	}
+}
+
+// 获取会话的所有连接快照，用户在多个设备上登录时返回所有设备
+func (s *Session) lookup(kind Kind, target int64) ([]network.Conn, error) {
+	switch kind {
+	case Conn:
+		conn, ok := s.loadConn(target)
+		if !ok {
+			return nil, errors.ErrNotFoundSession
+		}
+		return []network.Conn{conn}, nil
+	case User:
+		us := s.userShard(target)
+		us.rw.RLock()
+		defer us.rw.RUnlock()
+
+		conns, ok := us.users[target]
+		if !ok {
+			return nil, errors.ErrNotFoundSession
+		}
+		return append([]network.Conn(nil), conns...), nil
+	default:
+		return nil, errors.ErrInvalidSessionKind
+	}
+}
+
+// 移除用户的设备连接，调用方需持有用户所在分片的写锁，返回连接是否为用户的设备
+func (us *userShard) remove(uid, cid int64) bool {
+	conns, ok := us.users[uid]
+	if !ok {
+		return false
+	}
+
+	for i, conn := range conns {
+		if conn.ID() != cid {
+			continue
+		}
+
+		if len(conns) == 1 {
+			delete(us.users, uid)
+		} else {
+			us.users[uid] = append(conns[:i:i], conns[i+1:]...)
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func contains(indexes []int, index int) bool {
+	for _, i := range indexes {
+		if i == index {
+			return true
+		}
+	}
+
+	return false
+}
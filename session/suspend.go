@@ -0,0 +1,207 @@
+package session
+
+import (
+	"gatesvr/errors"
+	"gatesvr/network"
+	"sync"
+)
+
+type suspension struct {
+	mu     sync.Mutex
+	cid    int64             // 挂起前的连接ID
+	limit  int               // 最大缓存消息数
+	buffer [][]byte          // 缓存的消息
+	groups []string          // 挂起前加入的分组
+	attrs  map[string]string // 挂起前的连接属性
+}
+
+// 缓存消息，超出缓存上限时丢弃最早的消息
+func (s *suspension) push(msg []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.limit <= 0 {
+		return
+	}
+
+	if len(s.buffer) >= s.limit {
+		s.buffer = s.buffer[1:]
+	}
+
+	s.buffer = append(s.buffer, msg)
+}
+
+// 取出缓存的消息
+func (s *suspension) drain() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buffer := s.buffer
+	s.buffer = nil
+
+	return buffer
+}
+
+// Suspend 移除连接，并将连接绑定的用户置为挂起状态
+// 挂起期间用户仍视为在线，推送给该用户的消息将被缓存，最多缓存limit条
+// 用户仍有其他设备在线时仅移除连接，不挂起用户
+// 返回连接是否存在及用户是否被挂起
+func (s *Session) Suspend(conn network.Conn, limit int) (bool, bool) {
+	cid := conn.ID()
+
+	attrs, ok := s.remConn(cid)
+	if !ok {
+		return false, false
+	}
+
+	s.grw.Lock()
+	groups := make([]string, 0, len(s.joined[cid]))
+	for group := range s.joined[cid] {
+		groups = append(groups, group)
+	}
+	s.leaveAll(cid)
+	s.grw.Unlock()
+
+	// 连接移除后不会再被绑定，此时读取的用户ID不再变化
+	uid := conn.UID()
+	if uid == 0 {
+		return true, false
+	}
+
+	us := s.userShard(uid)
+	us.rw.Lock()
+	defer us.rw.Unlock()
+
+	if !us.remove(uid, cid) {
+		return true, false
+	}
+
+	// 用户仍有其他设备在线时无需挂起
+	if _, ok = us.users[uid]; ok {
+		return true, false
+	}
+
+	us.suspends[uid] = &suspension{cid: cid, limit: limit, groups: groups, attrs: attrs}
+
+	return true, true
+}
+
+// Resume 将挂起的用户绑定到新连接上，并向新连接重放挂起期间缓存的消息，返回重放的消息数
+// from为挂起前的连接ID，用户当前的挂起状态不是由该连接断开产生时不予恢复
+func (s *Session) Resume(cid, uid, from int64) (int, error) {
+	us := s.userShard(uid)
+	us.rw.Lock()
+	defer us.rw.Unlock()
+
+	sus, ok := us.suspends[uid]
+	if !ok || sus.cid != from {
+		return 0, errors.ErrNotFoundSession
+	}
+
+	conn, ok := s.loadConn(cid)
+	if !ok {
+		return 0, errors.ErrNotFoundSession
+	}
+
+	// 在连接分片锁内绑定并恢复挂起前的连接属性
+	cs := s.connShard(cid)
+	cs.rw.Lock()
+	_, exists := cs.conns[cid]
+	bound := conn.UID() != 0
+	if exists && !bound {
+		conn.Bind(uid)
+
+		for key, value := range sus.attrs {
+			attrs, ok := cs.attrs[cid]
+			if !ok {
+				attrs = make(map[string]string, len(sus.attrs))
+				cs.attrs[cid] = attrs
+			}
+
+			// 新连接上已设置的属性优先
+			if _, ok = attrs[key]; !ok {
+				attrs[key] = value
+			}
+		}
+	}
+	cs.rw.Unlock()
+
+	if !exists {
+		return 0, errors.ErrNotFoundSession
+	}
+
+	if bound {
+		return 0, errors.ErrIllegalOperation
+	}
+
+	delete(us.suspends, uid)
+	us.users[uid] = append(us.users[uid], conn)
+
+	// 恢复挂起前加入的分组
+	s.grw.Lock()
+	if _, ok = s.loadConn(cid); ok {
+		for _, group := range sus.groups {
+			s.join(group, cid)
+		}
+	}
+	s.grw.Unlock()
+
+	// 持有用户分片写锁期间重放，保证缓存消息先于后续推送的消息送达
+	n := 0
+	for _, msg := range sus.drain() {
+		if conn.Push(msg) == nil {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// Release 释放挂起的用户，返回挂起期间缓存的消息
+// from为挂起前的连接ID，不为0时仅释放由该连接断开产生的挂起状态
+func (s *Session) Release(uid, from int64) ([][]byte, bool) {
+	us := s.userShard(uid)
+	us.rw.Lock()
+	sus, ok := us.suspends[uid]
+	if ok && from != 0 && sus.cid != from {
+		ok = false
+	}
+	if ok {
+		delete(us.suspends, uid)
+	}
+	us.rw.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	return sus.drain(), true
+}
+
+// Expire 挂起超时，移除挂起的用户
+// cid为挂起前的连接ID，用于避免误移除用户后续的挂起状态；返回挂起前的连接属性及用户是否仍处于该次挂起状态
+func (s *Session) Expire(uid, cid int64) (map[string]string, bool) {
+	us := s.userShard(uid)
+	us.rw.Lock()
+	defer us.rw.Unlock()
+
+	sus, ok := us.suspends[uid]
+	if !ok || sus.cid != cid {
+		return nil, false
+	}
+
+	delete(us.suspends, uid)
+
+	return sus.attrs, true
+}
+
+// IsSuspended 用户是否处于挂起状态
+func (s *Session) IsSuspended(uid int64) bool {
+	us := s.userShard(uid)
+	us.rw.RLock()
+	defer us.rw.RUnlock()
+
+	_, ok := us.suspends[uid]
+
+	return ok
+}
@@ -0,0 +1,180 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"gatesvr/errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultFile = "./run/trace/spans.jsonl" // 默认导出文件
+
+	defaultQueueSize     = 4096        // 导出队列长度
+	defaultFlushInterval = time.Second // 刷盘间隔
+)
+
+type SpanData struct {
+	Name     string            `json:"name"`                // 跨度名称
+	TraceID  string            `json:"trace_id"`            // 链路ID
+	SpanID   string            `json:"span_id"`             // 跨度ID
+	ParentID string            `json:"parent_id,omitempty"` // 父跨度ID
+	Start    time.Time         `json:"start"`               // 开始时间
+	End      time.Time         `json:"end"`                 // 结束时间
+	Duration int64             `json:"duration_us"`         // 耗时（微秒）
+	Attrs    map[string]string `json:"attrs,omitempty"`     // 属性
+	Error    string            `json:"error,omitempty"`     // 错误信息
+}
+
+type Exporter interface {
+	// Export 导出跨度，调用方不应阻塞等待导出完成
+	Export(span *SpanData) error
+	// Close 关闭导出器
+	Close() error
+}
+
+var (
+	exporter     atomic.Pointer[Exporter]
+	exporterOnce sync.Once
+)
+
+// SetExporter 设置导出器，替换前的导出器由调用方负责关闭
+func SetExporter(e Exporter) {
+	if e == nil {
+		return
+	}
+
+	exporter.Store(&e)
+}
+
+// GetExporter 获取导出器，未设置时使用写入DefaultFile的JSON Lines文件导出器
+func GetExporter() Exporter {
+	if e := exporter.Load(); e != nil {
+		return *e
+	}
+
+	exporterOnce.Do(func() {
+		var e Exporter = NewFileExporter(DefaultFile)
+		exporter.CompareAndSwap(nil, &e)
+	})
+
+	return *exporter.Load()
+}
+
+// 导出跨度
+func export(span *SpanData) {
+	_ = GetExporter().Export(span)
+}
+
+type FileExporter struct {
+	path   string         // 导出文件
+	rw     sync.RWMutex   // 关闭锁
+	closed bool           // 已关闭
+	queue  chan *SpanData // 导出队列
+	done   chan struct{}  // 写入完成
+	err    error          // 写入错误
+}
+
+// NewFileExporter 创建JSON Lines文件导出器，每个跨度写入一行JSON
+// 文件在首次导出时创建，写入在后台进行，队列已满时丢弃跨度
+func NewFileExporter(path string) *FileExporter {
+	e := &FileExporter{
+		path:  path,
+		queue: make(chan *SpanData, defaultQueueSize),
+		done:  make(chan struct{}),
+	}
+
+	go e.run()
+
+	return e
+}
+
+// Export 导出跨度
+func (e *FileExporter) Export(span *SpanData) error {
+	e.rw.RLock()
+	defer e.rw.RUnlock()
+
+	if e.closed {
+		return os.ErrClosed
+	}
+
+	select {
+	case e.queue <- span:
+		return nil
+	default:
+		return errors.ErrTraceQueueFull
+	}
+}
+
+// Close 关闭导出器，写入队列中剩余的跨度后返回
+func (e *FileExporter) Close() error {
+	e.rw.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.rw.Unlock()
+
+	<-e.done
+
+	return e.err
+}
+
+// 后台写入
+func (e *FileExporter) run() {
+	defer close(e.done)
+
+	var (
+		file   *os.File
+		writer *bufio.Writer
+		ticker = time.NewTicker(defaultFlushInterval)
+	)
+	defer ticker.Stop()
+
+	defer func() {
+		if file != nil {
+			if err := writer.Flush(); err != nil && e.err == nil {
+				e.err = err
+			}
+			_ = file.Close()
+		}
+	}()
+
+	for {
+		select {
+		case span, ok := <-e.queue:
+			if !ok {
+				return
+			}
+
+			if file == nil {
+				if file, e.err = e.open(); e.err != nil {
+					// 文件无法打开时丢弃跨度，直至关闭
+					continue
+				}
+				writer = bufio.NewWriter(file)
+			}
+
+			if buf, err := json.Marshal(span); err == nil {
+				_, _ = writer.Write(append(buf, '\n'))
+			}
+		case <-ticker.C:
+			if writer != nil {
+				_ = writer.Flush()
+			}
+		}
+	}
+}
+
+// 打开导出文件
+func (e *FileExporter) open() (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(e.path), 0755); err != nil {
+		return nil, err
+	}
+
+	return os.OpenFile(e.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
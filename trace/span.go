@@ -0,0 +1,115 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Span struct {
+	mu       sync.Mutex
+	ended    bool
+	name     string            // 跨度名称
+	sc       SpanContext       // 链路上下文
+	parentID SpanID            // 父跨度ID，根跨度为空
+	start    time.Time         // 开始时间
+	end      time.Time         // 结束时间
+	attrs    map[string]string // 属性
+	err      string            // 错误信息
+}
+
+// Start 开始跨度
+// 上下文中存在链路上下文时创建其子跨度，否则创建新的链路；返回携带新跨度链路上下文的上下文
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	s := &Span{name: name, start: time.Now()}
+
+	if parent, ok := FromContext(ctx); ok {
+		s.sc = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: parent.Sampled}
+		s.parentID = parent.SpanID
+	} else {
+		s.sc = SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	}
+
+	return NewContext(ctx, s.sc), s
+}
+
+// Context 获取跨度的链路上下文
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.sc
+}
+
+// SetAttr 设置属性
+func (s *Span) SetAttr(key, value string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	if s.attrs == nil {
+		s.attrs = make(map[string]string)
+	}
+	s.attrs[key] = value
+}
+
+// SetError 记录错误
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if !s.ended {
+		s.err = err.Error()
+	}
+	s.mu.Unlock()
+}
+
+// End 结束跨度，已采样的跨度交由导出器导出，重复调用时仅首次生效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.sc.Sampled {
+		export(s.data())
+	}
+}
+
+// 转导出数据
+func (s *Span) data() *SpanData {
+	d := &SpanData{
+		Name:     s.name,
+		TraceID:  s.sc.TraceID.String(),
+		SpanID:   s.sc.SpanID.String(),
+		Start:    s.start,
+		End:      s.end,
+		Duration: s.end.Sub(s.start).Microseconds(),
+		Attrs:    s.attrs,
+		Error:    s.err,
+	}
+
+	if s.parentID.IsValid() {
+		d.ParentID = s.parentID.String()
+	}
+
+	return d
+}
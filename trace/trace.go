@@ -0,0 +1,134 @@
+package trace
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"gatesvr/errors"
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	TraceparentLen = 55 // traceparent长度，格式：version-traceid-spanid-flags
+	traceVersion   = "00"
+	sampledFlag    = 0x01
+)
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+type spanContextKey struct{}
+
+type SpanContext struct {
+	TraceID TraceID // 链路ID
+	SpanID  SpanID  // 跨度ID
+	Sampled bool    // 是否采样，未采样的跨度不导出
+}
+
+// String 转十六进制字符串
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid 检测链路ID是否有效
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String 转十六进制字符串
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid 检测跨度ID是否有效
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// IsValid 检测链路上下文是否有效
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent 转W3C traceparent格式
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return traceVersion + "-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent 解析W3C traceparent
+func ParseTraceparent(traceparent string) (SpanContext, error) {
+	var sc SpanContext
+
+	if len(traceparent) != TraceparentLen || strings.Count(traceparent, "-") != 3 {
+		return sc, errors.ErrInvalidTraceparent
+	}
+
+	parts := strings.Split(traceparent, "-")
+
+	if parts[0] != traceVersion || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, errors.ErrInvalidTraceparent
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, errors.ErrInvalidTraceparent
+	}
+
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, errors.ErrInvalidTraceparent
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, errors.ErrInvalidTraceparent
+	}
+
+	if !sc.IsValid() {
+		return sc, errors.ErrInvalidTraceparent
+	}
+
+	sc.Sampled = flags[0]&sampledFlag == sampledFlag
+
+	return sc, nil
+}
+
+// NewContext 将链路上下文存入上下文
+func NewContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// FromContext 从上下文中获取链路上下文
+func FromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+
+	return sc, ok && sc.IsValid()
+}
+
+// 生成链路ID
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+
+	return
+}
+
+// 生成跨度ID
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+
+	return
+}
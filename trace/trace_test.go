@@ -0,0 +1,105 @@
+package trace_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"gatesvr/trace"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, err := trace.ParseTraceparent(traceparent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !sc.Sampled || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected span context: %+v", sc)
+	}
+
+	if sc.Traceparent() != traceparent {
+		t.Fatalf("unexpected traceparent: %s", sc.Traceparent())
+	}
+
+	for _, invalid := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-3600f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bz-01",
+	} {
+		if _, err = trace.ParseTraceparent(invalid); err == nil {
+			t.Fatalf("expected invalid traceparent: %q", invalid)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	ctx, root := trace.Start(context.Background(), "root")
+
+	sc, ok := trace.FromContext(ctx)
+	if !ok || sc != root.Context() || !sc.Sampled {
+		t.Fatalf("unexpected root span context: %+v", sc)
+	}
+
+	ctx, child := trace.Start(ctx, "child")
+
+	if child.Context().TraceID != sc.TraceID || child.Context().SpanID == sc.SpanID {
+		t.Fatalf("unexpected child span context: %+v", child.Context())
+	}
+
+	if sc, _ = trace.FromContext(ctx); sc != child.Context() {
+		t.Fatalf("context should carry child span: %+v", sc)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "trace", "spans.jsonl")
+	exporter := trace.NewFileExporter(file)
+
+	trace.SetExporter(exporter)
+
+	ctx, root := trace.Start(context.Background(), "root")
+	_, child := trace.Start(ctx, "child")
+	child.SetAttr("route", "1")
+	child.End()
+	root.End()
+
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var spans []trace.SpanData
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		span := trace.SpanData{}
+		if err = json.Unmarshal(scanner.Bytes(), &span); err != nil {
+			t.Fatal(err)
+		}
+		spans = append(spans, span)
+	}
+
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	if spans[0].Name != "child" || spans[0].ParentID != spans[1].SpanID || spans[0].TraceID != spans[1].TraceID || spans[0].Attrs["route"] != "1" {
+		t.Fatalf("unexpected spans: %+v", spans)
+	}
+
+	if err = exporter.Export(&trace.SpanData{}); err == nil {
+		t.Fatal("expected export on closed exporter to fail")
+	}
+}
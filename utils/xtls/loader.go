@@ -0,0 +1,227 @@
+package xtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"gatesvr/log"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+type Loader struct {
+	certFile string                          // 证书文件
+	keyFile  string                          // 秘钥文件
+	caFile   string                          // CA证书文件，配置后启用双向认证
+	cert     atomic.Pointer[tls.Certificate] // 当前证书
+	pool     atomic.Pointer[x509.CertPool]   // 当前CA证书池
+	watcher  *fsnotify.Watcher               // 文件监听器
+	done     chan struct{}                   // 关闭信号
+}
+
+// NewLoader 创建证书加载器，证书文件发生变更时会自动重新加载
+func NewLoader(certFile, keyFile string, caFile ...string) (*Loader, error) {
+	l := &Loader{certFile: certFile, keyFile: keyFile, done: make(chan struct{})}
+
+	if len(caFile) > 0 {
+		l.caFile = caFile[0]
+	}
+
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+
+	if err := l.watch(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// IsMutual 是否启用双向认证
+func (l *Loader) IsMutual() bool {
+	return l.caFile != ""
+}
+
+// ServerConfig 获取服务端TLS配置
+func (l *Loader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			conf := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*l.cert.Load()},
+			}
+
+			if l.caFile != "" {
+				conf.ClientCAs = l.pool.Load()
+				conf.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+
+			return conf, nil
+		},
+	}
+}
+
+// ClientConfig 获取客户端TLS配置
+// 未配置CA证书时使用系统根证书校验服务端证书
+func (l *Loader) ClientConfig(serverName ...string) *tls.Config {
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return l.cert.Load(), nil
+		},
+	}
+
+	if len(serverName) > 0 {
+		conf.ServerName = serverName[0]
+	}
+
+	if l.caFile == "" {
+		return conf
+	}
+
+	// 使用自定义校验以便CA证书热更新后立即生效
+	conf.InsecureSkipVerify = true
+	conf.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("tls: missing peer certificate")
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         l.pool.Load(),
+			Intermediates: x509.NewCertPool(),
+		}
+
+		if conf.ServerName != "" {
+			opts.DNSName = conf.ServerName
+		}
+
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+
+		_, err := cs.PeerCertificates[0].Verify(opts)
+
+		return err
+	}
+
+	return conf
+}
+
+// Close 停止监听证书文件
+func (l *Loader) Close() error {
+	select {
+	case <-l.done:
+		return nil
+	default:
+		close(l.done)
+	}
+
+	return l.watcher.Close()
+}
+
+// 加载证书
+func (l *Loader) load() error {
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var pool *x509.CertPool
+	if l.caFile != "" {
+		data, err := os.ReadFile(l.caFile)
+		if err != nil {
+			return err
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return errors.New("tls: invalid ca certificate")
+		}
+	}
+
+	l.cert.Store(&cert)
+
+	if pool != nil {
+		l.pool.Store(pool)
+	}
+
+	return nil
+}
+
+// 监听证书文件变更
+// 监听文件所在目录，以兼容通过重命名替换文件的更新方式
+func (l *Loader) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dirs := make(map[string]struct{})
+	files := make(map[string]struct{})
+	for _, file := range []string{l.certFile, l.keyFile, l.caFile} {
+		if file == "" {
+			continue
+		}
+
+		file, err = filepath.Abs(file)
+		if err != nil {
+			_ = watcher.Close()
+			return err
+		}
+
+		dir := filepath.Dir(file)
+		if _, ok := dirs[dir]; !ok {
+			if err = watcher.Add(dir); err != nil {
+				_ = watcher.Close()
+				return err
+			}
+		}
+
+		dirs[dir] = struct{}{}
+		files[file] = struct{}{}
+	}
+
+	l.watcher = watcher
+
+	go func() {
+		var timer <-chan time.Time
+
+		for {
+			select {
+			case <-l.done:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if _, ok = files[filepath.Clean(event.Name)]; !ok {
+					continue
+				}
+
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+					// 证书与秘钥通常会先后写入，延迟合并变更后再加载
+					timer = time.After(200 * time.Millisecond)
+				}
+			case <-timer:
+				if err := l.load(); err != nil {
+					log.Errorf("tls certificate reload failed: %v", err)
+				} else {
+					log.Infof("tls certificate reloaded: %s", l.certFile)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Warnf("tls certificate watch error: %v", err)
+			}
+		}
+	}()
+
+	return nil
+}
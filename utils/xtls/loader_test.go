@@ -0,0 +1,176 @@
+package xtls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"gatesvr/utils/xtls"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoader_Mutual(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newCA(t, dir)
+	certFile, keyFile := newCert(t, dir, "node", 1, ca, caKey)
+
+	loader, err := xtls.NewLoader(certFile, keyFile, filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatalf("create loader failed: %v", err)
+	}
+	defer loader.Close()
+
+	if err = handshake(loader.ServerConfig(), loader.ClientConfig("localhost")); err != nil {
+		t.Fatalf("mutual handshake failed: %v", err)
+	}
+
+	// 未提供客户端证书时握手失败
+	if err = handshake(loader.ServerConfig(), &tls.Config{InsecureSkipVerify: true}); err == nil {
+		t.Fatal("handshake without client certificate should fail")
+	}
+}
+
+func TestLoader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newCA(t, dir)
+	certFile, keyFile := newCert(t, dir, "node", 1, ca, caKey)
+
+	loader, err := xtls.NewLoader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("create loader failed: %v", err)
+	}
+	defer loader.Close()
+
+	if serial := serverSerial(t, loader); serial != 1 {
+		t.Fatalf("unexpected certificate serial: %d", serial)
+	}
+
+	newCert(t, dir, "node", 2, ca, caKey)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for serverSerial(t, loader) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("certificate is not reloaded")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func handshake(serverConfig, clientConfig *tls.Config) error {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	chErr := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			chErr <- err
+			return
+		}
+		defer conn.Close()
+
+		chErr <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return <-chErr
+}
+
+func serverSerial(t *testing.T, loader *xtls.Loader) int64 {
+	conf, err := loader.ServerConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("get server config failed: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(conf.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("parse certificate failed: %v", err)
+	}
+
+	return cert.SerialNumber.Int64()
+}
+
+func newCA(t *testing.T, dir string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(100),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", der)
+
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ca, key
+}
+
+func newCert(t *testing.T, dir, name string, serial int64, ca *x509.Certificate, caKey *ecdsa.PrivateKey) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".key")
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDer)
+	writePEM(t, certFile, "CERTIFICATE", der)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, file, typ string, der []byte) {
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}